@@ -18,6 +18,8 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/google/go-licenses/licenses"
 	"github.com/spf13/cobra"
@@ -28,33 +30,93 @@ var (
 		Use:   "check <package>",
 		Short: "Checks whether licenses for a package are not Forbidden.",
 		Args:  cobra.MinimumNArgs(1),
-		RunE:  checkMain,
+		RunE:  runE(checkMain),
 	}
+
+	checkSummary     bool
+	checkAllLicenses bool
 )
 
 func init() {
+	checkCmd.Flags().BoolVar(&checkSummary, "summary", false, "Print the number of libraries of each license type to stderr at the end, whether or not the check passes")
+
+	checkCmd.Flags().BoolVar(&checkAllLicenses, "all_licenses", false, "Identify every license in each license file, e.g. in a document that concatenates several licenses, so that the check fails when any of them is Forbidden")
+
 	rootCmd.AddCommand(checkCmd)
 }
 
 func checkMain(_ *cobra.Command, args []string) error {
-	classifier, err := licenses.NewClassifier(confidenceThreshold)
+	classifier, err := newClassifier()
 	if err != nil {
 		return err
 	}
 
-	libs, err := licenses.Libraries(context.Background(), classifier, args...)
+	report, err := licenses.Report(context.Background(), licenses.ReportOptions{
+		Classifier:       classifier,
+		LibrariesOptions: librariesOptions(),
+		SkipURLs:         true,
+		AllLicenses:      checkAllLicenses,
+		ImportPaths:      args,
+	})
 	if err != nil {
 		return err
 	}
-	for _, lib := range libs {
-		licenseName, licenseType, err := classifier.Identify(lib.LicensePath)
-		if err != nil {
-			return err
+	checkErr := identifyError(report.Libraries)
+	violations := report.Violations()
+	if checkErr == nil {
+		for _, lib := range violations {
+			fmt.Fprintf(os.Stderr, "Forbidden license type %s for library %v\n", licenseNames(lib), lib)
 		}
-		if licenseType == licenses.Forbidden {
-			fmt.Fprintf(os.Stderr, "Forbidden license type %s for library %v\n", licenseName, lib)
-			os.Exit(1)
+	}
+	if checkSummary {
+		fmt.Fprintln(os.Stderr, summarizeLicenseTypes(report.Libraries))
+	}
+	if checkErr != nil {
+		return checkErr
+	}
+	if len(violations) > 0 {
+		return violationError(fmt.Errorf("found %d libraries with forbidden licenses", len(violations)))
+	}
+	return nil
+}
+
+// identifyError returns the first error identifying licenses of libs.
+// Libraries without license files are not errors, their license types are
+// Unknown.
+func identifyError(libs []*licenses.LibraryLicense) error {
+	for _, lib := range libs {
+		if lib.Err != nil {
+			return lib.Err
 		}
 	}
 	return nil
 }
+
+// summarizeLicenseTypes counts libraries of each license type, e.g.
+// "142 Notice, 8 Permissive, 1 Forbidden". Types are ordered by decreasing
+// count.
+func summarizeLicenseTypes(libs []*licenses.LibraryLicense) string {
+	counts := make(map[licenses.Type]int)
+	for _, lib := range libs {
+		counts[lib.LicenseType]++
+	}
+	types := make([]licenses.Type, 0, len(counts))
+	for t := range counts {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if counts[types[i]] != counts[types[j]] {
+			return counts[types[i]] > counts[types[j]]
+		}
+		return types[i].String() < types[j].String()
+	})
+	parts := make([]string, 0, len(types))
+	for _, t := range types {
+		name := strings.ToLower(t.String())
+		parts = append(parts, fmt.Sprintf("%d %s", counts[t], strings.ToUpper(name[:1])+name[1:]))
+	}
+	if len(parts) == 0 {
+		return "No libraries found"
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,50 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-licenses/licenses"
+)
+
+func TestSummarizeLicenseTypes(t *testing.T) {
+	var libs []*licenses.LibraryLicense
+	for _, licenseType := range []licenses.Type{licenses.Notice, licenses.Forbidden, licenses.Permissive, licenses.Notice, licenses.Unknown, licenses.Permissive, licenses.Notice} {
+		libs = append(libs, &licenses.LibraryLicense{LicenseType: licenseType})
+	}
+	want := "3 Notice, 2 Permissive, 1 Forbidden, 1 Unknown"
+	if got := summarizeLicenseTypes(libs); got != want {
+		t.Errorf("summarizeLicenseTypes() = %q, want %q", got, want)
+	}
+	if got, want := summarizeLicenseTypes(nil), "No libraries found"; got != want {
+		t.Errorf("summarizeLicenseTypes(nil) = %q, want %q", got, want)
+	}
+}
+
+func TestIdentifyError(t *testing.T) {
+	// a library without a license file has an Unknown license, it's not an
+	// error
+	noLicense := &licenses.LibraryLicense{Library: &licenses.Library{}, LicenseName: "Unknown", LicenseType: licenses.Unknown}
+	if err := identifyError([]*licenses.LibraryLicense{noLicense}); err != nil {
+		t.Errorf("identifyError(library without license) = %q, want nil", err)
+	}
+	wantErr := errors.New("unreadable license")
+	failed := &licenses.LibraryLicense{Library: &licenses.Library{LicensePath: "LICENSE"}, Err: wantErr}
+	if err := identifyError([]*licenses.LibraryLicense{noLicense, failed}); err != wantErr {
+		t.Errorf("identifyError(library failed to identify) = %v, want %q", err, wantErr)
+	}
+}
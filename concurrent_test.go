@@ -0,0 +1,59 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestForEachConcurrently(t *testing.T) {
+	for _, test := range []struct {
+		desc        string
+		n           int
+		concurrency int
+	}{
+		{desc: "no indexes", n: 0, concurrency: 2},
+		{desc: "more indexes than workers", n: 20, concurrency: 3},
+		{desc: "more workers than indexes", n: 2, concurrency: 8},
+		{desc: "default concurrency", n: 20, concurrency: 0},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			var mu sync.Mutex
+			calls := make([]int, test.n)
+			running, maxRunning := 0, 0
+			forEachConcurrently(test.n, test.concurrency, func(i int) {
+				mu.Lock()
+				calls[i]++
+				running++
+				if running > maxRunning {
+					maxRunning = running
+				}
+				mu.Unlock()
+				mu.Lock()
+				running--
+				mu.Unlock()
+			})
+			for i, got := range calls {
+				if got != 1 {
+					t.Errorf("forEachConcurrently() called f(%d) %d times, want 1", i, got)
+				}
+			}
+			if test.concurrency > 0 && maxRunning > test.concurrency {
+				t.Errorf("forEachConcurrently() ran %d calls concurrently, want at most %d", maxRunning, test.concurrency)
+			}
+		})
+	}
+}
@@ -15,14 +15,18 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/csv"
+	"io"
+	"io/ioutil"
 	"os"
-	"strings"
+	"path/filepath"
+	"strconv"
 
-	"github.com/golang/glog"
 	"github.com/google/go-licenses/licenses"
 	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
 )
 
 var (
@@ -30,70 +34,111 @@ var (
 		Use:   "csv <package>",
 		Short: "Prints all licenses that apply to a Go package and its dependencies",
 		Args:  cobra.MinimumNArgs(1),
-		RunE:  csvMain,
+		RunE:  runE(csvMain),
 	}
 
-	gitRemotes []string
+	gitRemotes           []string
+	includeConfidence    bool
+	csvOutput            string
+	reportBelowThreshold bool
+	csvAllLicenses       bool
 )
 
 func init() {
 	csvCmd.Flags().StringArrayVar(&gitRemotes, "git_remote", []string{"origin", "upstream"}, "Remote Git repositories to try")
+	csvCmd.Flags().BoolVar(&includeConfidence, "include_confidence", false, "Add a column with the confidence of each license match, between 0 and 1")
+	csvCmd.Flags().BoolVar(&reportBelowThreshold, "report_below_threshold", false, "Warn about license files skipped because their best match is below --confidence_threshold, with the license and confidence of the match")
+	csvCmd.Flags().BoolVar(&csvAllLicenses, "all_licenses", false, "Identify every license in each license file, e.g. in a document that concatenates several licenses, the license column lists them separated by commas, e.g. \"Apache-2.0,MIT\"")
+	csvCmd.Flags().StringVar(&csvOutput, "output", "", "File to write the CSV to instead of stdout. It's only replaced when all licenses are written successfully.")
 
 	rootCmd.AddCommand(csvCmd)
 }
 
 func csvMain(_ *cobra.Command, args []string) error {
-	writer := csv.NewWriter(os.Stdout)
+	var out io.Writer = os.Stdout
+	// The CSV is buffered when writing to a file, so that the file is left
+	// intact when the command fails.
+	var buf bytes.Buffer
+	if csvOutput != "" {
+		out = &buf
+	}
+	writer := csv.NewWriter(out)
 
-	classifier, err := licenses.NewClassifier(confidenceThreshold)
+	classifier, err := newClassifier()
 	if err != nil {
 		return err
 	}
 
-	libs, err := licenses.Libraries(context.Background(), classifier, args...)
+	libsOptions := librariesOptions()
+	libsOptions.ReportBelowThreshold = reportBelowThreshold
+	report, err := licenses.Report(context.Background(), licenses.ReportOptions{
+		Classifier:       classifier,
+		LibrariesOptions: libsOptions,
+		GitRemotes:       gitRemotes,
+		AllLicenses:      csvAllLicenses,
+		ImportPaths:      args,
+	})
 	if err != nil {
 		return err
 	}
-	for _, lib := range libs {
-		licenseURL := "Unknown"
-		licenseName := "Unknown"
-		if lib.LicensePath != "" {
-			// Find a URL for the license file, based on the URL of a remote for the Git repository.
-			var errs []string
-			repo, err := licenses.FindGitRepo(lib.LicensePath)
-			if err != nil {
-				// Can't find Git repo (possibly a Go Module?) - derive URL from lib name instead.
-				lURL, err := lib.FileURL(lib.LicensePath)
-				if err != nil {
-					errs = append(errs, err.Error())
-				} else {
-					licenseURL = lURL.String()
-				}
-			} else {
-				for _, remote := range gitRemotes {
-					url, err := repo.FileURL(lib.LicensePath, remote)
-					if err != nil {
-						errs = append(errs, err.Error())
-						continue
-					}
-					licenseURL = url.String()
-					break
-				}
-			}
-			if licenseURL == "Unknown" {
-				glog.Errorf("Error discovering URL for %q:\n- %s", lib.LicensePath, strings.Join(errs, "\n- "))
-			}
-			licenseName, _, err = classifier.Identify(lib.LicensePath)
-			if err != nil {
-				glog.Errorf("Error identifying license in %q: %v", lib.LicensePath, err)
-				licenseName = "Unknown"
-			}
+	for _, lib := range report.Libraries {
+		licenseURL := lib.LicenseURL
+		if licenseURL == "" {
+			licenseURL = "Unknown"
 		}
 		// Remove the "*/vendor/" prefix from the library name for conciseness.
-		if err := writer.Write([]string{unvendor(lib.Name()), licenseURL, licenseName}); err != nil {
+		record := []string{unvendor(lib.Name()), licenseURL, licenseNames(lib)}
+		if includeConfidence {
+			confidence := ""
+			if lib.Confidence > 0 {
+				confidence = strconv.FormatFloat(lib.Confidence, 'f', 2, 64)
+			}
+			record = append(record, confidence)
+		}
+		if err := writer.Write(record); err != nil {
 			return err
 		}
 	}
 	writer.Flush()
-	return writer.Error()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	// Matches below the threshold are listed after all licenses, so that
+	// they are easy to review.
+	for _, lib := range report.Libraries {
+		for _, m := range lib.BelowThreshold {
+			klog.Warningf("%s: %s matches %s with confidence %.2f, below --confidence_threshold", unvendor(lib.Name()), m.Path, m.Name, m.Confidence)
+		}
+	}
+	if csvOutput != "" {
+		return writeFileAtomic(csvOutput, buf.Bytes())
+	}
+	return nil
+}
+
+// writeFileAtomic writes content to a temp file in the directory of path,
+// and renames it to path, so that readers never see a partially written
+// file. The permissions of an existing file at path are kept.
+func writeFileAtomic(path string, content []byte) error {
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	// Removing the temp file fails harmlessly after it's renamed.
+	defer os.Remove(f.Name())
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
 }
@@ -0,0 +1,88 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-licenses/licenses"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "csv_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "licenses.csv")
+	if err := ioutil.WriteFile(path, []byte("old"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomic(path, []byte("new")); err != nil {
+		t.Fatalf("writeFileAtomic() = %q, want nil", err)
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(content), "new"; got != want {
+		t.Errorf("content = %q, want %q", got, want)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := info.Mode().Perm(), os.FileMode(0640); got != want {
+		t.Errorf("permissions = %v, want %v", got, want)
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("files in %s = %v, want only licenses.csv", dir, files)
+	}
+}
+
+func TestLicenseNames(t *testing.T) {
+	for _, test := range []struct {
+		desc string
+		lib  *licenses.LibraryLicense
+		want string
+	}{
+		{
+			desc: "best match only",
+			lib:  &licenses.LibraryLicense{LicenseName: "MIT"},
+			want: "MIT",
+		},
+		{
+			desc: "all licenses",
+			lib: &licenses.LibraryLicense{
+				LicenseName: "Apache-2.0",
+				Licenses:    []licenses.Match{{Name: "Apache-2.0"}, {Name: "MIT"}},
+			},
+			want: "Apache-2.0,MIT",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			if got := licenseNames(test.lib); got != test.want {
+				t.Errorf("licenseNames() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
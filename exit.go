@@ -0,0 +1,91 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+)
+
+// Exit codes of commands, the same as the v2 go-licenses, so that CI can
+// react differently to a forbidden license and e.g. a network failure.
+const (
+	// The command succeeded.
+	exitOK = 0
+	// Some licenses are forbidden or unknown.
+	exitViolation = 1
+	// Invalid arguments or flags.
+	exitUsage = 2
+	// Failed to find, identify or save licenses.
+	exitScanError = 3
+)
+
+// exitError is an error that exits a command with a specific exit code.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// violationError marks err as forbidden or unknown licenses.
+func violationError(err error) error {
+	return &exitError{code: exitViolation, err: err}
+}
+
+// usageError marks err as invalid arguments or flags.
+func usageError(err error) error {
+	return &exitError{code: exitUsage, err: err}
+}
+
+// runE adapts a command implementation to cobra.Command.RunE. Errors without
+// an exit code are scan errors, so that errors returned by cobra itself, e.g.
+// for missing arguments, are distinguished as usage errors by exitCode.
+func runE(run func(cmd *cobra.Command, args []string) error) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		// Usage is only relevant to errors before running the command.
+		cmd.SilenceUsage = true
+		err := run(cmd, args)
+		if err == nil {
+			return nil
+		}
+		var exitErr *exitError
+		if errors.As(err, &exitErr) {
+			return err
+		}
+		return &exitError{code: exitScanError, err: err}
+	}
+}
+
+// exitCode returns the exit code for an error returned by a command.
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+	// Errors not returned by runE are returned by cobra before running the
+	// command, e.g. unknown flags or a wrong number of arguments.
+	return exitUsage
+}
@@ -0,0 +1,49 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestExitCode(t *testing.T) {
+	for _, test := range []struct {
+		desc string
+		err  error
+		want int
+	}{
+		{desc: "success", err: nil, want: exitOK},
+		{desc: "violation", err: violationError(errors.New("forbidden")), want: exitViolation},
+		{desc: "wrapped violation", err: fmt.Errorf("check: %w", violationError(errors.New("forbidden"))), want: exitViolation},
+		{desc: "usage", err: usageError(errors.New("invalid flag")), want: exitUsage},
+		{desc: "scan error", err: errors.New("network failure"), want: exitScanError},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			err := runE(func(*cobra.Command, []string) error { return test.err })(cmd, nil)
+			if got := exitCode(err); got != test.want {
+				t.Errorf("exitCode(runE(%v)) = %d, want %d", test.err, got, test.want)
+			}
+		})
+	}
+	// errors of cobra itself are returned before running a command
+	if got := exitCode(errors.New("unknown flag: --foo")); got != exitUsage {
+		t.Errorf("exitCode(cobra error) = %d, want %d", got, exitUsage)
+	}
+}
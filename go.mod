@@ -3,7 +3,6 @@ module github.com/google/go-licenses
 go 1.13
 
 require (
-	github.com/golang/glog v0.0.0-20160126235308-23def4e6c14b
 	github.com/google/go-cmp v0.3.1
 	github.com/google/licenseclassifier v0.0.0-20210325184830-bb04aff29e72
 	github.com/otiai10/copy v1.2.0
@@ -14,4 +13,5 @@ require (
 	golang.org/x/sys v0.0.0-20191119060738-e882bf8e40c2 // indirect
 	golang.org/x/tools v0.0.0-20191118222007-07fc4c7f2b98
 	gopkg.in/src-d/go-git.v4 v4.13.1
+	k8s.io/klog/v2 v2.9.0
 )
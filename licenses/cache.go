@@ -0,0 +1,153 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"k8s.io/klog/v2"
+)
+
+// cachedClassifier is a classifier that caches results of Identify on disk,
+// keyed by the hash of license file content.
+type cachedClassifier struct {
+	*googleClassifier
+	confidenceThreshold float64
+	cacheDir            string
+}
+
+// cacheEntry is a classification result stored in the cache.
+type cacheEntry struct {
+	// Confidence threshold used for the classification, entries classified
+	// using another threshold are ignored.
+	ConfidenceThreshold float64 `json:"confidenceThreshold"`
+	// Name of the license, empty when the license is unknown.
+	Name       string  `json:"name"`
+	Type       Type    `json:"type"`
+	Confidence float64 `json:"confidence"`
+	// Digest of custom licenses used for the classification, entries
+	// classified using other custom licenses are ignored.
+	CustomLicenses string `json:"customLicenses,omitempty"`
+}
+
+// NewClassifierWithCache creates a classifier like NewClassifier, which
+// caches results of Identify in cacheDir, so that the same license files are
+// not classified again in later runs. Cached results are ignored when the
+// confidence threshold changes.
+func NewClassifierWithCache(confidenceThreshold float64, cacheDir string) (Classifier, error) {
+	return NewClassifierWithOptions(confidenceThreshold, ClassifierOptions{CacheDir: cacheDir})
+}
+
+func newCachedClassifier(c *googleClassifier, confidenceThreshold float64, cacheDir string) (*cachedClassifier, error) {
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return nil, err
+	}
+	return &cachedClassifier{
+		googleClassifier:    c,
+		confidenceThreshold: confidenceThreshold,
+		cacheDir:            cacheDir,
+	}, nil
+}
+
+// Identify returns the name and type of a license, given its file path.
+// An empty license path results in an empty name and Unknown type.
+func (c *cachedClassifier) Identify(licensePath string) (string, Type, error) {
+	m, err := c.IdentifyMatch(licensePath)
+	return m.Name, m.Type, err
+}
+
+// IdentifyMatch returns the name, type and confidence of a license, given its
+// file path. An empty license path results in an empty name and Unknown type.
+func (c *cachedClassifier) IdentifyMatch(licensePath string) (Match, error) {
+	if licensePath == "" {
+		return Match{Type: Unknown}, nil
+	}
+	content, err := ioutil.ReadFile(licensePath)
+	if err != nil {
+		return Match{}, err
+	}
+	sum := sha256.Sum256(content)
+	entryPath := filepath.Join(c.cacheDir, hex.EncodeToString(sum[:])+".json")
+	entry, ok := c.load(entryPath)
+	if !ok {
+		entry = cacheEntry{ConfidenceThreshold: c.confidenceThreshold, CustomLicenses: c.customDigest()}
+		entry.Name, entry.Confidence, err = c.identifyContent(content)
+		if err != nil && err != errUnknownLicense {
+			return Match{}, err
+		}
+		if entry.Name != "" {
+			entry.Type = c.licenseType(entry.Name)
+		}
+		if err := c.store(entryPath, entry); err != nil {
+			// The cache is only an optimization, classification still works.
+			klog.Warningf("Failed to cache license classification of %s: %v", licensePath, err)
+		}
+	}
+	if entry.Name == "" {
+		return Match{}, errUnknownLicense
+	}
+	return Match{Name: entry.Name, Type: entry.Type, Confidence: entry.Confidence}, nil
+}
+
+// load reads a cache entry, it reports false when the entry does not exist or
+// was classified using another confidence threshold or custom licenses.
+func (c *cachedClassifier) load(entryPath string) (cacheEntry, bool) {
+	var entry cacheEntry
+	data, err := ioutil.ReadFile(entryPath)
+	if err != nil {
+		return entry, false
+	}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		klog.Warningf("Ignoring invalid license classification cache %s: %v", entryPath, err)
+		return entry, false
+	}
+	return entry, entry.ConfidenceThreshold == c.confidenceThreshold && entry.CustomLicenses == c.customDigest()
+}
+
+// customDigest returns the digest of custom licenses, empty when there are
+// none.
+func (c *cachedClassifier) customDigest() string {
+	if c.custom == nil {
+		return ""
+	}
+	return c.custom.digest
+}
+
+// store writes a cache entry, the entry is written to a temp file and renamed,
+// so that concurrent runs never see partial entries.
+func (c *cachedClassifier) store(entryPath string, entry cacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(c.cacheDir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), entryPath)
+}
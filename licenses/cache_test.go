@@ -0,0 +1,100 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestIdentifyWithCache(t *testing.T) {
+	const file = "testdata/MIT/LICENSE.MIT"
+	cacheDir, err := ioutil.TempDir("", "cache_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cacheDir)
+	c, err := NewClassifierWithCache(1, cacheDir)
+	if err != nil {
+		t.Fatalf("NewClassifierWithCache(1, %q) = (_, %q), want (_, nil)", cacheDir, err)
+	}
+	gotLicense, gotType, err := c.Identify(file)
+	if err != nil || gotLicense != "MIT" || gotType != Notice {
+		t.Fatalf("c.Identify(%q) = (%q, %q, %v), want (%q, %q, <nil>)", file, gotLicense, gotType, err, "MIT", Notice)
+	}
+	entries, err := filepath.Glob(filepath.Join(cacheDir, "*.json"))
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("cache entries = (%q, %v), want 1 entry", entries, err)
+	}
+	data, err := ioutil.ReadFile(entries[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		t.Fatal(err)
+	}
+	want := cacheEntry{ConfidenceThreshold: 1, Name: "MIT", Type: Notice, Confidence: 1}
+	if diff := cmp.Diff(want, entry); diff != "" {
+		t.Errorf("cache entry returned diff (-want +got):\n%s", diff)
+	}
+
+	// Tamper with the cache entry, so that we can tell whether it's used.
+	entry.Name = "BSD-3-Clause"
+	data, err = json.Marshal(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(entries[0], data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	gotLicense, _, err = c.Identify(file)
+	if err != nil || gotLicense != "BSD-3-Clause" {
+		t.Errorf("c.Identify(%q) = (%q, _, %v), want cached license %q", file, gotLicense, err, "BSD-3-Clause")
+	}
+
+	// The cache entry is ignored when the confidence threshold changes.
+	c, err = NewClassifierWithCache(0.9, cacheDir)
+	if err != nil {
+		t.Fatalf("NewClassifierWithCache(0.9, %q) = (_, %q), want (_, nil)", cacheDir, err)
+	}
+	gotLicense, _, err = c.Identify(file)
+	if err != nil || gotLicense != "MIT" {
+		t.Errorf("c.Identify(%q) = (%q, _, %v), want %q classified again", file, gotLicense, err, "MIT")
+	}
+}
+
+func TestIdentifyWithCache_UnknownLicense(t *testing.T) {
+	const file = "testdata/direct/direct.go"
+	cacheDir, err := ioutil.TempDir("", "cache_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cacheDir)
+	c, err := NewClassifierWithCache(1, cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if gotLicense, _, err := c.Identify(file); err == nil {
+			t.Errorf("c.Identify(%q) = (%q, _, nil), want an unknown license error", file, gotLicense)
+		}
+	}
+}
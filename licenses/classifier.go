@@ -15,8 +15,9 @@
 package licenses
 
 import (
-	"fmt"
+	"errors"
 	"io/ioutil"
+	"sort"
 
 	"github.com/google/licenseclassifier"
 )
@@ -63,39 +64,214 @@ func (t Type) String() string {
 	}
 }
 
-// Classifier can detect the type of a software license.
+// Classifier can detect the type of a software license. NewClassifier returns
+// the default implementation using github.com/google/licenseclassifier, other
+// implementations, e.g. using licenseclassifier/v2 or an internal matcher, can
+// be passed wherever a Classifier is accepted, or as ClassifierOptions.Backend.
 type Classifier interface {
 	Identify(licensePath string) (string, Type, error)
 }
 
+// MultiClassifier can detect all of the software licenses in a file, e.g. a
+// document that concatenates several licenses.
+type MultiClassifier interface {
+	Classifier
+	IdentifyAll(licensePath string) ([]Match, error)
+}
+
+// ConfidenceClassifier can also report how confident a license match is, e.g.
+// to review matches close to the confidence threshold.
+type ConfidenceClassifier interface {
+	Classifier
+	IdentifyMatch(licensePath string) (Match, error)
+}
+
+// NearestClassifier is a Classifier that also reports the best matching
+// license in a file when its confidence is below the confidence threshold,
+// e.g. to tune the threshold.
+type NearestClassifier interface {
+	Classifier
+	NearestMatch(licensePath string) (Match, error)
+}
+
+// Match is a license detected in a file.
+type Match struct {
+	// Name of the license.
+	Name string
+	// Type of the license.
+	Type Type
+	// Confidence of the match, between 0 and 1.
+	Confidence float64
+}
+
 type googleClassifier struct {
 	classifier *licenseclassifier.License
+	// custom licenses, nil when there are none
+	custom *customLicenses
+}
+
+// ClassifierOptions are optional features of a classifier.
+type ClassifierOptions struct {
+	// Directory to cache classification results in, see
+	// NewClassifierWithCache.
+	CacheDir string
+	// Directory of custom license texts organized as <type>/<ID>.txt, e.g.
+	// restricted/LicenseRef-Acme.txt. They are identified in addition to
+	// known open source licenses.
+	LicensesDir string
+	// Backend identifies licenses instead of the default implementation,
+	// e.g. a classifier using licenseclassifier/v2. CacheDir and LicensesDir
+	// are features of the default implementation, they cannot be used with a
+	// backend.
+	Backend Classifier
 }
 
 // NewClassifier creates a classifier that requires a specified confidence threshold
 // in order to return a positive license classification.
+// The returned classifier also implements MultiClassifier and
+// ConfidenceClassifier.
 func NewClassifier(confidenceThreshold float64) (Classifier, error) {
-	c, err := licenseclassifier.New(confidenceThreshold)
+	return NewClassifierWithOptions(confidenceThreshold, ClassifierOptions{})
+}
+
+// NewClassifierWithOptions creates a classifier like NewClassifier, with
+// optional features enabled by options. When options.Backend is set, it's
+// returned instead, and applies its own confidence threshold.
+func NewClassifierWithOptions(confidenceThreshold float64, options ClassifierOptions) (Classifier, error) {
+	if options.Backend != nil {
+		if options.CacheDir != "" || options.LicensesDir != "" {
+			return nil, errors.New("CacheDir and LicensesDir are not supported by a classifier backend")
+		}
+		return options.Backend, nil
+	}
+	lc, err := licenseclassifier.New(confidenceThreshold)
 	if err != nil {
 		return nil, err
 	}
-	return &googleClassifier{classifier: c}, nil
+	c := &googleClassifier{classifier: lc}
+	if options.LicensesDir != "" {
+		c.custom, err = loadCustomLicenses(confidenceThreshold, options.LicensesDir)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if options.CacheDir != "" {
+		return newCachedClassifier(c, confidenceThreshold, options.CacheDir)
+	}
+	return c, nil
 }
 
 // Identify returns the name and type of a license, given its file path.
 // An empty license path results in an empty name and Unknown type.
 func (c *googleClassifier) Identify(licensePath string) (string, Type, error) {
+	m, err := c.IdentifyMatch(licensePath)
+	return m.Name, m.Type, err
+}
+
+// IdentifyMatch returns the name, type and confidence of a license, given its
+// file path. An empty license path results in an empty name and Unknown type.
+func (c *googleClassifier) IdentifyMatch(licensePath string) (Match, error) {
+	if licensePath == "" {
+		return Match{Type: Unknown}, nil
+	}
+	content, err := ioutil.ReadFile(licensePath)
+	if err != nil {
+		return Match{}, err
+	}
+	licenseName, confidence, err := c.identifyContent(content)
+	if err != nil {
+		return Match{}, err
+	}
+	return Match{
+		Name:       licenseName,
+		Type:       c.licenseType(licenseName),
+		Confidence: confidence,
+	}, nil
+}
+
+// identifyContent returns the name of the best matching license in content,
+// and the confidence of the match.
+func (c *googleClassifier) identifyContent(content []byte) (string, float64, error) {
+	matches := c.match(content)
+	if len(matches) == 0 {
+		return "", 0, errUnknownLicense
+	}
+	return matches[0].Name, matches[0].Confidence, nil
+}
+
+// match returns all licenses matched in content, ordered by confidence.
+// Custom licenses win on ties.
+func (c *googleClassifier) match(content []byte) []Match {
+	var matches []Match
+	if c.custom != nil {
+		matches = c.custom.match(content)
+	}
+	for _, m := range c.classifier.MultipleMatch(string(content), true) {
+		matches = append(matches, Match{Name: m.Name, Type: c.licenseType(m.Name), Confidence: m.Confidence})
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Confidence > matches[j].Confidence
+	})
+	return matches
+}
+
+// NearestMatch returns the best matching license in a file, given its file
+// path, regardless of the confidence threshold. Custom licenses win on ties.
+func (c *googleClassifier) NearestMatch(licensePath string) (Match, error) {
+	content, err := ioutil.ReadFile(licensePath)
+	if err != nil {
+		return Match{}, err
+	}
+	var best Match
+	if c.custom != nil {
+		if m := c.custom.classifier.NearestMatch(string(content)); m != nil && m.Name != "" {
+			best = Match{Name: m.Name, Type: c.custom.types[m.Name], Confidence: m.Confidence}
+		}
+	}
+	if m := c.classifier.NearestMatch(string(content)); m != nil && m.Name != "" && m.Confidence > best.Confidence {
+		best = Match{Name: m.Name, Type: c.licenseType(m.Name), Confidence: m.Confidence}
+	}
+	if best.Name == "" {
+		return Match{}, errUnknownLicense
+	}
+	return best, nil
+}
+
+// licenseType returns the type of a license, given its name.
+func (c *googleClassifier) licenseType(name string) Type {
+	if c.custom != nil {
+		if t, ok := c.custom.types[name]; ok {
+			return t
+		}
+	}
+	return Type(licenseclassifier.LicenseType(name))
+}
+
+var errUnknownLicense = errors.New("unknown license")
+
+// IdentifyAll returns the names, types and confidence of all licenses found in a file,
+// given its file path. Licenses are ordered by confidence and each license is
+// only returned once. An empty license path results in no licenses.
+func (c *googleClassifier) IdentifyAll(licensePath string) ([]Match, error) {
 	if licensePath == "" {
-		return "", Unknown, nil
+		return nil, nil
 	}
 	content, err := ioutil.ReadFile(licensePath)
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
-	matches := c.classifier.MultipleMatch(string(content), true)
+	matches := c.match(content)
 	if len(matches) == 0 {
-		return "", "", fmt.Errorf("unknown license")
+		return nil, errUnknownLicense
+	}
+	var licenses []Match
+	seen := make(map[string]bool)
+	for _, m := range matches {
+		if seen[m.Name] {
+			continue
+		}
+		seen[m.Name] = true
+		licenses = append(licenses, m)
 	}
-	licenseName := matches[0].Name
-	return licenseName, Type(licenseclassifier.LicenseType(licenseName)), nil
+	return licenses, nil
 }
@@ -16,9 +16,13 @@ package licenses
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 // Useful in other tests in this package
@@ -101,3 +105,125 @@ func TestIdentify(t *testing.T) {
 		})
 	}
 }
+
+func TestIdentifyMatch(t *testing.T) {
+	const file = "testdata/MIT/LICENSE.MIT"
+	c, err := NewClassifier(0.9)
+	if err != nil {
+		t.Fatalf("NewClassifier(0.9) = (_, %q), want (_, nil)", err)
+	}
+	got, err := c.(ConfidenceClassifier).IdentifyMatch(file)
+	if err != nil {
+		t.Fatalf("c.IdentifyMatch(%q) = (_, %q), want (_, nil)", file, err)
+	}
+	want := Match{Name: "MIT", Type: Notice, Confidence: 1}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("c.IdentifyMatch(%q) returned diff (-want +got):\n%s", file, diff)
+	}
+}
+
+func TestNearestMatch(t *testing.T) {
+	content, err := ioutil.ReadFile("testdata/MIT/LICENSE.MIT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A modified MIT license, so that it's no longer an exact match.
+	modified := strings.Replace(string(content), "free of charge", "for a small fee", 1)
+	modified = strings.Replace(modified, "WITHOUT WARRANTY OF ANY KIND", "WITH LIMITED WARRANTY", 1)
+	dir, err := ioutil.TempDir("", "classifier_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "LICENSE")
+	if err := ioutil.WriteFile(file, []byte(modified), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewClassifier(0.99)
+	if err != nil {
+		t.Fatalf("NewClassifier(0.99) = (_, %q), want (_, nil)", err)
+	}
+	if _, _, err := c.Identify(file); err == nil {
+		t.Fatalf("c.Identify(%q) = (_, _, nil), want an error below the threshold", file)
+	}
+	got, err := c.(NearestClassifier).NearestMatch(file)
+	if err != nil {
+		t.Fatalf("c.NearestMatch(%q) = (_, %q), want (_, nil)", file, err)
+	}
+	if got.Name != "MIT" || got.Type != Notice || got.Confidence >= 0.99 || got.Confidence < 0.5 {
+		t.Errorf("c.NearestMatch(%q) = %+v, want MIT below the threshold", file, got)
+	}
+}
+
+func TestIdentifyAll(t *testing.T) {
+	for _, test := range []struct {
+		desc         string
+		file         string
+		confidence   float64
+		wantLicenses []Match
+		wantErr      bool
+	}{
+		{
+			desc:         "single license",
+			file:         "testdata/MIT/LICENSE.MIT",
+			confidence:   1,
+			wantLicenses: []Match{{Name: "MIT", Type: Notice, Confidence: 1}},
+		},
+		{
+			desc:         "multiple licenses",
+			file:         "testdata/multiple/LICENSE",
+			confidence:   1,
+			wantLicenses: []Match{{Name: "Apache-2.0", Type: Notice, Confidence: 1}, {Name: "MIT", Type: Notice, Confidence: 1}},
+		},
+		{
+			desc:       "non-existent file",
+			file:       "non-existent-file",
+			confidence: 1,
+			wantErr:    true,
+		},
+		{
+			desc:       "empty file path",
+			file:       "",
+			confidence: 1,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			c, err := NewClassifier(test.confidence)
+			if err != nil {
+				t.Fatalf("NewClassifier(%v) = (_, %q), want (_, nil)", test.confidence, err)
+			}
+			gotLicenses, err := c.(MultiClassifier).IdentifyAll(test.file)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("c.IdentifyAll(%q) = (_, %q), want err? %t", test.file, err, test.wantErr)
+			} else if gotErr {
+				return
+			}
+			if diff := cmp.Diff(test.wantLicenses, gotLicenses); diff != "" {
+				t.Errorf("c.IdentifyAll(%q) returned diff (-want +got):\n%s", test.file, diff)
+			}
+		})
+	}
+}
+
+func TestNewClassifierWithOptions_Backend(t *testing.T) {
+	backend := classifierStub{
+		licenseNames: map[string]string{"testdata/LICENSE": "LicenseRef-Internal"},
+		licenseTypes: map[string]Type{"testdata/LICENSE": Notice},
+	}
+	c, err := NewClassifierWithOptions(0.9, ClassifierOptions{Backend: backend})
+	if err != nil {
+		t.Fatalf("NewClassifierWithOptions(0.9, {Backend: stub}) = (_, %q), want (_, nil)", err)
+	}
+	file, err := filepath.Abs("testdata/LICENSE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotLicense, gotType, err := c.Identify(file)
+	if err != nil || gotLicense != "LicenseRef-Internal" || gotType != Notice {
+		t.Errorf("c.Identify(%q) = (%q, %q, %v), want (%q, %q, <nil>)", file, gotLicense, gotType, err, "LicenseRef-Internal", Notice)
+	}
+	if _, err := NewClassifierWithOptions(0.9, ClassifierOptions{Backend: backend, CacheDir: "cache"}); err == nil {
+		t.Errorf("NewClassifierWithOptions(0.9, {Backend: stub, CacheDir: %q}) = (_, nil), want an error", "cache")
+	}
+}
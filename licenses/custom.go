@@ -0,0 +1,103 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/licenseclassifier"
+	"github.com/google/licenseclassifier/stringclassifier"
+)
+
+// customLicenseTypes are license types that custom licenses can have, keyed
+// by the lowercase name of the folder they are in.
+var customLicenseTypes = map[string]Type{
+	string(Restricted):                 Restricted,
+	string(Reciprocal):                 Reciprocal,
+	string(Notice):                     Notice,
+	string(Permissive):                 Permissive,
+	string(Unencumbered):               Unencumbered,
+	strings.ToLower(string(Forbidden)): Forbidden,
+}
+
+// customLicenses are license texts unknown to licenseclassifier, e.g.
+// proprietary licenses of a company.
+type customLicenses struct {
+	classifier *stringclassifier.Classifier
+	// license ID -> type
+	types map[string]Type
+	// digest of all the license texts, IDs and types
+	digest string
+}
+
+// loadCustomLicenses loads license texts in dir organized as <type>/<ID>.txt.
+func loadCustomLicenses(confidenceThreshold float64, dir string) (*customLicenses, error) {
+	typeDirs, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	custom := &customLicenses{
+		classifier: stringclassifier.New(confidenceThreshold, licenseclassifier.Normalizers...),
+		types:      make(map[string]Type),
+	}
+	hash := sha256.New()
+	for _, typeDir := range typeDirs {
+		if !typeDir.IsDir() {
+			continue
+		}
+		t, ok := customLicenseTypes[strings.ToLower(typeDir.Name())]
+		if !ok {
+			return nil, fmt.Errorf("custom licenses in %s: unknown license type %q", filepath.Join(dir, typeDir.Name()), typeDir.Name())
+		}
+		files, err := ioutil.ReadDir(filepath.Join(dir, typeDir.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			if f.IsDir() || filepath.Ext(f.Name()) != ".txt" {
+				continue
+			}
+			id := strings.TrimSuffix(f.Name(), ".txt")
+			if previous, ok := custom.types[id]; ok {
+				return nil, fmt.Errorf("custom licenses in %s: license %q is both %s and %s", dir, id, previous, t)
+			}
+			content, err := ioutil.ReadFile(filepath.Join(dir, typeDir.Name(), f.Name()))
+			if err != nil {
+				return nil, err
+			}
+			if err := custom.classifier.AddValue(id, string(content)); err != nil {
+				return nil, err
+			}
+			custom.types[id] = t
+			fmt.Fprintf(hash, "%s/%s\n%s\n", t, id, content)
+		}
+	}
+	custom.digest = hex.EncodeToString(hash.Sum(nil))
+	return custom, nil
+}
+
+// match returns custom licenses matched in content.
+func (c *customLicenses) match(content []byte) []Match {
+	var matches []Match
+	for _, m := range c.classifier.MultipleMatch(string(content)) {
+		matches = append(matches, Match{Name: m.Name, Type: c.types[m.Name], Confidence: m.Confidence})
+	}
+	return matches
+}
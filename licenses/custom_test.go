@@ -0,0 +1,68 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIdentifyCustomLicenses(t *testing.T) {
+	const licensesDir = "testdata/customlicenses"
+	c, err := NewClassifierWithOptions(0.9, ClassifierOptions{LicensesDir: licensesDir})
+	if err != nil {
+		t.Fatalf("NewClassifierWithOptions(0.9, {LicensesDir: %q}) = (_, %q), want (_, nil)", licensesDir, err)
+	}
+	for _, test := range []struct {
+		file        string
+		wantLicense string
+		wantType    Type
+	}{
+		{
+			file:        "testdata/custom/LICENSE",
+			wantLicense: "LicenseRef-Acme",
+			wantType:    Restricted,
+		},
+		{
+			// known open source licenses are still identified
+			file:        "testdata/MIT/LICENSE.MIT",
+			wantLicense: "MIT",
+			wantType:    Notice,
+		},
+	} {
+		t.Run(test.file, func(t *testing.T) {
+			gotLicense, gotType, err := c.Identify(test.file)
+			if err != nil || gotLicense != test.wantLicense || gotType != test.wantType {
+				t.Errorf("c.Identify(%q) = (%q, %q, %v), want (%q, %q, <nil>)", test.file, gotLicense, gotType, err, test.wantLicense, test.wantType)
+			}
+		})
+	}
+}
+
+func TestIdentifyCustomLicenses_InvalidType(t *testing.T) {
+	licensesDir, err := ioutil.TempDir("", "custom_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(licensesDir)
+	if err := os.Mkdir(filepath.Join(licensesDir, "proprietary"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewClassifierWithOptions(0.9, ClassifierOptions{LicensesDir: licensesDir}); err == nil {
+		t.Errorf("NewClassifierWithOptions(0.9, {LicensesDir: %q}) = (_, nil), want an unknown license type error", licensesDir)
+	}
+}
@@ -23,7 +23,7 @@ import (
 )
 
 var (
-	licenseRegexp = regexp.MustCompile(`^(?i)(LICEN(S|C)E|COPYING|README|NOTICE)(\..+)?$`)
+	licenseRegexp = regexp.MustCompile(`^(?i)(LICEN(S|C)E|COPYING|COPYRIGHT|UNLICENSE|README|NOTICE)(\..+)?$`)
 	srcDirRegexps = func() []*regexp.Regexp {
 		var rs []*regexp.Regexp
 		for _, s := range build.Default.SrcDirs() {
@@ -36,12 +36,21 @@ var (
 
 // Find returns the file path of the license for this package.
 func Find(dir string, classifier Classifier) (string, error) {
+	return findLicense(dir, classifier, nil)
+}
+
+// findLicense is like Find, and calls rejected, if not nil, with paths of
+// license files skipped because their licenses cannot be identified.
+func findLicense(dir string, classifier Classifier, rejected func(path string)) (string, error) {
 	var stopAt []*regexp.Regexp
 	stopAt = append(stopAt, srcDirRegexps...)
 	stopAt = append(stopAt, vendorRegexp)
 	return findUpwards(dir, licenseRegexp, stopAt, func(path string) bool {
 		// TODO(RJPercival): Return license details
 		if _, _, err := classifier.Identify(path); err != nil {
+			if rejected != nil {
+				rejected(path)
+			}
 			return false
 		}
 		return true
@@ -28,22 +28,28 @@ func TestFind(t *testing.T) {
 
 	classifier := classifierStub{
 		licenseNames: map[string]string{
-			"testdata/LICENSE":           "foo",
-			"testdata/MIT/LICENSE.MIT":   "MIT",
-			"testdata/licence/LICENCE":   "foo",
-			"testdata/copying/COPYING":   "foo",
-			"testdata/notice/NOTICE.txt": "foo",
-			"testdata/readme/README.md":  "foo",
-			"testdata/lowercase/license": "foo",
+			"testdata/LICENSE":                      "foo",
+			"testdata/MIT/LICENSE.MIT":              "MIT",
+			"testdata/licence/LICENCE":              "foo",
+			"testdata/copying/COPYING":              "foo",
+			"testdata/copyinglesser/COPYING.LESSER": "foo",
+			"testdata/copyright/COPYRIGHT":          "foo",
+			"testdata/unlicense/UNLICENSE":          "foo",
+			"testdata/notice/NOTICE.txt":            "foo",
+			"testdata/readme/README.md":             "foo",
+			"testdata/lowercase/license":            "foo",
 		},
 		licenseTypes: map[string]Type{
-			"testdata/LICENSE":           Notice,
-			"testdata/MIT/LICENSE.MIT":   Notice,
-			"testdata/licence/LICENCE":   Notice,
-			"testdata/copying/COPYING":   Notice,
-			"testdata/notice/NOTICE.txt": Notice,
-			"testdata/readme/README.md":  Notice,
-			"testdata/lowercase/license": Notice,
+			"testdata/LICENSE":                      Notice,
+			"testdata/MIT/LICENSE.MIT":              Notice,
+			"testdata/licence/LICENCE":              Notice,
+			"testdata/copying/COPYING":              Notice,
+			"testdata/copyinglesser/COPYING.LESSER": Notice,
+			"testdata/copyright/COPYRIGHT":          Notice,
+			"testdata/unlicense/UNLICENSE":          Unencumbered,
+			"testdata/notice/NOTICE.txt":            Notice,
+			"testdata/readme/README.md":             Notice,
+			"testdata/lowercase/license":            Notice,
 		},
 	}
 
@@ -72,6 +78,21 @@ func TestFind(t *testing.T) {
 			dir:             "testdata/copying",
 			wantLicensePath: filepath.Join(wd, "testdata/copying/COPYING"),
 		},
+		{
+			desc:            "COPYING.LESSER",
+			dir:             "testdata/copyinglesser",
+			wantLicensePath: filepath.Join(wd, "testdata/copyinglesser/COPYING.LESSER"),
+		},
+		{
+			desc:            "COPYRIGHT",
+			dir:             "testdata/copyright",
+			wantLicensePath: filepath.Join(wd, "testdata/copyright/COPYRIGHT"),
+		},
+		{
+			desc:            "UNLICENSE",
+			dir:             "testdata/unlicense",
+			wantLicensePath: filepath.Join(wd, "testdata/unlicense/UNLICENSE"),
+		},
 		{
 			desc:            "NOTICE",
 			dir:             "testdata/notice",
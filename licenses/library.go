@@ -24,8 +24,8 @@ import (
 	"sort"
 	"strings"
 
-	"github.com/golang/glog"
 	"golang.org/x/tools/go/packages"
+	"k8s.io/klog/v2"
 )
 
 var (
@@ -43,6 +43,18 @@ type Library struct {
 	// Packages contains import paths for Go packages in this library.
 	// It may not be the complete set of all packages in the library.
 	Packages []string
+	// BelowThreshold contains the best matches of license files skipped
+	// while finding the library's license, because their confidence is
+	// below the threshold. It's only set when
+	// LibrariesOptions.ReportBelowThreshold is true.
+	BelowThreshold []FileMatch
+}
+
+// FileMatch is the best matching license in a file.
+type FileMatch struct {
+	// Path of the file.
+	Path string
+	Match
 }
 
 // PackagesError aggregates all Packages[].Errors into a single error.
@@ -66,6 +78,31 @@ func (e PackagesError) Error() string {
 // Packages not covered by a license will be returned as individual libraries.
 // Standard library packages will be ignored.
 func Libraries(ctx context.Context, classifier Classifier, importPaths ...string) ([]*Library, error) {
+	return LibrariesWithOptions(ctx, classifier, LibrariesOptions{}, importPaths...)
+}
+
+// LibrariesOptions customizes which packages are returned by
+// LibrariesWithOptions.
+type LibrariesOptions struct {
+	// Ignore contains import path prefixes or globs, e.g.
+	// "github.com/acme" or "github.com/acme/*", of packages that are
+	// skipped without looking for their licenses. A prefix matches whole
+	// path elements, and a glob matches a package or any of its parents.
+	// Dependencies of ignored packages are still returned.
+	Ignore []string
+	// ReportBelowThreshold reports the best matches of license files whose
+	// confidence is below the threshold in Library.BelowThreshold, when the
+	// classifier is a NearestClassifier.
+	ReportBelowThreshold bool
+}
+
+// LibrariesWithOptions is like Libraries, but customized by options.
+func LibrariesWithOptions(ctx context.Context, classifier Classifier, options LibrariesOptions, importPaths ...string) ([]*Library, error) {
+	for _, pattern := range options.Ignore {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid ignore pattern %q: %v", pattern, err)
+		}
+	}
 	cfg := &packages.Config{
 		Context: ctx,
 		Mode:    packages.NeedImports | packages.NeedDeps | packages.NeedFiles | packages.NeedName,
@@ -78,6 +115,8 @@ func Libraries(ctx context.Context, classifier Classifier, importPaths ...string
 
 	pkgs := map[string]*packages.Package{}
 	pkgsByLicense := make(map[string][]*packages.Package)
+	// import path -> license files skipped while finding its license
+	rejectedByPkg := make(map[string][]string)
 	errorOccurred := false
 	packages.Visit(rootPkgs, func(p *packages.Package) bool {
 		if len(p.Errors) > 0 {
@@ -88,8 +127,12 @@ func Libraries(ctx context.Context, classifier Classifier, importPaths ...string
 			// No license requirements for the Go standard library.
 			return false
 		}
+		if isIgnored(p.PkgPath, options.Ignore) {
+			// Its dependencies may not be ignored.
+			return true
+		}
 		if len(p.OtherFiles) > 0 {
-			glog.Warningf("%q contains non-Go code that can't be inspected for further dependencies:\n%s", p.PkgPath, strings.Join(p.OtherFiles, "\n"))
+			klog.Warningf("%q contains non-Go code that can't be inspected for further dependencies:\n%s", p.PkgPath, strings.Join(p.OtherFiles, "\n"))
 		}
 		var pkgDir string
 		switch {
@@ -103,9 +146,15 @@ func Libraries(ctx context.Context, classifier Classifier, importPaths ...string
 			// This package is empty - nothing to do.
 			return true
 		}
-		licensePath, err := Find(pkgDir, classifier)
+		var rejected func(path string)
+		if options.ReportBelowThreshold {
+			rejected = func(path string) {
+				rejectedByPkg[p.PkgPath] = append(rejectedByPkg[p.PkgPath], path)
+			}
+		}
+		licensePath, err := findLicense(pkgDir, classifier, rejected)
 		if err != nil {
-			glog.Errorf("Failed to find license for %s: %v", p.PkgPath, err)
+			klog.Errorf("Failed to find license for %s: %v", p.PkgPath, err)
 		}
 		pkgs[p.PkgPath] = p
 		pkgsByLicense[licensePath] = append(pkgsByLicense[licensePath], p)
@@ -117,19 +166,51 @@ func Libraries(ctx context.Context, classifier Classifier, importPaths ...string
 		}
 	}
 
+	// license file -> its best match, each file is only matched once
+	nearestMatches := make(map[string]*FileMatch)
+	belowThreshold := func(pkgs []*packages.Package) []FileMatch {
+		nearest, ok := classifier.(NearestClassifier)
+		if !ok {
+			return nil
+		}
+		var matches []FileMatch
+		seen := make(map[string]bool)
+		for _, p := range pkgs {
+			for _, path := range rejectedByPkg[p.PkgPath] {
+				if seen[path] {
+					continue
+				}
+				seen[path] = true
+				m, ok := nearestMatches[path]
+				if !ok {
+					if match, err := nearest.NearestMatch(path); err == nil {
+						m = &FileMatch{Path: path, Match: match}
+					}
+					nearestMatches[path] = m
+				}
+				if m != nil {
+					matches = append(matches, *m)
+				}
+			}
+		}
+		return matches
+	}
+
 	var libraries []*Library
 	for licensePath, pkgs := range pkgsByLicense {
 		if licensePath == "" {
 			// No license for these packages - return each one as a separate library.
 			for _, p := range pkgs {
 				libraries = append(libraries, &Library{
-					Packages: []string{p.PkgPath},
+					Packages:       []string{p.PkgPath},
+					BelowThreshold: belowThreshold([]*packages.Package{p}),
 				})
 			}
 			continue
 		}
 		lib := &Library{
-			LicensePath: licensePath,
+			LicensePath:    licensePath,
+			BelowThreshold: belowThreshold(pkgs),
 		}
 		for _, pkg := range pkgs {
 			lib.Packages = append(lib.Packages, pkg.PkgPath)
@@ -139,6 +220,22 @@ func Libraries(ctx context.Context, classifier Classifier, importPaths ...string
 	return libraries, nil
 }
 
+// isIgnored reports whether pkgPath matches any of the ignore patterns, see
+// LibrariesOptions.Ignore.
+func isIgnored(pkgPath string, patterns []string) bool {
+	for _, pattern := range patterns {
+		for p := pkgPath; p != "." && p != "/"; p = path.Dir(p) {
+			if p == pattern {
+				return true
+			}
+			if matched, _ := path.Match(pattern, p); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // Name is the common prefix of the import paths for all of the packages in this library.
 func (l *Library) Name() string {
 	return commonAncestor(l.Packages)
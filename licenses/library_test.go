@@ -17,6 +17,7 @@ package licenses
 import (
 	"context"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -41,6 +42,7 @@ func TestLibraries(t *testing.T) {
 		desc       string
 		importPath string
 		goflags    string
+		ignore     []string
 		wantLibs   []string
 	}{
 		{
@@ -69,13 +71,30 @@ func TestLibraries(t *testing.T) {
 				"github.com/google/go-licenses/licenses/testdata/indirect",
 			},
 		},
+		{
+			desc:       "Ignored prefix",
+			importPath: "github.com/google/go-licenses/licenses/testdata",
+			ignore:     []string{"github.com/google/go-licenses/licenses/testdata/direct"},
+			wantLibs: []string{
+				"github.com/google/go-licenses/licenses/testdata",
+				"github.com/google/go-licenses/licenses/testdata/indirect",
+			},
+		},
+		{
+			desc:       "Ignored glob",
+			importPath: "github.com/google/go-licenses/licenses/testdata",
+			ignore:     []string{"github.com/google/go-licenses/licenses/testdata/*"},
+			wantLibs: []string{
+				"github.com/google/go-licenses/licenses/testdata",
+			},
+		},
 	} {
 		t.Run(test.desc, func(t *testing.T) {
 			if test.goflags != "" {
 				os.Setenv("GOFLAGS", test.goflags)
 				defer os.Unsetenv("GOFLAGS")
 			}
-			gotLibs, err := Libraries(context.Background(), classifier, test.importPath)
+			gotLibs, err := LibrariesWithOptions(context.Background(), classifier, LibrariesOptions{Ignore: test.ignore}, test.importPath)
 			if err != nil {
 				t.Fatalf("Libraries(_, %q) = (_, %q), want (_, nil)", test.importPath, err)
 			}
@@ -90,6 +109,74 @@ func TestLibraries(t *testing.T) {
 	}
 }
 
+// nearestClassifierStub is a classifierStub that also reports programmed
+// nearest matches.
+type nearestClassifierStub struct {
+	classifierStub
+	nearest map[string]Match
+}
+
+func (c nearestClassifierStub) NearestMatch(licensePath string) (Match, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return Match{}, err
+	}
+	relPath, err := filepath.Rel(wd, licensePath)
+	if err != nil {
+		return Match{}, err
+	}
+	if m, ok := c.nearest[relPath]; ok {
+		return m, nil
+	}
+	return Match{}, errUnknownLicense
+}
+
+func TestLibraries_ReportBelowThreshold(t *testing.T) {
+	classifier := nearestClassifierStub{
+		classifierStub: classifierStub{
+			licenseNames: map[string]string{
+				"testdata/LICENSE":          "foo",
+				"testdata/indirect/LICENSE": "foo",
+			},
+			licenseTypes: map[string]Type{
+				"testdata/LICENSE":          Notice,
+				"testdata/indirect/LICENSE": Notice,
+			},
+			errors: map[string]error{
+				"testdata/direct/LICENSE": errUnknownLicense,
+			},
+		},
+		nearest: map[string]Match{
+			"testdata/direct/LICENSE": {Name: "MIT", Type: Notice, Confidence: 0.85},
+		},
+	}
+	const importPath = "github.com/google/go-licenses/licenses/testdata/direct"
+	for _, report := range []bool{false, true} {
+		libs, err := LibrariesWithOptions(context.Background(), classifier, LibrariesOptions{ReportBelowThreshold: report}, importPath)
+		if err != nil {
+			t.Fatalf("LibrariesWithOptions(_, %q) = (_, %q), want (_, nil)", importPath, err)
+		}
+		var got []FileMatch
+		for _, lib := range libs {
+			got = append(got, lib.BelowThreshold...)
+		}
+		var want []FileMatch
+		if report {
+			wd, err := os.Getwd()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want = []FileMatch{{
+				Path:  filepath.Join(wd, "testdata/direct/LICENSE"),
+				Match: Match{Name: "MIT", Type: Notice, Confidence: 0.85},
+			}}
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("LibrariesWithOptions(ReportBelowThreshold=%v) below threshold diff (-want +got):\n%s", report, diff)
+		}
+	}
+}
+
 func TestLibraryName(t *testing.T) {
 	for _, test := range []struct {
 		desc     string
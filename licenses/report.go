@@ -0,0 +1,192 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+// ReportOptions customizes Report.
+type ReportOptions struct {
+	// Classifier identifies licenses of libraries, it's required.
+	Classifier Classifier
+	// LibrariesOptions customizes which libraries are reported.
+	LibrariesOptions LibrariesOptions
+	// GitRemotes are the names of remote Git repositories tried in order to
+	// find license URLs. Defaults to "origin" and "upstream".
+	GitRemotes []string
+	// SkipURLs skips finding license URLs, e.g. when only checking licenses.
+	SkipURLs bool
+	// AllLicenses identifies every license in each license file, e.g. in a
+	// document that concatenates several licenses, when Classifier is a
+	// MultiClassifier.
+	AllLicenses bool
+	// ImportPaths are the packages whose dependencies are reported.
+	ImportPaths []string
+}
+
+// LibraryLicense is a library with its identified license.
+type LibraryLicense struct {
+	*Library
+	// LicenseName is the name of the license, e.g. "MIT", or "Unknown" when
+	// no license file is found or it cannot be identified.
+	LicenseName string
+	// LicenseType is the type of the license, Unknown when it's not
+	// identified.
+	LicenseType Type
+	// LicenseURL is where the license file can be viewed, empty when it's
+	// not found.
+	LicenseURL string
+	// Confidence of the license match between 0 and 1, 0 when the license
+	// is not identified or the classifier does not report confidence.
+	Confidence float64
+	// Licenses are all licenses found in the license file ordered by
+	// confidence, when ReportOptions.AllLicenses is set and the classifier
+	// is a MultiClassifier. The first one is the license of LicenseName.
+	Licenses []Match
+	// Err is the error identifying the license, if any.
+	Err error
+}
+
+// LicenseReport is the result of Report.
+type LicenseReport struct {
+	// Libraries are sorted by name.
+	Libraries []*LibraryLicense
+}
+
+// Violations returns libraries whose licenses are forbidden, including any
+// of their Licenses.
+func (r *LicenseReport) Violations() []*LibraryLicense {
+	var violations []*LibraryLicense
+	for _, lib := range r.Libraries {
+		if lib.LicenseType == Forbidden {
+			violations = append(violations, lib)
+			continue
+		}
+		for _, m := range lib.Licenses {
+			if m.Type == Forbidden {
+				violations = append(violations, lib)
+				break
+			}
+		}
+	}
+	return violations
+}
+
+// Report finds the libraries used by packages in options, directly or
+// transitively, and identifies their licenses the same way as the csv and
+// check commands.
+func Report(ctx context.Context, options ReportOptions) (*LicenseReport, error) {
+	if options.Classifier == nil {
+		return nil, errors.New("a classifier is required")
+	}
+	gitRemotes := options.GitRemotes
+	if len(gitRemotes) == 0 {
+		gitRemotes = []string{"origin", "upstream"}
+	}
+	libs, err := LibrariesWithOptions(ctx, options.Classifier, options.LibrariesOptions, options.ImportPaths...)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(libs, func(i, j int) bool {
+		return libs[i].Name() < libs[j].Name()
+	})
+	report := &LicenseReport{}
+	for _, lib := range libs {
+		license := identifyLibrary(lib, options.Classifier)
+		if options.AllLicenses && license.Err == nil {
+			identifyAll(license, options.Classifier)
+		}
+		if lib.LicensePath != "" && !options.SkipURLs {
+			license.LicenseURL = licenseURL(lib, gitRemotes)
+		}
+		report.Libraries = append(report.Libraries, license)
+	}
+	return report, nil
+}
+
+// identifyLibrary identifies the license of lib.
+func identifyLibrary(lib *Library, classifier Classifier) *LibraryLicense {
+	result := &LibraryLicense{
+		Library:     lib,
+		LicenseName: "Unknown",
+		LicenseType: Unknown,
+	}
+	if lib.LicensePath == "" {
+		return result
+	}
+	var err error
+	if c, ok := classifier.(ConfidenceClassifier); ok {
+		var m Match
+		m, err = c.IdentifyMatch(lib.LicensePath)
+		result.LicenseName, result.LicenseType, result.Confidence = m.Name, m.Type, m.Confidence
+	} else {
+		result.LicenseName, result.LicenseType, err = classifier.Identify(lib.LicensePath)
+	}
+	if err != nil {
+		klog.Errorf("Error identifying license in %q: %v", lib.LicensePath, err)
+		result.LicenseName = "Unknown"
+		result.LicenseType = Unknown
+		result.Confidence = 0
+		result.Err = err
+	}
+	return result
+}
+
+// identifyAll identifies all licenses in the license file of lib, when
+// classifier is a MultiClassifier.
+func identifyAll(lib *LibraryLicense, classifier Classifier) {
+	c, ok := classifier.(MultiClassifier)
+	if !ok || lib.LicensePath == "" {
+		return
+	}
+	matches, err := c.IdentifyAll(lib.LicensePath)
+	if err != nil {
+		klog.Errorf("Error identifying all licenses in %q: %v", lib.LicensePath, err)
+		lib.Err = err
+		return
+	}
+	lib.Licenses = matches
+}
+
+// licenseURL finds a URL for the license file of lib, based on the URL of a
+// remote for its Git repository, or empty when it's not found.
+func licenseURL(lib *Library, gitRemotes []string) string {
+	var errs []string
+	repo, err := FindGitRepo(lib.LicensePath)
+	if err != nil {
+		// Can't find Git repo (possibly a Go Module?) - derive URL from lib name instead.
+		url, err := lib.FileURL(lib.LicensePath)
+		if err == nil {
+			return url.String()
+		}
+		errs = append(errs, err.Error())
+	} else {
+		for _, remote := range gitRemotes {
+			url, err := repo.FileURL(lib.LicensePath, remote)
+			if err == nil {
+				return url.String()
+			}
+			errs = append(errs, err.Error())
+		}
+	}
+	klog.Errorf("Error discovering URL for %q:\n- %s", lib.LicensePath, strings.Join(errs, "\n- "))
+	return ""
+}
@@ -0,0 +1,165 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReport(t *testing.T) {
+	classifier := classifierStub{
+		licenseNames: map[string]string{
+			"testdata/LICENSE":          "foo",
+			"testdata/direct/LICENSE":   "foo",
+			"testdata/indirect/LICENSE": "bar",
+		},
+		licenseTypes: map[string]Type{
+			"testdata/LICENSE":          Notice,
+			"testdata/direct/LICENSE":   Notice,
+			"testdata/indirect/LICENSE": Forbidden,
+		},
+	}
+	report, err := Report(context.Background(), ReportOptions{
+		Classifier:  classifier,
+		SkipURLs:    true,
+		ImportPaths: []string{"github.com/google/go-licenses/licenses/testdata"},
+	})
+	if err != nil {
+		t.Fatalf("Report() = (_, %q), want (_, nil)", err)
+	}
+	type result struct {
+		Name        string
+		LicenseName string
+		LicenseType Type
+	}
+	summarize := func(libs []*LibraryLicense) []result {
+		var results []result
+		for _, lib := range libs {
+			results = append(results, result{Name: lib.Name(), LicenseName: lib.LicenseName, LicenseType: lib.LicenseType})
+		}
+		return results
+	}
+	wantLibs := []result{
+		{Name: "github.com/google/go-licenses/licenses/testdata", LicenseName: "foo", LicenseType: Notice},
+		{Name: "github.com/google/go-licenses/licenses/testdata/direct", LicenseName: "foo", LicenseType: Notice},
+		{Name: "github.com/google/go-licenses/licenses/testdata/indirect", LicenseName: "bar", LicenseType: Forbidden},
+	}
+	if diff := cmp.Diff(wantLibs, summarize(report.Libraries)); diff != "" {
+		t.Errorf("Report() libraries diff (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(wantLibs[2:], summarize(report.Violations())); diff != "" {
+		t.Errorf("Violations() diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestReport_NoClassifier(t *testing.T) {
+	if _, err := Report(context.Background(), ReportOptions{}); err == nil {
+		t.Errorf("Report() = (_, nil), want an error")
+	}
+}
+
+// multiClassifierStub is a classifierStub that also identifies all licenses
+// of a file.
+type multiClassifierStub struct {
+	classifierStub
+	licenses map[string][]Match
+}
+
+func (c multiClassifierStub) IdentifyAll(licensePath string) ([]Match, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	relPath, err := filepath.Rel(wd, licensePath)
+	if err != nil {
+		return nil, err
+	}
+	return c.licenses[relPath], nil
+}
+
+func TestReport_AllLicenses(t *testing.T) {
+	classifier := multiClassifierStub{
+		classifierStub: classifierStub{
+			licenseNames: map[string]string{
+				"testdata/LICENSE":          "foo",
+				"testdata/direct/LICENSE":   "foo",
+				"testdata/indirect/LICENSE": "bar",
+			},
+			licenseTypes: map[string]Type{
+				"testdata/LICENSE":          Notice,
+				"testdata/direct/LICENSE":   Notice,
+				"testdata/indirect/LICENSE": Notice,
+			},
+		},
+		licenses: map[string][]Match{
+			"testdata/LICENSE":          {{Name: "foo", Type: Notice}},
+			"testdata/direct/LICENSE":   {{Name: "foo", Type: Notice}},
+			"testdata/indirect/LICENSE": {{Name: "bar", Type: Notice}, {Name: "baz", Type: Forbidden}},
+		},
+	}
+	for _, test := range []struct {
+		desc           string
+		allLicenses    bool
+		wantLicenses   [][]Match
+		wantViolations []string
+	}{
+		{
+			desc:         "top match only",
+			allLicenses:  false,
+			wantLicenses: [][]Match{nil, nil, nil},
+		},
+		{
+			desc:        "all licenses",
+			allLicenses: true,
+			wantLicenses: [][]Match{
+				{{Name: "foo", Type: Notice}},
+				{{Name: "foo", Type: Notice}},
+				{{Name: "bar", Type: Notice}, {Name: "baz", Type: Forbidden}},
+			},
+			wantViolations: []string{"github.com/google/go-licenses/licenses/testdata/indirect"},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			report, err := Report(context.Background(), ReportOptions{
+				Classifier:  classifier,
+				SkipURLs:    true,
+				AllLicenses: test.allLicenses,
+				ImportPaths: []string{"github.com/google/go-licenses/licenses/testdata"},
+			})
+			if err != nil {
+				t.Fatalf("Report() = (_, %q), want (_, nil)", err)
+			}
+			var gotLicenses [][]Match
+			for _, lib := range report.Libraries {
+				gotLicenses = append(gotLicenses, lib.Licenses)
+			}
+			if diff := cmp.Diff(test.wantLicenses, gotLicenses); diff != "" {
+				t.Errorf("Report() licenses diff (-want +got):\n%s", diff)
+			}
+			var gotViolations []string
+			for _, lib := range report.Violations() {
+				gotViolations = append(gotViolations, lib.Name())
+			}
+			if diff := cmp.Diff(test.wantViolations, gotViolations); diff != "" {
+				t.Errorf("Violations() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
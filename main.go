@@ -16,10 +16,12 @@ package main
 
 import (
 	"flag"
+	"os"
 	"strings"
 
-	"github.com/golang/glog"
+	"github.com/google/go-licenses/licenses"
 	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
 )
 
 var (
@@ -29,19 +31,61 @@ var (
 
 	// Flags shared between subcommands
 	confidenceThreshold float64
+	classifierCacheDir  string
+	licensesDir         string
+	ignore              []string
 )
 
 func init() {
 	rootCmd.PersistentFlags().Float64Var(&confidenceThreshold, "confidence_threshold", 0.9, "Minimum confidence required in order to positively identify a license.")
+	rootCmd.PersistentFlags().StringVar(&classifierCacheDir, "classifier_cache_dir", "", "Directory to cache license classification results in, so that unchanged license files are not classified again in later runs.")
+	rootCmd.PersistentFlags().StringVar(&licensesDir, "licenses_dir", "", "Directory of custom license texts organized as <type>/<ID>.txt, e.g. restricted/LicenseRef-Acme.txt, identified in addition to known open source licenses.")
+	rootCmd.PersistentFlags().StringSliceVar(&ignore, "ignore", nil, "Comma separated import path prefixes or globs of packages to skip without looking for their licenses, e.g. github.com/acme/* for private repos. Ignored packages are omitted from the output, but their dependencies are not.")
 }
 
 func main() {
+	klog.InitFlags(nil)
 	flag.Parse()
 	rootCmd.PersistentFlags().AddGoFlagSet(flag.CommandLine)
 
-	if err := rootCmd.Execute(); err != nil {
-		glog.Exit(err)
+	// Errors are logged here instead, so that they're logged by klog.
+	rootCmd.SilenceErrors = true
+	err := rootCmd.Execute()
+	if err != nil {
+		klog.Error(err)
 	}
+	klog.Flush()
+	os.Exit(exitCode(err))
+}
+
+// newClassifier creates a license classifier using the shared flags.
+func newClassifier() (licenses.Classifier, error) {
+	return licenses.NewClassifierWithOptions(confidenceThreshold, licenses.ClassifierOptions{
+		CacheDir:    classifierCacheDir,
+		LicensesDir: licensesDir,
+	})
+}
+
+// librariesOptions returns options of finding libraries using the shared
+// flags.
+func librariesOptions() licenses.LibrariesOptions {
+	return licenses.LibrariesOptions{
+		Ignore: ignore,
+	}
+}
+
+// licenseNames returns the names of all licenses of lib separated by commas,
+// e.g. "Apache-2.0,MIT", or its license name when all licenses are not
+// identified.
+func licenseNames(lib *licenses.LibraryLicense) string {
+	if len(lib.Licenses) == 0 {
+		return lib.LicenseName
+	}
+	names := make([]string, 0, len(lib.Licenses))
+	for _, m := range lib.Licenses {
+		names = append(names, m.Name)
+	}
+	return strings.Join(names, ",")
 }
 
 // Unvendor removes the "*/vendor/" prefix from the given import path, if present.
@@ -15,18 +15,22 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 
-	"github.com/golang/glog"
 	"github.com/google/go-licenses/licenses"
 	"github.com/otiai10/copy"
 	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
 )
 
 var (
@@ -34,65 +38,175 @@ var (
 		Use:   "save <package>",
 		Short: "Saves licenses, copyright notices and source code, as required by a Go package's dependencies, to a directory.",
 		Args:  cobra.MinimumNArgs(1),
-		RunE:  saveMain,
+		RunE:  runE(saveMain),
 	}
 
-	noticeRegexp = regexp.MustCompile(`^NOTICE(\.(txt|md))?$`)
+	// noticeNames are regexps of file names of copyright notices, copied
+	// along with licenses.
+	noticeNames   []string
+	noticeRegexps = []*regexp.Regexp{regexp.MustCompile(defaultNoticeName)}
 
 	// savePath is where the output of the command is written to.
 	savePath string
 	// overwriteSavePath controls behaviour when the directory indicated by savePath already exists.
 	// If true, the directory will be replaced. If false, the command will fail.
 	overwriteSavePath bool
+	// mergeSavePath updates the directory indicated by savePath in place when
+	// it already exists, instead of failing.
+	mergeSavePath bool
+	// noticeFile is where licenses and notices of all libraries are also
+	// written to as a single file, if not empty.
+	noticeFile string
+	// saveConcurrency is the number of libraries saved concurrently.
+	saveConcurrency int
+	// includeGoMod controls whether go.mod and go.sum of the main module are
+	// also saved, to document the exact versions of libraries.
+	includeGoMod bool
+	// saveLayout is how libraries are organized in savePath, one of
+	// layoutFlat or layoutByType.
+	saveLayout string
+	// unknownAction is what happens to libraries whose licenses are unknown,
+	// one of unknownActionFail, unknownActionWarn or unknownActionSkip.
+	unknownAction string
+)
+
+const defaultNoticeName = `^NOTICE(\.(txt|md))?$`
+
+const (
+	// layoutFlat saves every library to savePath/<library>.
+	layoutFlat = "flat"
+	// layoutByType saves libraries whose source code is saved to
+	// savePath/source/<library>, and other libraries to
+	// savePath/notices/<library>.
+	layoutByType = "by_type"
+)
+
+const (
+	// unknownActionFail fails the command, nothing is saved.
+	unknownActionFail = "fail"
+	// unknownActionWarn saves other libraries, and warns about libraries
+	// whose licenses are unknown.
+	unknownActionWarn = "warn"
+	// unknownActionSkip saves other libraries silently.
+	unknownActionSkip = "skip"
 )
 
 func init() {
 	saveCmd.Flags().StringVar(&savePath, "save_path", "", "Directory into which files should be saved that are required by license terms")
 	if err := saveCmd.MarkFlagRequired("save_path"); err != nil {
-		glog.Fatal(err)
+		klog.Fatal(err)
 	}
 	if err := saveCmd.MarkFlagFilename("save_path"); err != nil {
-		glog.Fatal(err)
+		klog.Fatal(err)
 	}
 
 	saveCmd.Flags().BoolVar(&overwriteSavePath, "force", false, "Delete the destination directory if it already exists.")
+	saveCmd.Flags().BoolVar(&mergeSavePath, "merge", false, "Update the destination directory in place if it already exists, instead of failing. Directories of saved libraries are replaced, other files are kept.")
+	saveCmd.Flags().StringSliceVar(&noticeNames, "notice_names", []string{defaultNoticeName}, "Comma separated regexps of file names of copyright notices, copied from the directory of each license, e.g. ^AUTHORS$")
+	saveCmd.Flags().BoolVar(&includeGoMod, "include_gomod", false, "Also save go.mod and go.sum of the main module, to document the exact versions of libraries whose source code is saved")
+	saveCmd.Flags().IntVar(&saveConcurrency, "concurrency", 0, "Number of libraries whose licenses and source code are saved concurrently, defaults to GOMAXPROCS")
+	saveCmd.Flags().StringVar(&saveLayout, "layout", layoutFlat, "How libraries are organized in the save path: flat, or by_type to separate libraries whose source code is saved into source/ from others in notices/")
+	saveCmd.Flags().StringVar(&unknownAction, "unknown_action", unknownActionFail, "What to do with libraries whose licenses are unknown: fail, or warn or skip to save other libraries without them. Forbidden licenses always fail")
+	saveCmd.Flags().StringVar(&noticeFile, "notice_file", "", "File into which licenses and copyright notices of all libraries are also written, each preceded by a header with the library name")
 
 	rootCmd.AddCommand(saveCmd)
 }
 
 func saveMain(_ *cobra.Command, args []string) error {
+	var err error
+	noticeRegexps, err = compileNoticeNames(noticeNames)
+	if err != nil {
+		return usageError(err)
+	}
+	if saveLayout != layoutFlat && saveLayout != layoutByType {
+		return usageError(fmt.Errorf("invalid --layout %q, must be %s or %s", saveLayout, layoutFlat, layoutByType))
+	}
+	if unknownAction != unknownActionFail && unknownAction != unknownActionWarn && unknownAction != unknownActionSkip {
+		return usageError(fmt.Errorf("invalid --unknown_action %q, must be %s, %s or %s", unknownAction, unknownActionFail, unknownActionWarn, unknownActionSkip))
+	}
+	if overwriteSavePath && mergeSavePath {
+		return usageError(fmt.Errorf("--force and --merge cannot be used at the same time"))
+	}
 	if overwriteSavePath {
 		if err := os.RemoveAll(savePath); err != nil {
 			return err
 		}
 	}
 
-	// Check that the save path doesn't exist, otherwise it'd end up with a mix of
-	// existing files and the output of this command.
-	if d, err := os.Open(savePath); err == nil {
-		d.Close()
-		return fmt.Errorf("%s already exists", savePath)
-	} else if !os.IsNotExist(err) {
-		return err
+	if !mergeSavePath {
+		// Check that the save path doesn't exist, otherwise it'd end up with a mix of
+		// existing files and the output of this command.
+		if d, err := os.Open(savePath); err == nil {
+			d.Close()
+			return usageError(fmt.Errorf("%s already exists, pass --force to replace it or --merge to update it", savePath))
+		} else if !os.IsNotExist(err) {
+			return err
+		}
 	}
 
-	classifier, err := licenses.NewClassifier(confidenceThreshold)
+	classifier, err := newClassifier()
 	if err != nil {
 		return err
 	}
 
-	libs, err := licenses.Libraries(context.Background(), classifier, args...)
+	libs, err := licenses.LibrariesWithOptions(context.Background(), classifier, librariesOptions(), args...)
 	if err != nil {
 		return err
 	}
-	libsWithBadLicenses := make(map[licenses.Type][]*licenses.Library)
-	for _, lib := range libs {
-		libSaveDir := filepath.Join(savePath, unvendor(lib.Name()))
+	// Sort libraries, so that the notice file is deterministic.
+	sort.Slice(libs, func(i, j int) bool {
+		return libs[i].Name() < libs[j].Name()
+	})
+	// Libraries sharing a source directory are only saved once.
+	savedAs := dedupeSourceRoots(libs)
+	aliases := make(map[int][]string)
+	for i, j := range savedAs {
+		if i != j {
+			aliases[j] = append(aliases[j], unvendor(libs[i].Name()))
+		}
+	}
+	// Libraries are saved concurrently, results are stored by index so that
+	// output does not depend on concurrency.
+	errs := make([]error, len(libs))
+	notices := make([][]byte, len(libs))
+	licenseTypes := make([]licenses.Type, len(libs))
+	var mu sync.Mutex // guards libsWithBadLicenses and unknownLibs
+	libsWithBadLicenses := make(map[licenses.Type][]string)
+	// libraries with unknown licenses that are not saved, unless
+	// --unknown_action is fail
+	var unknownLibs []string
+	reject := func(lib *licenses.Library, licenseType licenses.Type) {
+		mu.Lock()
+		defer mu.Unlock()
+		if licenseType == licenses.Unknown && unknownAction != unknownActionFail {
+			unknownLibs = append(unknownLibs, lib.Name())
+			return
+		}
+		libsWithBadLicenses[licenseType] = append(libsWithBadLicenses[licenseType], lib.Name())
+	}
+	saveLib := func(i int) error {
+		lib := libs[i]
 		// Detect what type of license this library has and fulfill its requirements, e.g. copy license, copyright notice, source code, etc.
 		_, licenseType, err := classifier.Identify(lib.LicensePath)
 		if err != nil {
 			return err
 		}
+		licenseTypes[i] = licenseType
+		libSaveDir := filepath.Join(savePath, layoutDir(saveLayout, licenseType), unvendor(lib.Name()))
+		if savedAs[i] != i {
+			// Saved by the library it's an alias of.
+			if !isSavedType(licenseType) {
+				reject(lib, licenseType)
+			}
+			return nil
+		}
+		if mergeSavePath && isSavedType(licenseType) {
+			// Replace the library's previous files, so that files it no
+			// longer has are not kept.
+			if err := os.RemoveAll(libSaveDir); err != nil {
+				return err
+			}
+		}
 		switch licenseType {
 		case licenses.Restricted, licenses.Reciprocal:
 			// Copy the entire source directory for the library.
@@ -106,15 +220,139 @@ func saveMain(_ *cobra.Command, args []string) error {
 				return err
 			}
 		default:
-			libsWithBadLicenses[licenseType] = append(libsWithBadLicenses[licenseType], lib)
+			reject(lib, licenseType)
+			return nil
+		}
+		if noticeFile != "" {
+			var buf bytes.Buffer
+			names := append([]string{unvendor(lib.Name())}, aliases[i]...)
+			if err := appendNotices(&buf, strings.Join(names, ", "), lib.LicensePath); err != nil {
+				return err
+			}
+			notices[i] = buf.Bytes()
 		}
+		return nil
+	}
+	forEachConcurrently(len(libs), saveConcurrency, func(i int) {
+		errs[i] = saveLib(i)
+	})
+
+	var firstErr error
+	var errMessages []string
+	for i, err := range errs {
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			errMessages = append(errMessages, fmt.Sprintf("%s: %v", libs[i].Name(), err))
+		}
+	}
+	if len(errMessages) == 1 {
+		return firstErr
+	}
+	if len(errMessages) > 1 {
+		return fmt.Errorf("failed to save %d libraries:\n%s", len(errMessages), strings.Join(errMessages, "\n"))
 	}
 	if len(libsWithBadLicenses) > 0 {
-		return fmt.Errorf("one or more libraries have an incompatible/unknown license: %q", libsWithBadLicenses)
+		for _, names := range libsWithBadLicenses {
+			sort.Strings(names)
+		}
+		return violationError(fmt.Errorf("one or more libraries have an incompatible/unknown license: %q", libsWithBadLicenses))
+	}
+	if len(unknownLibs) > 0 && unknownAction == unknownActionWarn {
+		sort.Strings(unknownLibs)
+		klog.Warningf("%d libraries have an unknown license and are not saved:\n%s", len(unknownLibs), strings.Join(unknownLibs, "\n"))
+	}
+	if noticeFile != "" {
+		if err := ioutil.WriteFile(noticeFile, bytes.Join(notices, nil), 0644); err != nil {
+			return err
+		}
+	}
+	if len(aliases) > 0 {
+		if err := writeAliases(libs, savedAs, licenseTypes); err != nil {
+			return err
+		}
+	}
+	if includeGoMod {
+		if err := copyGoMod(savePath); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+// isSavedType reports whether libraries with licenseType are saved.
+func isSavedType(licenseType licenses.Type) bool {
+	switch licenseType {
+	case licenses.Restricted, licenses.Reciprocal, licenses.Notice, licenses.Permissive, licenses.Unencumbered:
+		return true
+	default:
+		return false
+	}
+}
+
+// aliasesFileName is the file in the save path recording libraries that are
+// saved as other libraries sharing their source directories.
+const aliasesFileName = "aliases.txt"
+
+// dedupeSourceRoots finds libraries whose license directories resolve to the
+// same absolute path, e.g. modules replaced by the same directory or
+// symlinked copies, so that each directory is only saved once. It returns
+// the index of the library each library is saved as, the first one in libs
+// sharing its directory, or its own index.
+func dedupeSourceRoots(libs []*licenses.Library) []int {
+	savedAs := make([]int, len(libs))
+	firstByRoot := make(map[string]int)
+	for i, lib := range libs {
+		savedAs[i] = i
+		if lib.LicensePath == "" {
+			continue
+		}
+		root, err := filepath.Abs(filepath.Dir(lib.LicensePath))
+		if err != nil {
+			continue
+		}
+		if resolved, err := filepath.EvalSymlinks(root); err == nil {
+			root = resolved
+		}
+		if first, ok := firstByRoot[root]; ok {
+			savedAs[i] = first
+			continue
+		}
+		firstByRoot[root] = i
+	}
+	return savedAs
+}
+
+// writeAliases writes aliasesFileName in the save path, with a line like
+// "<library> => <directory in the save path>" for each library saved as
+// another library.
+func writeAliases(libs []*licenses.Library, savedAs []int, licenseTypes []licenses.Type) error {
+	var buf bytes.Buffer
+	for i, j := range savedAs {
+		if i == j || !isSavedType(licenseTypes[j]) {
+			continue
+		}
+		dir := filepath.Join(layoutDir(saveLayout, licenseTypes[j]), unvendor(libs[j].Name()))
+		fmt.Fprintf(&buf, "%s => %s\n", unvendor(libs[i].Name()), filepath.ToSlash(dir))
+	}
+	return ioutil.WriteFile(filepath.Join(savePath, aliasesFileName), buf.Bytes(), 0644)
+}
+
+// layoutDir returns the directory in the save path that libraries with
+// licenseType are saved to in layout.
+func layoutDir(layout string, licenseType licenses.Type) string {
+	if layout != layoutByType {
+		return ""
+	}
+	switch licenseType {
+	case licenses.Restricted, licenses.Reciprocal:
+		return "source"
+	default:
+		return "notices"
+	}
+}
+
 func copySrc(src, dest string) error {
 	// Skip the .git directory for copying, if it exists, since we don't want to save the user's
 	// local Git config along with the source code.
@@ -125,6 +363,53 @@ func copySrc(src, dest string) error {
 	return nil
 }
 
+// copyGoMod copies go.mod and go.sum of the main module to dest. Files that do
+// not exist are skipped, e.g. in GOPATH mode.
+func copyGoMod(dest string) error {
+	out, err := exec.Command("go", "env", "GOMOD").Output()
+	if err != nil {
+		return fmt.Errorf("go env GOMOD: %v", err)
+	}
+	goMod := strings.TrimSpace(string(out))
+	if goMod == "" || goMod == os.DevNull {
+		klog.Warning("Not in a Go module, go.mod and go.sum are not saved")
+		return nil
+	}
+	for _, path := range []string{goMod, filepath.Join(filepath.Dir(goMod), "go.sum")} {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			klog.Warningf("%s does not exist, not saved", path)
+			continue
+		}
+		if err := copy.Copy(path, filepath.Join(dest, filepath.Base(path))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compileNoticeNames compiles regexps of notice file names.
+func compileNoticeNames(names []string) ([]*regexp.Regexp, error) {
+	var regexps []*regexp.Regexp
+	for _, name := range names {
+		r, err := regexp.Compile(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --notice_names %q: %v", name, err)
+		}
+		regexps = append(regexps, r)
+	}
+	return regexps, nil
+}
+
+// isNotice reports whether a file name matches any of noticeRegexps.
+func isNotice(name string) bool {
+	for _, r := range noticeRegexps {
+		if r.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
 func copyNotices(licensePath, dest string) error {
 	if err := copy.Copy(licensePath, filepath.Join(dest, filepath.Base(licensePath))); err != nil {
 		return err
@@ -136,7 +421,7 @@ func copyNotices(licensePath, dest string) error {
 		return err
 	}
 	for _, f := range files {
-		if fName := f.Name(); !f.IsDir() && noticeRegexp.MatchString(fName) {
+		if fName := f.Name(); !f.IsDir() && isNotice(fName) {
 			if err := copy.Copy(filepath.Join(src, fName), filepath.Join(dest, fName)); err != nil {
 				return err
 			}
@@ -144,3 +429,29 @@ func copyNotices(licensePath, dest string) error {
 	}
 	return nil
 }
+
+// appendNotices writes the license and copyright notices of a library to buf,
+// preceded by a header with the library name.
+func appendNotices(buf *bytes.Buffer, name, licensePath string) error {
+	fmt.Fprintf(buf, "============= %s =============\n", name)
+	paths := []string{licensePath}
+	src := filepath.Dir(licensePath)
+	files, err := ioutil.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if fName := f.Name(); !f.IsDir() && isNotice(fName) && fName != filepath.Base(licensePath) {
+			paths = append(paths, filepath.Join(src, fName))
+		}
+	}
+	for _, path := range paths {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		buf.Write(content)
+		buf.WriteString("\n\n")
+	}
+	return nil
+}
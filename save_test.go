@@ -0,0 +1,377 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-licenses/licenses"
+)
+
+func TestAppendNotices(t *testing.T) {
+	src, err := ioutil.TempDir("", "save_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(src)
+	for _, name := range []string{"LICENSE", "NOTICE", "main.go"} {
+		if err := ioutil.WriteFile(filepath.Join(src, name), []byte(name+" text"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := appendNotices(&buf, "example.com/a", filepath.Join(src, "LICENSE")); err != nil {
+		t.Fatalf("appendNotices() = %v, want nil", err)
+	}
+	if err := appendNotices(&buf, "example.com/b", filepath.Join(src, "NOTICE")); err != nil {
+		t.Fatalf("appendNotices() = %v, want nil", err)
+	}
+	want := "============= example.com/a =============\nLICENSE text\n\nNOTICE text\n\n" +
+		// A license file that is also a notice file is written once.
+		"============= example.com/b =============\nNOTICE text\n\n"
+	if got := buf.String(); got != want {
+		t.Errorf("appendNotices() wrote %q, want %q", got, want)
+	}
+}
+
+func TestSaveMain_Concurrency(t *testing.T) {
+	pkgs := []string{
+		"github.com/google/go-licenses/licenses/testdata/direct",
+		"github.com/google/go-licenses/licenses/testdata/indirect",
+	}
+	dir, err := ioutil.TempDir("", "save_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(path, notice string, concurrency int) {
+		savePath, noticeFile, saveConcurrency = path, notice, concurrency
+	}(savePath, noticeFile, saveConcurrency)
+
+	// The notice file is the same no matter how many libraries are saved
+	// concurrently.
+	var want []byte
+	for _, concurrency := range []int{1, 4} {
+		savePath = filepath.Join(dir, fmt.Sprintf("out%d", concurrency))
+		noticeFile = filepath.Join(dir, fmt.Sprintf("NOTICE%d", concurrency))
+		saveConcurrency = concurrency
+		if err := saveMain(nil, pkgs); err != nil {
+			t.Fatalf("saveMain() with --concurrency=%d = %q, want nil", concurrency, err)
+		}
+		for _, pkg := range pkgs {
+			if _, err := os.Stat(filepath.Join(savePath, filepath.FromSlash(pkg), "LICENSE")); err != nil {
+				t.Errorf("LICENSE of %s is not saved with --concurrency=%d: %v", pkg, concurrency, err)
+			}
+		}
+		got, err := ioutil.ReadFile(noticeFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want == nil {
+			want = got
+		} else if !bytes.Equal(got, want) {
+			t.Errorf("notice file with --concurrency=%d = %q, want %q", concurrency, got, want)
+		}
+	}
+}
+
+func TestCopyGoMod(t *testing.T) {
+	dest, err := ioutil.TempDir("", "save_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+	// Tests run in the directory of the main module.
+	if err := copyGoMod(dest); err != nil {
+		t.Fatalf("copyGoMod() = %v, want nil", err)
+	}
+	for _, name := range []string{"go.mod", "go.sum"} {
+		want, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadFile(filepath.Join(dest, name))
+		if err != nil {
+			t.Errorf("%s is not saved: %v", name, err)
+			continue
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("saved %s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestCopyGoMod_WithoutGoSum(t *testing.T) {
+	mod, err := ioutil.TempDir("", "save_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(mod)
+	if err := ioutil.WriteFile(filepath.Join(mod, "go.mod"), []byte("module example.com/a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := os.Chdir(mod); err != nil {
+		t.Fatal(err)
+	}
+
+	dest := filepath.Join(mod, "out")
+	if err := copyGoMod(dest); err != nil {
+		t.Fatalf("copyGoMod() = %v, want nil", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "go.mod")); err != nil {
+		t.Errorf("go.mod is not saved: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "go.sum")); !os.IsNotExist(err) {
+		t.Errorf("go.sum is saved, want it skipped")
+	}
+}
+
+func TestCopyNotices(t *testing.T) {
+	src, err := ioutil.TempDir("", "save_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(src)
+	for _, name := range []string{"LICENSE", "NOTICE", "NOTICES", "AUTHORS", "PATENTS", "main.go"} {
+		if err := ioutil.WriteFile(filepath.Join(src, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func(regexps []*regexp.Regexp) { noticeRegexps = regexps }(noticeRegexps)
+
+	for _, test := range []struct {
+		desc        string
+		noticeNames []string
+		wantFiles   []string
+	}{
+		{
+			desc:        "default",
+			noticeNames: []string{defaultNoticeName},
+			wantFiles:   []string{"LICENSE", "NOTICE"},
+		},
+		{
+			desc:        "NOTICES and AUTHORS",
+			noticeNames: []string{defaultNoticeName, `^NOTICES$`, `^AUTHORS$`},
+			wantFiles:   []string{"AUTHORS", "LICENSE", "NOTICE", "NOTICES"},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			noticeRegexps, err = compileNoticeNames(test.noticeNames)
+			if err != nil {
+				t.Fatalf("compileNoticeNames(%q) = (_, %q), want (_, nil)", test.noticeNames, err)
+			}
+			dest, err := ioutil.TempDir("", "save_test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dest)
+			if err := copyNotices(filepath.Join(src, "LICENSE"), dest); err != nil {
+				t.Fatalf("copyNotices() = %q, want nil", err)
+			}
+			files, err := ioutil.ReadDir(dest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var gotFiles []string
+			for _, f := range files {
+				gotFiles = append(gotFiles, f.Name())
+			}
+			if diff := cmp.Diff(test.wantFiles, gotFiles); diff != "" {
+				t.Errorf("copyNotices() copied diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCompileNoticeNames_Invalid(t *testing.T) {
+	if _, err := compileNoticeNames([]string{"("}); err == nil {
+		t.Errorf("compileNoticeNames(%q) = (_, nil), want an error", "(")
+	}
+}
+
+func TestLayoutDir(t *testing.T) {
+	for _, test := range []struct {
+		layout      string
+		licenseType licenses.Type
+		want        string
+	}{
+		{layout: layoutFlat, licenseType: licenses.Restricted, want: ""},
+		{layout: layoutFlat, licenseType: licenses.Notice, want: ""},
+		{layout: layoutByType, licenseType: licenses.Restricted, want: "source"},
+		{layout: layoutByType, licenseType: licenses.Reciprocal, want: "source"},
+		{layout: layoutByType, licenseType: licenses.Notice, want: "notices"},
+		{layout: layoutByType, licenseType: licenses.Unencumbered, want: "notices"},
+	} {
+		if got := layoutDir(test.layout, test.licenseType); got != test.want {
+			t.Errorf("layoutDir(%q, %v) = %q, want %q", test.layout, test.licenseType, got, test.want)
+		}
+	}
+}
+
+func TestDedupeSourceRoots(t *testing.T) {
+	dir, err := ioutil.TempDir("", "save_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"a", "b"} {
+		if err := os.Mkdir(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name, "LICENSE"), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(dir, "a"), filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	libs := []*licenses.Library{
+		{LicensePath: filepath.Join(dir, "a", "LICENSE")},
+		{LicensePath: filepath.Join(dir, "b", "LICENSE")},
+		{LicensePath: filepath.Join(dir, "link", "LICENSE")},
+		{LicensePath: filepath.Join(dir, "b", "..", "a", "LICENSE")},
+		{},
+		{},
+	}
+	want := []int{0, 1, 0, 0, 4, 5}
+	if got := dedupeSourceRoots(libs); !cmp.Equal(got, want) {
+		t.Errorf("dedupeSourceRoots() = %v, want %v", got, want)
+	}
+}
+
+func TestSaveMain_UnknownAction(t *testing.T) {
+	// A module whose root package has no license, and whose known package
+	// has an MIT license. Licenses are not searched above vendor folders, so
+	// that no license is found for the root package.
+	dir, err := ioutil.TempDir("", "save_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	mod := filepath.Join(dir, "vendor", "unknown")
+	mit, err := ioutil.ReadFile("licenses/testdata/MIT/LICENSE.MIT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(mod, "known"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for path, content := range map[string][]byte{
+		"go.mod":         []byte("module example.com/unknown\n"),
+		"unknown.go":     []byte("package unknown\n"),
+		"known/LICENSE":  mit,
+		"known/known.go": []byte("package known\n"),
+	} {
+		if err := ioutil.WriteFile(filepath.Join(mod, filepath.FromSlash(path)), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := os.Chdir(mod); err != nil {
+		t.Fatal(err)
+	}
+	defer func(path, action string) { savePath, unknownAction = path, action }(savePath, unknownAction)
+
+	for _, test := range []struct {
+		action  string
+		wantErr bool
+	}{
+		{action: unknownActionFail, wantErr: true},
+		{action: unknownActionWarn},
+		{action: unknownActionSkip},
+	} {
+		t.Run(test.action, func(t *testing.T) {
+			savePath = filepath.Join(dir, "out", test.action)
+			unknownAction = test.action
+			err := saveMain(nil, []string{".", "./known"})
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("saveMain() with --unknown_action=%s = %v, want error %v", test.action, err, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+			if _, err := os.Stat(filepath.Join(savePath, "example.com", "unknown", "known", "LICENSE")); err != nil {
+				t.Errorf("LICENSE of example.com/unknown/known is not saved: %v", err)
+			}
+			if _, err := os.Stat(filepath.Join(savePath, "example.com", "unknown", "unknown.go")); !os.IsNotExist(err) {
+				t.Errorf("example.com/unknown with an unknown license is saved, want it skipped")
+			}
+		})
+	}
+
+	unknownAction = "ignore"
+	savePath = filepath.Join(dir, "out", "invalid")
+	if err := saveMain(nil, []string{"./known"}); err == nil {
+		t.Errorf("saveMain() with --unknown_action=%s = nil, want an error", unknownAction)
+	}
+}
+
+func TestSaveMain_Merge(t *testing.T) {
+	const pkg = "github.com/google/go-licenses/licenses/testdata/indirect"
+	dir, err := ioutil.TempDir("", "save_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	libDir := filepath.Join(dir, filepath.FromSlash(pkg))
+	if err := os.MkdirAll(libDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	staleFile := filepath.Join(libDir, "STALE")
+	otherFile := filepath.Join(dir, "README")
+	for _, path := range []string{staleFile, otherFile} {
+		if err := ioutil.WriteFile(path, []byte("content"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func(path string, merge bool) { savePath, mergeSavePath = path, merge }(savePath, mergeSavePath)
+	savePath = dir
+
+	mergeSavePath = false
+	if err := saveMain(nil, []string{pkg}); exitCode(err) != exitUsage {
+		t.Errorf("saveMain() without --merge = %v, want exit code %d", err, exitUsage)
+	}
+
+	mergeSavePath = true
+	if err := saveMain(nil, []string{pkg}); err != nil {
+		t.Fatalf("saveMain() with --merge = %q, want nil", err)
+	}
+	if _, err := os.Stat(filepath.Join(libDir, "LICENSE")); err != nil {
+		t.Errorf("LICENSE of %s is not saved: %v", pkg, err)
+	}
+	if _, err := os.Stat(staleFile); !os.IsNotExist(err) {
+		t.Errorf("stale file of %s is kept, want it removed", pkg)
+	}
+	if _, err := os.Stat(otherFile); err != nil {
+		t.Errorf("file of no library is removed, want it kept: %v", err)
+	}
+}
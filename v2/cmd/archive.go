@@ -0,0 +1,302 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+)
+
+// saveOutput is where the save command writes files to, either a directory
+// or an archive. Names are slash separated paths relative to the output root.
+type saveOutput interface {
+	writeFile(name string, content []byte) error
+	// copyDir copies files in dir recursively to name, except .git folders.
+	copyDir(dir string, name string) error
+	// close finishes writing the output.
+	close() error
+	// discard cleans up a partially written output after a failure.
+	discard()
+}
+
+// dirOutput writes files to a directory.
+type dirOutput struct {
+	root string
+}
+
+func (o *dirOutput) writeFile(name string, content []byte) error {
+	dest := filepath.Join(o.root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(dest), permDirCurrentUser); err != nil {
+		return errors.Wrapf(err, "Failed to mkdir %s", filepath.Dir(dest))
+	}
+	if err := ioutil.WriteFile(dest, content, permFileCurrentUser); err != nil {
+		return errors.Wrapf(err, "Failed to write %s", dest)
+	}
+	return nil
+}
+
+func (o *dirOutput) copyDir(dir string, name string) error {
+	return copySrc(dir, filepath.Join(o.root, filepath.FromSlash(name)))
+}
+
+func (o *dirOutput) close() error {
+	return nil
+}
+
+func (o *dirOutput) discard() {}
+
+// archiveEntryWriter writes entries of an archive format.
+type archiveEntryWriter interface {
+	// writeDir adds a folder entry, name ends with a slash.
+	writeDir(name string, mode os.FileMode) error
+	// writeFile adds a file entry of size bytes, and returns a writer of
+	// its content.
+	writeFile(name string, mode os.FileMode, size int64) (io.Writer, error)
+	close() error
+}
+
+// archiveOutput streams files into an archive, without creating the
+// directory first. It's written to a temp file next to the archive path, and
+// renamed on close, so that failures do not leave a partial archive.
+type archiveOutput struct {
+	path    string
+	f       *os.File
+	entries archiveEntryWriter
+	// folders already written to the archive
+	dirs map[string]bool
+	// modification time of all entries, so that archives are comparable
+	modTime time.Time
+}
+
+// isArchivePath reports whether path has an archive extension supported by
+// newArchiveOutput.
+func isArchivePath(path string) bool {
+	return strings.HasSuffix(path, ".tar.gz") || strings.HasSuffix(path, ".tgz") || strings.HasSuffix(path, ".zip")
+}
+
+// newArchiveOutput creates an archive at path, a .tar.gz, .tgz or .zip file.
+func newArchiveOutput(archivePath string) (*archiveOutput, error) {
+	if !isArchivePath(archivePath) {
+		return nil, fmt.Errorf("unsupported archive %q: must end with .tar.gz, .tgz or .zip", archivePath)
+	}
+	modTime, err := archiveModTime()
+	if err != nil {
+		return nil, err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(archivePath), ".tmp-"+filepath.Base(archivePath))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to create archive %s", archivePath)
+	}
+	o := &archiveOutput{
+		path:    archivePath,
+		f:       f,
+		dirs:    make(map[string]bool),
+		modTime: modTime,
+	}
+	if strings.HasSuffix(archivePath, ".zip") {
+		o.entries = &zipEntryWriter{zw: zip.NewWriter(f), modTime: o.modTime}
+	} else {
+		gw := gzip.NewWriter(f)
+		o.entries = &tarEntryWriter{gw: gw, tw: tar.NewWriter(gw), modTime: o.modTime}
+	}
+	return o, nil
+}
+
+// defaultArchiveModTime is the modification time of archive entries when
+// SOURCE_DATE_EPOCH is not set. It's the earliest time zip supports.
+var defaultArchiveModTime = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// archiveModTime returns the modification time of archive entries, so that
+// archives of the same files are identical. It's SOURCE_DATE_EPOCH when set,
+// see https://reproducible-builds.org/specs/source-date-epoch/.
+func archiveModTime() (time.Time, error) {
+	epoch := os.Getenv("SOURCE_DATE_EPOCH")
+	if epoch == "" {
+		return defaultArchiveModTime, nil
+	}
+	seconds, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH %q: must be seconds since the Unix epoch", epoch)
+	}
+	return time.Unix(seconds, 0).UTC(), nil
+}
+
+// mkdirAll adds entries of dir and its parent folders that are not added yet.
+func (o *archiveOutput) mkdirAll(dir string, mode os.FileMode) error {
+	if dir == "." || dir == "/" || o.dirs[dir] {
+		return nil
+	}
+	if err := o.mkdirAll(path.Dir(dir), permDirCurrentUser); err != nil {
+		return err
+	}
+	o.dirs[dir] = true
+	return o.entries.writeDir(dir+"/", mode)
+}
+
+func (o *archiveOutput) writeFile(name string, content []byte) error {
+	if err := o.mkdirAll(path.Dir(name), permDirCurrentUser); err != nil {
+		return errors.Wrapf(err, "Failed to write %s to archive", path.Dir(name))
+	}
+	w, err := o.entries.writeFile(name, permFileCurrentUser, int64(len(content)))
+	if err == nil {
+		_, err = w.Write(content)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "Failed to write %s to archive", name)
+	}
+	return nil
+}
+
+func (o *archiveOutput) copyDir(dir string, name string) error {
+	return filepath.Walk(dir, func(src string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		// Skip the .git directory for copying, if it exists, since we don't want to save the user's
+		// local Git config along with the source code.
+		if strings.HasSuffix(src, ".git") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, src)
+		if err != nil {
+			return err
+		}
+		dest := path.Join(name, filepath.ToSlash(rel))
+		// Go module files are by default read-only, so we add the same
+		// permissions as copying to a directory.
+		switch {
+		case info.IsDir():
+			if err := o.mkdirAll(dest, info.Mode().Perm()|permDirCurrentUser); err != nil {
+				return errors.Wrapf(err, "Failed to write %s to archive", dest)
+			}
+			return nil
+		case !info.Mode().IsRegular():
+			klog.V(2).InfoS("Skipped file that is not regular when archiving", "path", src)
+			return nil
+		}
+		f, err := os.Open(src)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w, err := o.entries.writeFile(dest, info.Mode().Perm()|permFileCurrentUser, info.Size())
+		if err == nil {
+			_, err = io.Copy(w, f)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "Failed to write %s to archive", dest)
+		}
+		return nil
+	})
+}
+
+func (o *archiveOutput) close() error {
+	if err := o.entries.close(); err != nil {
+		o.discard()
+		return errors.Wrapf(err, "Failed to write archive %s", o.path)
+	}
+	if err := o.f.Close(); err != nil {
+		o.discard()
+		return errors.Wrapf(err, "Failed to write archive %s", o.path)
+	}
+	if err := os.Chmod(o.f.Name(), permFileCurrentUser); err != nil {
+		o.discard()
+		return err
+	}
+	if err := os.Rename(o.f.Name(), o.path); err != nil {
+		o.discard()
+		return errors.Wrapf(err, "Failed to write archive %s", o.path)
+	}
+	return nil
+}
+
+func (o *archiveOutput) discard() {
+	o.f.Close()
+	os.Remove(o.f.Name())
+}
+
+type tarEntryWriter struct {
+	gw      *gzip.Writer
+	tw      *tar.Writer
+	modTime time.Time
+}
+
+func (t *tarEntryWriter) writeDir(name string, mode os.FileMode) error {
+	return t.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeDir,
+		Name:     name,
+		Mode:     int64(mode.Perm()),
+		ModTime:  t.modTime,
+	})
+}
+
+func (t *tarEntryWriter) writeFile(name string, mode os.FileMode, size int64) (io.Writer, error) {
+	err := t.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     int64(mode.Perm()),
+		Size:     size,
+		ModTime:  t.modTime,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t.tw, nil
+}
+
+func (t *tarEntryWriter) close() error {
+	if err := t.tw.Close(); err != nil {
+		return err
+	}
+	return t.gw.Close()
+}
+
+type zipEntryWriter struct {
+	zw      *zip.Writer
+	modTime time.Time
+}
+
+func (z *zipEntryWriter) writeDir(name string, mode os.FileMode) error {
+	header := &zip.FileHeader{Name: name, Modified: z.modTime}
+	header.SetMode(os.ModeDir | mode.Perm())
+	_, err := z.zw.CreateHeader(header)
+	return err
+}
+
+func (z *zipEntryWriter) writeFile(name string, mode os.FileMode, size int64) (io.Writer, error) {
+	header := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: z.modTime}
+	header.SetMode(mode.Perm())
+	return z.zw.CreateHeader(header)
+}
+
+func (z *zipEntryWriter) close() error {
+	return z.zw.Close()
+}
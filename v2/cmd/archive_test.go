@@ -0,0 +1,230 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsArchivePath(t *testing.T) {
+	tests := map[string]bool{
+		"notices.tar.gz": true,
+		"notices.tgz":    true,
+		"notices.zip":    true,
+		"notices.tar":    false,
+		"notices":        false,
+	}
+	for path, want := range tests {
+		assert.Equal(t, want, isArchivePath(path), path)
+	}
+}
+
+func TestNewArchiveOutput_Unsupported(t *testing.T) {
+	_, err := newArchiveOutput(filepath.Join(t.TempDir(), "notices.rar"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "must end with .tar.gz, .tgz or .zip")
+}
+
+// writeArchive saves a license file and a source folder to archivePath.
+func writeArchive(t *testing.T, archivePath string) {
+	src := t.TempDir()
+	writeFiles(t, src, "LICENSE", "pkg/a.go", ".git/config")
+	out, err := newArchiveOutput(archivePath)
+	require.NoError(t, err)
+	require.NoError(t, out.writeFile("licenses.txt", []byte("MIT")))
+	require.NoError(t, out.copyDir(src, "src/example.com/a"))
+	require.NoError(t, out.close())
+}
+
+// wantArchiveFiles are files in archives written by writeArchive, .git is
+// skipped.
+var wantArchiveFiles = map[string]string{
+	"licenses.txt":               "MIT",
+	"src/example.com/a/LICENSE":  "LICENSE",
+	"src/example.com/a/pkg/a.go": "pkg/a.go",
+}
+
+func TestArchiveOutput_TarGz(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "notices.tar.gz")
+	writeArchive(t, archivePath)
+
+	f, err := os.Open(archivePath)
+	require.NoError(t, err)
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	tr := tar.NewReader(gr)
+	files := make(map[string]string)
+	var dirs []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		if header.Typeflag == tar.TypeDir {
+			dirs = append(dirs, header.Name)
+			continue
+		}
+		content, err := ioutil.ReadAll(tr)
+		require.NoError(t, err)
+		files[header.Name] = string(content)
+	}
+	assert.Equal(t, wantArchiveFiles, files)
+	sort.Strings(dirs)
+	assert.Equal(t, []string{"src/", "src/example.com/", "src/example.com/a/", "src/example.com/a/pkg/"}, dirs)
+
+	// Only the archive is left, the temp file is renamed.
+	entries, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "notices.tar.gz", entries[0].Name())
+}
+
+func TestArchiveOutput_Zip(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "notices.zip")
+	writeArchive(t, archivePath)
+
+	zr, err := zip.OpenReader(archivePath)
+	require.NoError(t, err)
+	defer zr.Close()
+	files := make(map[string]string)
+	for _, file := range zr.File {
+		if strings.HasSuffix(file.Name, "/") {
+			continue
+		}
+		r, err := file.Open()
+		require.NoError(t, err)
+		content, err := ioutil.ReadAll(r)
+		r.Close()
+		require.NoError(t, err)
+		files[file.Name] = string(content)
+	}
+	assert.Equal(t, wantArchiveFiles, files)
+}
+
+func TestArchiveOutput_Discard(t *testing.T) {
+	dir := t.TempDir()
+	out, err := newArchiveOutput(filepath.Join(dir, "notices.zip"))
+	require.NoError(t, err)
+	require.NoError(t, out.writeFile("licenses.txt", []byte("MIT")))
+	out.discard()
+
+	entries, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+// setenv sets an environment variable, or unsets it when value is empty, and
+// returns a function restoring its previous value.
+func setenv(t *testing.T, key, value string) func() {
+	previous, ok := os.LookupEnv(key)
+	if value == "" {
+		require.NoError(t, os.Unsetenv(key))
+	} else {
+		require.NoError(t, os.Setenv(key, value))
+	}
+	return func() {
+		if ok {
+			os.Setenv(key, previous)
+		} else {
+			os.Unsetenv(key)
+		}
+	}
+}
+
+func TestArchiveModTime(t *testing.T) {
+	tests := []struct {
+		name    string
+		epoch   string
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "default", epoch: "", want: defaultArchiveModTime},
+		{name: "SOURCE_DATE_EPOCH", epoch: "1634400000", want: time.Date(2021, time.October, 16, 16, 0, 0, 0, time.UTC)},
+		{name: "invalid SOURCE_DATE_EPOCH", epoch: "yesterday", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer setenv(t, "SOURCE_DATE_EPOCH", tt.epoch)()
+			got, err := archiveModTime()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tt.want.Equal(got), "archiveModTime() = %v, want %v", got, tt.want)
+		})
+	}
+}
+
+func TestArchiveOutput_ModTime(t *testing.T) {
+	defer setenv(t, "SOURCE_DATE_EPOCH", "")()
+	dir, err := ioutil.TempDir("", "archive_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	for _, ext := range []string{".tar.gz", ".zip"} {
+		t.Run(ext, func(t *testing.T) {
+			archivePath := filepath.Join(dir, "licenses"+ext)
+			o, err := newArchiveOutput(archivePath)
+			require.NoError(t, err)
+			require.NoError(t, o.writeFile("source/example.com/a/LICENSE", []byte("MPL-2.0")))
+			require.NoError(t, o.close())
+
+			modTimes := make(map[string]time.Time)
+			if ext == ".zip" {
+				zr, err := zip.OpenReader(archivePath)
+				require.NoError(t, err)
+				defer zr.Close()
+				for _, f := range zr.File {
+					modTimes[f.Name] = f.Modified
+				}
+			} else {
+				f, err := os.Open(archivePath)
+				require.NoError(t, err)
+				defer f.Close()
+				gr, err := gzip.NewReader(f)
+				require.NoError(t, err)
+				tr := tar.NewReader(gr)
+				for {
+					header, err := tr.Next()
+					if err == io.EOF {
+						break
+					}
+					require.NoError(t, err)
+					modTimes[header.Name] = header.ModTime
+				}
+			}
+			assert.Len(t, modTimes, 4)
+			for name, modTime := range modTimes {
+				assert.True(t, defaultArchiveModTime.Equal(modTime), "modification time of %s = %v, want %v", name, modTime, defaultArchiveModTime)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"os"
+	"text/template"
+
+	"github.com/google/go-licenses/v2/dict"
+	"github.com/google/go-licenses/v2/ghutils"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+)
+
+// attribution targets
+const (
+	// iOS Settings bundle plist, e.g. Settings.bundle/Acknowledgements.plist
+	attributionTargetIos = "ios"
+	// Android open_source_licenses.html shown in an app's about screen
+	attributionTargetAndroid = "android"
+)
+
+// flag variables
+var attributionTarget string     // which app store format to generate
+var attributionOutputPath string // where to write the attribution file, stdout when empty
+
+// attributionCmd represents the attribution command
+var attributionCmd = &cobra.Command{
+	Use:   "attribution <LICENSE_CSV_PATH> --target {ios,android}",
+	Short: "Generate attribution text in the format required by an app store",
+	Long: `"go-licenses attribution" downloads full license text of modules in a licenses csv,
+and generates an attribution file in the format a platform requires, e.g. an iOS
+Settings bundle plist, or an Android open_source_licenses.html.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runE(func(cmd *cobra.Command, args []string) error {
+		return attributionImp(args[0], attributionTarget, attributionOutputPath)
+	}),
+}
+
+func init() {
+	rootCmd.AddCommand(attributionCmd)
+	addDownloadFlags(attributionCmd)
+	attributionCmd.Flags().StringVar(&attributionTarget, "target", "", fmt.Sprintf("attribution format, one of %q, %q", attributionTargetIos, attributionTargetAndroid))
+	if err := attributionCmd.MarkFlagRequired("target"); err != nil {
+		klog.Fatal(err)
+	}
+	attributionCmd.Flags().StringVar(&attributionOutputPath, "output", "", "file to write the attribution to, defaults to stdout")
+	if err := attributionCmd.MarkFlagFilename("output"); err != nil {
+		klog.Fatal(err)
+	}
+}
+
+// attribution is a module to be attributed, with its full license text.
+type attribution struct {
+	Module  string
+	License string // SPDX ID of the license
+	Url     string // license download url
+	Text    string // full license text
+}
+
+var attributionTemplates = map[string]*template.Template{
+	attributionTargetIos:     template.Must(template.New(attributionTargetIos).Funcs(attributionFuncs).Parse(iosPlistTemplate)),
+	attributionTargetAndroid: template.Must(template.New(attributionTargetAndroid).Funcs(attributionFuncs).Parse(androidHtmlTemplate)),
+}
+
+// Both plist and html are xml-like, escaping html special characters works
+// for both of them.
+var attributionFuncs = template.FuncMap{"escape": html.EscapeString}
+
+const iosPlistTemplate = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>PreferenceSpecifiers</key>
+	<array>
+{{- range .}}
+		<dict>
+			<key>FooterText</key>
+			<string>{{escape .Text}}</string>
+			<key>License</key>
+			<string>{{escape .License}}</string>
+			<key>Title</key>
+			<string>{{escape .Module}}</string>
+			<key>Type</key>
+			<string>PSGroupSpecifier</string>
+		</dict>
+{{- end}}
+	</array>
+	<key>StringsTable</key>
+	<string>Acknowledgements</string>
+	<key>Title</key>
+	<string>Acknowledgements</string>
+</dict>
+</plist>
+`
+
+const androidHtmlTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Open source licenses</title>
+</head>
+<body>
+<h1>Open source licenses</h1>
+{{- range .}}
+<h3>{{escape .Module}}</h3>
+<p>{{escape .License}}</p>
+<pre>{{escape .Text}}</pre>
+{{- end}}
+</body>
+</html>
+`
+
+func attributionImp(csvPath string, target string, outputPath string) error {
+	tmpl, ok := attributionTemplates[target]
+	if !ok {
+		return usageError(fmt.Errorf("invalid --target %q: expected %q or %q", target, attributionTargetIos, attributionTargetAndroid))
+	}
+	info, err := loadInfo(csvPath)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to load license info csv %s", csvPath)
+	}
+	attributions, err := downloadAttributions(info, newDownloader(), *flagDownloadConcurrency)
+	if err != nil {
+		return err
+	}
+	if outputPath == "" {
+		if err := tmpl.Execute(os.Stdout, attributions); err != nil {
+			return errors.Wrapf(err, "Failed to write %s attribution", target)
+		}
+		return nil
+	}
+	f, err := os.Create(outputPath)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to create %s", outputPath)
+	}
+	if err := tmpl.Execute(f, attributions); err != nil {
+		f.Close()
+		return errors.Wrapf(err, "Failed to write %s attribution", target)
+	}
+	// Errors of writes buffered by the OS may only be reported by Close.
+	if err := f.Close(); err != nil {
+		return errors.Wrapf(err, "Failed to write %s", outputPath)
+	}
+	return nil
+}
+
+// downloadAttributions downloads full license text of every record.
+func downloadAttributions(info []*dict.LicenseRecord, download *ghutils.Downloader, concurrency int) ([]attribution, error) {
+	records := make([]*dict.LicenseRecord, 0, len(info))
+	for _, record := range info {
+		if !record.ShouldIgnore {
+			records = append(records, record)
+		}
+	}
+	// Stored by index, so that attributions are in the same order as info.
+	attributions := make([]attribution, len(records))
+	err := forEachConcurrently(context.Background(), len(records), downloadConcurrency(concurrency), func(_ context.Context, i int) error {
+		record := records[i]
+		licenseContent, err := download.SmartDownload(record.DownaloadUrl)
+		if err != nil {
+			return errors.Wrapf(err, "%s", record.Module)
+		}
+		klog.V(2).Infof("%s: Downloaded %s", record.Module, record.DownaloadUrl)
+		attributions[i] = attribution{
+			Module:  record.Module,
+			License: record.Type,
+			Url:     record.DownaloadUrl,
+			Text:    string(licenseContent),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return attributions, nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-licenses/v2/dict"
+	"github.com/google/go-licenses/v2/ghutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttributionTemplates(t *testing.T) {
+	attributions := []attribution{{
+		Module:  "example.com/a",
+		License: "MIT",
+		Url:     "https://example.com/a/LICENSE",
+		Text:    "Copyright <A & B>",
+	}}
+	tests := []struct {
+		target string
+		want   []string
+	}{
+		{
+			target: attributionTargetIos,
+			want: []string{
+				"<key>PreferenceSpecifiers</key>",
+				"<key>FooterText</key>\n\t\t\t<string>Copyright &lt;A &amp; B&gt;</string>",
+				"<key>License</key>\n\t\t\t<string>MIT</string>",
+				"<key>Title</key>\n\t\t\t<string>example.com/a</string>",
+			},
+		},
+		{
+			target: attributionTargetAndroid,
+			want: []string{
+				"<h3>example.com/a</h3>\n<p>MIT</p>\n<pre>Copyright &lt;A &amp; B&gt;</pre>",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			var out bytes.Buffer
+			require.NoError(t, attributionTemplates[tt.target].Execute(&out, attributions))
+			for _, want := range tt.want {
+				assert.Contains(t, out.String(), want)
+			}
+		})
+	}
+}
+
+func TestAttributionImp_InvalidTarget(t *testing.T) {
+	err := attributionImp("licenses.csv", "windows", "")
+	assert.EqualError(t, err, `invalid --target "windows": expected "ios" or "android"`)
+}
+
+func TestDownloadAttributions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("license of " + r.URL.Path))
+	}))
+	defer server.Close()
+	info := []*dict.LicenseRecord{
+		{Module: "example.com/a", Type: "MIT", DownaloadUrl: server.URL + "/a"},
+		{Module: "example.com/ignored", Type: "MIT", DownaloadUrl: server.URL + "/ignored", ShouldIgnore: true},
+		{Module: "example.com/b", Type: "Apache-2.0", DownaloadUrl: server.URL + "/b"},
+	}
+
+	attributions, err := downloadAttributions(info, &ghutils.Downloader{}, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []attribution{
+		{Module: "example.com/a", License: "MIT", Url: server.URL + "/a", Text: "license of /a"},
+		{Module: "example.com/b", License: "Apache-2.0", Url: server.URL + "/b", Text: "license of /b"},
+	}, attributions)
+}
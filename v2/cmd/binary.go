@@ -0,0 +1,48 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+)
+
+// binaryCmd represents the binary command
+var binaryCmd = &cobra.Command{
+	Use:   "binary <binary_path>...",
+	Short: "Generate dependency licenses csv from a built go binary",
+	Long: `"go-licenses binary" generates the same licenses csv as "go-licenses csv --binary".
+Dependencies are read from module information embedded in the go binary, so you can
+audit a shipped artifact. The binary must be built in go modules mode, and the
+command must run in the go module dir the binary was built from, to find module
+source code. Multiple binaries built from the same go module can be passed, licenses
+of the union of their dependencies are generated.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runE(func(cmd *cobra.Command, args []string) error {
+		*flagBinary = true
+		return csvImp(context.Background(), args)
+	}),
+}
+
+func init() {
+	rootCmd.AddCommand(binaryCmd)
+	addScanFlags(binaryCmd)
+	// The argument is always a binary.
+	if err := binaryCmd.Flags().MarkHidden("binary"); err != nil {
+		klog.Fatal(err)
+	}
+}
@@ -0,0 +1,31 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBinaryCmd_Flags(t *testing.T) {
+	// The argument is always a binary, so --binary is hidden.
+	binary := binaryCmd.Flags().Lookup("binary")
+	require.NotNil(t, binary)
+	assert.True(t, binary.Hidden)
+	// Other scan flags are the same as the csv command.
+	assert.NotNil(t, binaryCmd.Flags().Lookup("exclude"))
+}
@@ -0,0 +1,99 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	configmodule "github.com/google/go-licenses/v2/config"
+	"github.com/google/go-licenses/v2/gocli"
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+)
+
+// blameCmd represents the blame command
+var blameCmd = &cobra.Command{
+	Use:   "blame {<package>, --binary <binary_path>}",
+	Short: "Report which direct dependencies introduce restricted or reciprocal licenses",
+	Long: `"go-licenses blame" scans licenses of dependencies, and for each module with a
+restricted or reciprocal license, reports the shortest chain of module requirements
+from the main module that pulls it in, according to "go mod graph". Use it to find
+out whether dropping a direct dependency eliminates a copyleft license.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runE(func(cmd *cobra.Command, args []string) error {
+		return blameImp(context.Background(), args[0])
+	}),
+}
+
+func init() {
+	rootCmd.AddCommand(blameCmd)
+	addScanFlags(blameCmd)
+}
+
+func blameImp(ctx context.Context, binaryOrImportPath string) error {
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	rows, scanErr := scanLicenses(ctx, []string{binaryOrImportPath}, config)
+	if rows == nil {
+		return scanErr
+	}
+	graph, err := gocli.ModGraphInDir(flagDir)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "# module, license, chain of requirements from the main module\n")
+	for _, row := range rows {
+		if !isCopyleft(row.SpdxId, config.Licenses) {
+			continue
+		}
+		chain := requirementChain(graph, row.Module)
+		if chain == nil {
+			klog.Warningf("%s: not found in go mod graph", row.Module)
+			continue
+		}
+		fmt.Fprintf(os.Stdout, "%s, %s, %s\n", row.Module, row.SpdxId, strings.Join(chain, " > "))
+	}
+	return scanErr
+}
+
+// isCopyleft reports whether any license in spdxIds, joined by "/", is
+// restricted or reciprocal.
+func isCopyleft(spdxIds string, cfg configmodule.LicensesConfig) bool {
+	for _, part := range strings.Split(spdxIds, "/") {
+		switch licenseType(strings.TrimSpace(part), cfg) {
+		case "restricted", "reciprocal":
+			return true
+		}
+	}
+	return false
+}
+
+// requirementChain returns the shortest chain of module requirements from the
+// main module to module. module may be a sub module path, e.g. a vendored
+// dependency, then the chain to its closest parent module is returned.
+func requirementChain(graph *gocli.ModuleGraph, module string) []string {
+	for p := module; p != "." && p != "/"; p = path.Dir(p) {
+		if chain := graph.ShortestPath(p); chain != nil {
+			return chain
+		}
+	}
+	return nil
+}
@@ -0,0 +1,489 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	configmodule "github.com/google/go-licenses/v2/config"
+	"github.com/google/go-licenses/v2/licenses"
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+)
+
+// checkCmd represents the check command
+var checkCmd = &cobra.Command{
+	Use:   "check {<package>, --binary <binary_path>}",
+	Short: "Check dependency licenses of a go package or a built go binary are not forbidden",
+	Long: `"go-licenses check" scans licenses of dependencies the same way as "go-licenses csv",
+and fails when any of the licenses has a disallowed type, by default forbidden according to
+https://github.com/google/licenseclassifier/blob/df6aa8a2788bdf5ac382148c2453a407a29819b8/license_type.go#L341.
+License types can be overridden using go-licenses.yaml.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runE(func(cmd *cobra.Command, args []string) error {
+		binaryOrImportPath := args[0]
+		return checkImp(context.Background(), binaryOrImportPath)
+	}),
+}
+
+const (
+	checkFormatText   = "text"
+	checkFormatSarif  = "sarif"
+	checkFormatNdjson = "ndjson"
+	checkFormatJson   = "json"
+)
+
+var flagCheckFormat *string
+var flagFailOnConflict *bool
+var flagFailOnUnknown *bool
+var flagDisallowedTypes *[]string
+var flagAllowedLicenses *[]string
+var flagDisallowedLicenses *[]string
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+	addScanFlags(checkCmd)
+	flagCheckFormat = checkCmd.Flags().String("format", checkFormatText, "output format of check results, one of text, sarif, ndjson or json")
+	flagFailOnConflict = checkCmd.Flags().Bool("fail_on_conflict", false, "fail when a module has licenses with different compliance requirements, e.g. both MIT and GPL-2.0, so that it's manually determined which license applies")
+	flagFailOnUnknown = checkCmd.Flags().Bool("fail_on_unknown", false, "also fail when a license type is unknown, the same as adding Unknown to --disallowed_types")
+	flagDisallowedTypes = checkCmd.Flags().StringSlice("disallowed_types", []string{"Forbidden"}, "comma separated license types that fail the check, e.g. Forbidden,Restricted,Unknown")
+	flagAllowedLicenses = checkCmd.Flags().StringSlice("allowed_licenses", nil, "comma separated SPDX IDs of licenses that never fail the check, even if their types are disallowed")
+	flagDisallowedLicenses = checkCmd.Flags().StringSlice("disallowed_licenses", nil, "comma separated SPDX IDs of licenses that always fail the check, it takes precedence over --allowed_licenses")
+}
+
+// violation is a license that fails the check.
+type violation struct {
+	Rule        string // the rule that is violated, e.g. forbidden
+	Row         licenseRow
+	SpdxId      string // the SPDX ID that fails the check, Row.SpdxId may contain multiple licenses
+	LicenseType string
+	Reason      string // why the license fails the check
+}
+
+func checkImp(ctx context.Context, binaryOrImportPath string) error {
+	format := *flagCheckFormat
+	if format != checkFormatText && format != checkFormatSarif && format != checkFormatNdjson && format != checkFormatJson {
+		return usageError(fmt.Errorf("invalid --format %q: must be one of %s, %s, %s, %s", format, checkFormatText, checkFormatSarif, checkFormatNdjson, checkFormatJson))
+	}
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	policy, err := newCheckPolicy(config.Licenses.Policy)
+	if err != nil {
+		return usageError(err)
+	}
+	var rows []licenseRow
+	var scanErr error
+	violations := make([]violation, 0)
+	if format == checkFormatNdjson {
+		// ndjson violations are written as soon as each module is scanned,
+		// so that pipelines can consume them before the scan completes.
+		ndjson := newNdjsonWriter(os.Stdout)
+		rows, scanErr = scanLicensesEach(ctx, []string{binaryOrImportPath}, config, func(moduleRows []licenseRow) error {
+			found, err := checkViolations(moduleRows, config.Licenses, policy, ndjson.write)
+			violations = append(violations, found...)
+			return err
+		})
+		if rows == nil {
+			return scanErr
+		}
+	} else {
+		rows, scanErr = scanLicenses(ctx, []string{binaryOrImportPath}, config)
+		if rows == nil {
+			return scanErr
+		}
+		violations, err = checkViolations(rows, config.Licenses, policy, nil)
+		if err != nil {
+			return err
+		}
+	}
+	switch format {
+	case checkFormatSarif:
+		if err := writeSarif(os.Stdout, violations); err != nil {
+			return err
+		}
+	case checkFormatNdjson:
+		// already written
+	case checkFormatJson:
+		// All results are written before failing, so that tools can render
+		// a summary even when the check fails.
+		if err := writeJSON(os.Stdout, checkResults(rows, violations, config.Licenses)); err != nil {
+			return err
+		}
+	default:
+		for _, v := range violations {
+			klog.ErrorS(errors.New(v.Reason), "Failed", "module", v.Row.Module, "license", v.SpdxId, "url", v.Row.Url)
+		}
+	}
+	if len(violations) > 0 {
+		return violationError(fmt.Errorf("Found %v license violation(s): %s", len(violations), summarizeViolations(violations)))
+	}
+	// Licenses that are found do not violate the check, but modules that
+	// failed scanning still fail it.
+	return scanErr
+}
+
+// summarizeViolations returns the number of violations of each rule, e.g.
+// "forbidden: 2, unknown: 1".
+func summarizeViolations(violations []violation) string {
+	counts := make(map[string]int)
+	for _, v := range violations {
+		counts[v.Rule] = counts[v.Rule] + 1
+	}
+	rules := make([]string, 0, len(counts))
+	for rule := range counts {
+		rules = append(rules, rule)
+	}
+	sort.Strings(rules)
+	summary := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		summary = append(summary, fmt.Sprintf("%s: %v", rule, counts[rule]))
+	}
+	return strings.Join(summary, ", ")
+}
+
+// rules a license may violate, besides disallowed license types
+const (
+	ruleCommercial = "commercial"
+	ruleConflict   = "conflict"
+	// a license explicitly disallowed by SPDX ID
+	ruleDisallowedLicense = "disallowed_license"
+)
+
+// knownLicenseTypes are license types that can be disallowed, "" is the
+// unknown type.
+var knownLicenseTypes = []string{"FORBIDDEN", "restricted", "reciprocal", "notice", "permissive", "unencumbered", "by_exception_only", configmodule.LicenseTypeCommercial, ""}
+
+// parseLicenseTypes parses license type names like Forbidden or Unknown,
+// case insensitively, to a set of license types.
+func parseLicenseTypes(names []string) (map[string]bool, error) {
+	types := make(map[string]bool)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, t := range knownLicenseTypes {
+			if strings.EqualFold(name, displayLicenseType(t)) {
+				types[t] = true
+				found = true
+			}
+		}
+		if !found {
+			names := make([]string, 0, len(knownLicenseTypes))
+			for _, t := range knownLicenseTypes {
+				names = append(names, displayLicenseType(t))
+			}
+			return nil, fmt.Errorf("unknown license type %q, must be one of %s", name, strings.Join(names, ", "))
+		}
+	}
+	return types, nil
+}
+
+// checkPolicy decides which licenses fail the check.
+type checkPolicy struct {
+	disallowedTypes map[string]bool // license types that fail the check, "" is the unknown type
+	// SPDX IDs of licenses that never fail the check
+	allowedLicenses map[string]bool
+	// SPDX IDs of licenses that always fail the check, they take precedence
+	// over allowedLicenses
+	disallowedLicenses map[string]bool
+}
+
+// newCheckPolicy combines check flags with policy in config. Licenses
+// disallowed by either of them fail the check.
+func newCheckPolicy(cfg configmodule.Policy) (policy checkPolicy, err error) {
+	policy.disallowedTypes, err = parseLicenseTypes(append(append([]string{}, *flagDisallowedTypes...), cfg.DisallowedTypes...))
+	if err != nil {
+		return policy, fmt.Errorf("invalid --disallowed_types or licenses.policy.disallowedTypes: %w", err)
+	}
+	if *flagFailOnUnknown {
+		policy.disallowedTypes[""] = true
+	}
+	if len(cfg.AllowedTypes) > 0 {
+		allowedTypes, err := parseLicenseTypes(cfg.AllowedTypes)
+		if err != nil {
+			return policy, fmt.Errorf("invalid licenses.policy.allowedTypes: %w", err)
+		}
+		for _, t := range knownLicenseTypes {
+			if !allowedTypes[t] {
+				policy.disallowedTypes[t] = true
+			}
+		}
+	}
+	policy.allowedLicenses = stringSet(append(append([]string{}, *flagAllowedLicenses...), cfg.AllowedLicenses...))
+	policy.disallowedLicenses = stringSet(append(append([]string{}, *flagDisallowedLicenses...), cfg.DisallowedLicenses...))
+	return policy, nil
+}
+
+// stringSet returns a set of trimmed non-empty values.
+func stringSet(values []string) map[string]bool {
+	set := make(map[string]bool)
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value != "" {
+			set[value] = true
+		}
+	}
+	return set
+}
+
+// checkViolations returns licenses in rows that violate policy, and
+// conflicting licenses with --fail_on_conflict. Each violation is also passed
+// to emit as soon as it's found, unless emit is nil.
+func checkViolations(rows []licenseRow, cfg configmodule.LicensesConfig, policy checkPolicy, emit func(violation) error) ([]violation, error) {
+	violations, err := checkLicenses(rows, cfg, policy, emit)
+	if err != nil {
+		return nil, err
+	}
+	if *flagFailOnConflict {
+		conflicts, err := conflictingLicenses(rows, cfg, emit)
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, conflicts...)
+	}
+	return violations, nil
+}
+
+// checkLicenses returns licenses in rows that are disallowed by policy, or
+// commercial when they are not allowed. Licenses can be SPDX expressions, see
+// expressionViolations. Each violation is also passed to emit as soon as
+// it's found, unless emit is nil.
+func checkLicenses(rows []licenseRow, cfg configmodule.LicensesConfig, policy checkPolicy, emit func(violation) error) ([]violation, error) {
+	violations := make([]violation, 0)
+	for _, row := range rows {
+		for _, part := range strings.Split(row.SpdxId, "/") {
+			for _, v := range expressionViolations(spdxExpression(part), row, cfg, policy) {
+				violations = append(violations, v)
+				if emit != nil {
+					if err := emit(v); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+	}
+	return violations, nil
+}
+
+// expressionViolations returns violations of licenses in an SPDX license
+// expression. Licenses combined by AND violate the check when any of them
+// does, and licenses combined by OR only when all of them do, because any of
+// them can be chosen.
+func expressionViolations(expression *licenses.Expression, row licenseRow, cfg configmodule.LicensesConfig, policy checkPolicy) []violation {
+	violations := make([]violation, 0)
+	switch expression.Operator {
+	case licenses.OperatorAnd:
+		for _, operand := range expression.Operands {
+			violations = append(violations, expressionViolations(operand, row, cfg, policy)...)
+		}
+	case licenses.OperatorOr:
+		for _, operand := range expression.Operands {
+			operandViolations := expressionViolations(operand, row, cfg, policy)
+			if len(operandViolations) == 0 {
+				return nil
+			}
+			violations = append(violations, operandViolations...)
+		}
+	default:
+		if v := licenseViolation(expression.SpdxId, row, cfg, policy); v != nil {
+			violations = append(violations, *v)
+		}
+	}
+	return violations
+}
+
+// licenseViolation returns the violation of a single license, or nil when it
+// doesn't violate the check.
+func licenseViolation(spdxId string, row licenseRow, cfg configmodule.LicensesConfig, policy checkPolicy) *violation {
+	t := licenseType(spdxId, cfg)
+	switch {
+	case policy.disallowedLicenses[spdxId]:
+		return &violation{
+			Rule:        ruleDisallowedLicense,
+			Row:         row,
+			SpdxId:      spdxId,
+			LicenseType: displayLicenseType(t),
+			Reason:      fmt.Sprintf("disallowed license %s", spdxId),
+		}
+	case policy.allowedLicenses[spdxId]:
+		// Explicitly allowed, regardless of its type.
+	case policy.disallowedTypes[t]:
+		return &violation{
+			Rule:        strings.ToLower(displayLicenseType(t)),
+			Row:         row,
+			SpdxId:      spdxId,
+			LicenseType: displayLicenseType(t),
+			Reason:      fmt.Sprintf("disallowed license type %s", displayLicenseType(t)),
+		}
+	case t == configmodule.LicenseTypeCommercial && !cfg.Commercial.Allowed:
+		return &violation{
+			Rule:        ruleCommercial,
+			Row:         row,
+			SpdxId:      spdxId,
+			LicenseType: displayLicenseType(t),
+			Reason:      "commercial license is not allowed, set licenses.commercial.allowed in config if it's licensed",
+		}
+	case t == configmodule.LicenseTypeCommercial:
+		// Allowed, but reported separately so they can be tracked.
+		klog.InfoS("Commercial license", "module", row.Module, "license", spdxId, "url", row.Url)
+	}
+	return nil
+}
+
+// conflictingLicenses returns licenses of modules that have licenses with
+// different compliance requirements. For example, a module with both MIT and
+// GPL-2.0 license files is ambiguous about whether its source code needs to be
+// redistributed. Each violation is also passed to emit as soon as it's found,
+// unless emit is nil.
+func conflictingLicenses(rows []licenseRow, cfg configmodule.LicensesConfig, emit func(violation) error) ([]violation, error) {
+	modules := make([]string, 0)
+	licensesByModule := make(map[string][]violation)
+	requirementsByModule := make(map[string]map[ComplianceReq]bool)
+	for _, row := range rows {
+		if _, ok := licensesByModule[row.Module]; !ok {
+			modules = append(modules, row.Module)
+			requirementsByModule[row.Module] = make(map[ComplianceReq]bool)
+		}
+		for _, part := range strings.Split(row.SpdxId, "/") {
+			// An SPDX expression is a single license here, e.g. the
+			// requirement of "MIT OR GPL-2.0-only" is the one of MIT.
+			license := strings.TrimSpace(part)
+			reqType, err := requirementType(license, cfg)
+			if err != nil || reqType == Unknown {
+				// Unknown licenses are not conflicts by themselves.
+				continue
+			}
+			requirementsByModule[row.Module][reqType] = true
+			licensesByModule[row.Module] = append(licensesByModule[row.Module], violation{
+				Rule:        ruleConflict,
+				Row:         row,
+				SpdxId:      license,
+				LicenseType: displayLicenseTypes(license, cfg),
+			})
+		}
+	}
+	violations := make([]violation, 0)
+	for _, module := range modules {
+		if len(requirementsByModule[module]) <= 1 {
+			continue
+		}
+		found := make([]string, 0, len(licensesByModule[module]))
+		for _, v := range licensesByModule[module] {
+			found = append(found, fmt.Sprintf("%s (%s)", v.SpdxId, v.LicenseType))
+		}
+		reason := fmt.Sprintf("conflicting license types in module: %s", strings.Join(found, ", "))
+		for _, v := range licensesByModule[module] {
+			v.Reason = reason
+			violations = append(violations, v)
+			if emit != nil {
+				if err := emit(v); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return violations, nil
+}
+
+// status of a license that doesn't violate any rule
+const statusOk = "ok"
+
+// checkResult is the check result of a license in json output.
+type checkResult struct {
+	Module      string `json:"module"`
+	LicenseId   string `json:"license_id"`
+	LicenseUrl  string `json:"license_url"`
+	LicenseType string `json:"license_type"`
+	Status      string `json:"status"` // ok, or the first rule the license violates, e.g. forbidden
+}
+
+// checkResults returns check results of every license in rows.
+func checkResults(rows []licenseRow, violations []violation, cfg configmodule.LicensesConfig) []checkResult {
+	results := make([]checkResult, 0, len(rows))
+	for _, row := range rows {
+		for _, part := range strings.Split(row.SpdxId, "/") {
+			license := strings.TrimSpace(part)
+			// Violations are of the whole license, or of a license in its
+			// SPDX expression.
+			spdxIds := stringSet(append(spdxExpression(license).SpdxIds(), license))
+			status := statusOk
+			for _, v := range violations {
+				if v.Row == row && spdxIds[v.SpdxId] {
+					status = v.Rule
+					break
+				}
+			}
+			results = append(results, checkResult{
+				Module:      row.Module,
+				LicenseId:   license,
+				LicenseUrl:  row.Url,
+				LicenseType: displayLicenseTypes(license, cfg),
+				Status:      status,
+			})
+		}
+	}
+	return results
+}
+
+// ndjsonViolation is a line of ndjson check output.
+type ndjsonViolation struct {
+	Module  string `json:"module"`
+	Version string `json:"version"`
+	License string `json:"license"`
+	Type    string `json:"type"`
+	Url     string `json:"url"`
+	Reason  string `json:"reason"`
+}
+
+// ndjsonWriter writes each violation as a json object in a separate line, so
+// that log-based pipelines can consume them one by one.
+type ndjsonWriter struct {
+	w       *bufio.Writer
+	encoder *json.Encoder
+}
+
+func newNdjsonWriter(w io.Writer) *ndjsonWriter {
+	buffered := bufio.NewWriter(w)
+	return &ndjsonWriter{w: buffered, encoder: json.NewEncoder(buffered)}
+}
+
+// write writes a violation line, and flushes it, so that it's visible to
+// readers before the next violation is found.
+func (n *ndjsonWriter) write(v violation) error {
+	err := n.encoder.Encode(ndjsonViolation{
+		Module:  v.Row.Module,
+		Version: v.Row.Version,
+		License: v.SpdxId,
+		Type:    v.LicenseType,
+		Url:     v.Row.Url,
+		Reason:  v.Reason,
+	})
+	if err == nil {
+		err = n.w.Flush()
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to write ndjson: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,439 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	configmodule "github.com/google/go-licenses/v2/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConflictingLicenses(t *testing.T) {
+	const reason = "conflicting license types in module: MIT (Notice), GPL-2.0 (Restricted)"
+	tests := []struct {
+		name string
+		rows []licenseRow
+		want []violation
+	}{
+		{
+			name: "same requirements",
+			rows: []licenseRow{
+				{Module: "example.com/a", SpdxId: "MIT"},
+				{Module: "example.com/a", SpdxId: "Apache-2.0"},
+			},
+			want: []violation{},
+		},
+		{
+			name: "unknown licenses are not conflicts",
+			rows: []licenseRow{
+				{Module: "example.com/a", SpdxId: "MIT / LicenseRef-Unknown"},
+			},
+			want: []violation{},
+		},
+		{
+			name: "different requirements in one row",
+			rows: []licenseRow{
+				{Module: "example.com/a", SpdxId: "MIT"},
+				{Module: "example.com/b", SpdxId: "GPL-2.0"},
+				{Module: "example.com/c", SpdxId: "MIT / GPL-2.0"},
+			},
+			want: []violation{
+				{Rule: ruleConflict, Row: licenseRow{Module: "example.com/c", SpdxId: "MIT / GPL-2.0"}, SpdxId: "MIT", LicenseType: "Notice", Reason: reason},
+				{Rule: ruleConflict, Row: licenseRow{Module: "example.com/c", SpdxId: "MIT / GPL-2.0"}, SpdxId: "GPL-2.0", LicenseType: "Restricted", Reason: reason},
+			},
+		},
+		{
+			name: "different requirements in rows of one module",
+			rows: []licenseRow{
+				{Module: "example.com/a", SpdxId: "MIT"},
+				{Module: "example.com/a", SpdxId: "GPL-2.0"},
+			},
+			want: []violation{
+				{Rule: ruleConflict, Row: licenseRow{Module: "example.com/a", SpdxId: "MIT"}, SpdxId: "MIT", LicenseType: "Notice", Reason: reason},
+				{Rule: ruleConflict, Row: licenseRow{Module: "example.com/a", SpdxId: "GPL-2.0"}, SpdxId: "GPL-2.0", LicenseType: "Restricted", Reason: reason},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations, err := conflictingLicenses(tt.rows, configmodule.LicensesConfig{}, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, violations)
+		})
+	}
+}
+
+func TestNdjsonWriter(t *testing.T) {
+	violations := []violation{
+		{
+			Row:         licenseRow{Module: "example.com/gpl", Version: "v1.0.0", Url: "https://example.com/gpl/LICENSE"},
+			SpdxId:      "GPL-2.0",
+			LicenseType: "restricted",
+			Reason:      "forbidden license type",
+		},
+		{
+			Row:         licenseRow{Module: "example.com/unknown", Version: "v0.1.0"},
+			SpdxId:      "LicenseRef-Acme",
+			LicenseType: "unknown",
+			Reason:      "unknown license type",
+		},
+	}
+	var buf bytes.Buffer
+	ndjson := newNdjsonWriter(&buf)
+	for _, v := range violations {
+		require.NoError(t, ndjson.write(v))
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 2)
+	assert.JSONEq(t, `{"module": "example.com/gpl", "version": "v1.0.0", "license": "GPL-2.0", "type": "restricted", "url": "https://example.com/gpl/LICENSE", "reason": "forbidden license type"}`, lines[0])
+	assert.JSONEq(t, `{"module": "example.com/unknown", "version": "v0.1.0", "license": "LicenseRef-Acme", "type": "unknown", "url": "", "reason": "unknown license type"}`, lines[1])
+}
+
+func TestCheckLicenses_Commercial(t *testing.T) {
+	row := licenseRow{Module: "example.com/acme/sdk", SpdxId: "MIT / LicenseRef-Acme"}
+	tests := []struct {
+		name    string
+		allowed bool
+		want    []violation
+	}{
+		{
+			name: "not allowed",
+			want: []violation{{
+				Rule:        ruleCommercial,
+				Row:         row,
+				SpdxId:      "LicenseRef-Acme",
+				LicenseType: "Commercial",
+				Reason:      "commercial license is not allowed, set licenses.commercial.allowed in config if it's licensed",
+			}},
+		},
+		{name: "allowed", allowed: true, want: []violation{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := configmodule.LicensesConfig{}
+			cfg.Types.Overrides = []configmodule.LicenseTypeOverride{{SpdxId: "LicenseRef-Acme", Type: configmodule.LicenseTypeCommercial}}
+			cfg.Commercial.Allowed = tt.allowed
+			violations, err := checkLicenses([]licenseRow{row}, cfg, checkPolicy{disallowedTypes: map[string]bool{"FORBIDDEN": true}}, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, violations)
+		})
+	}
+}
+
+func TestParseLicenseTypes(t *testing.T) {
+	types, err := parseLicenseTypes([]string{"Forbidden", " restricted", "UNKNOWN", "by_exception_only"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"FORBIDDEN": true, "restricted": true, "": true, "by_exception_only": true}, types)
+
+	_, err = parseLicenseTypes([]string{"Notice", "Proprietary"})
+	assert.EqualError(t, err, `unknown license type "Proprietary", must be one of Forbidden, Restricted, Reciprocal, Notice, Permissive, Unencumbered, By_exception_only, Commercial, Unknown`)
+}
+
+func TestCheckLicenses_Disallowed(t *testing.T) {
+	cfg := configmodule.LicensesConfig{}
+	cfg.Types.Overrides = []configmodule.LicenseTypeOverride{{SpdxId: "LicenseRef-Internal", Type: "notice"}}
+	rows := []licenseRow{
+		{Module: "example.com/a", SpdxId: "MIT / LicenseRef-Acme"},
+		{Module: "example.com/b", SpdxId: "LicenseRef-Internal"},
+		{Module: "example.com/c", SpdxId: "GPL-2.0"},
+		{Module: "example.com/d", SpdxId: "MPL-2.0"},
+	}
+	tests := []struct {
+		name       string
+		disallowed []string
+		want       []violation
+	}{
+		{name: "default", disallowed: []string{"Forbidden"}, want: []violation{}},
+		{
+			name:       "restricted and unknown",
+			disallowed: []string{"Restricted", "Unknown"},
+			want: []violation{
+				{Rule: "unknown", Row: rows[0], SpdxId: "LicenseRef-Acme", LicenseType: "Unknown", Reason: "disallowed license type Unknown"},
+				{Rule: "restricted", Row: rows[2], SpdxId: "GPL-2.0", LicenseType: "Restricted", Reason: "disallowed license type Restricted"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			disallowed, err := parseLicenseTypes(tt.disallowed)
+			require.NoError(t, err)
+			violations, err := checkLicenses(rows, cfg, checkPolicy{disallowedTypes: disallowed}, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, violations)
+		})
+	}
+}
+
+func TestSummarizeViolations(t *testing.T) {
+	violations := []violation{
+		{Rule: "unknown"},
+		{Rule: "forbidden"},
+		{Rule: ruleConflict},
+		{Rule: "forbidden"},
+	}
+	assert.Equal(t, "conflict: 1, forbidden: 2, unknown: 1", summarizeViolations(violations))
+	assert.Equal(t, "", summarizeViolations(nil))
+}
+
+func TestCheckLicenses_Licenses(t *testing.T) {
+	rows := []licenseRow{
+		{Module: "example.com/a", SpdxId: "MIT / BSD-3-Clause"},
+		{Module: "example.com/b", SpdxId: "GPL-2.0"},
+		{Module: "example.com/c", SpdxId: "AGPL-3.0"},
+	}
+	policy := checkPolicy{
+		disallowedTypes:    map[string]bool{"FORBIDDEN": true, "restricted": true},
+		allowedLicenses:    stringSet([]string{"GPL-2.0", " AGPL-3.0", ""}),
+		disallowedLicenses: stringSet([]string{"BSD-3-Clause", "AGPL-3.0"}),
+	}
+	violations, err := checkLicenses(rows, configmodule.LicensesConfig{}, policy, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []violation{
+		{Rule: ruleDisallowedLicense, Row: rows[0], SpdxId: "BSD-3-Clause", LicenseType: "Notice", Reason: "disallowed license BSD-3-Clause"},
+		// Disallowed licenses take precedence over allowed ones.
+		{Rule: ruleDisallowedLicense, Row: rows[2], SpdxId: "AGPL-3.0", LicenseType: "Forbidden", Reason: "disallowed license AGPL-3.0"},
+	}, violations)
+}
+
+func TestCheckResults(t *testing.T) {
+	rows := []licenseRow{
+		{Module: "example.com/a", Url: "https://example.com/a/LICENSE", SpdxId: "MIT / AGPL-3.0"},
+		{Module: "example.com/b", Url: "https://example.com/b/LICENSE", SpdxId: "LicenseRef-Acme"},
+	}
+	cfg := configmodule.LicensesConfig{}
+	violations, err := checkLicenses(rows, cfg, checkPolicy{disallowedTypes: map[string]bool{"FORBIDDEN": true}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []checkResult{
+		{Module: "example.com/a", LicenseId: "MIT", LicenseUrl: "https://example.com/a/LICENSE", LicenseType: "Notice", Status: statusOk},
+		{Module: "example.com/a", LicenseId: "AGPL-3.0", LicenseUrl: "https://example.com/a/LICENSE", LicenseType: "Forbidden", Status: "forbidden"},
+		{Module: "example.com/b", LicenseId: "LicenseRef-Acme", LicenseUrl: "https://example.com/b/LICENSE", LicenseType: "Unknown", Status: statusOk},
+	}, checkResults(rows, violations, cfg))
+}
+
+func TestNewCheckPolicy(t *testing.T) {
+	defer func(disallowedTypes, allowedLicenses, disallowedLicenses []string, failOnUnknown bool) {
+		*flagDisallowedTypes, *flagAllowedLicenses, *flagDisallowedLicenses, *flagFailOnUnknown = disallowedTypes, allowedLicenses, disallowedLicenses, failOnUnknown
+	}(*flagDisallowedTypes, *flagAllowedLicenses, *flagDisallowedLicenses, *flagFailOnUnknown)
+
+	tests := []struct {
+		name               string
+		disallowedTypes    []string
+		allowedLicenses    []string
+		disallowedLicenses []string
+		failOnUnknown      bool
+		cfg                configmodule.Policy
+		want               checkPolicy
+		wantErr            bool
+	}{
+		{
+			name:            "flags",
+			disallowedTypes: []string{"Forbidden", " restricted"},
+			allowedLicenses: []string{"GPL-2.0", ""},
+			failOnUnknown:   true,
+			want: checkPolicy{
+				disallowedTypes:    map[string]bool{"FORBIDDEN": true, "restricted": true, "": true},
+				allowedLicenses:    map[string]bool{"GPL-2.0": true},
+				disallowedLicenses: map[string]bool{},
+			},
+		},
+		{
+			name:               "flags and config are combined",
+			disallowedTypes:    []string{"Forbidden"},
+			disallowedLicenses: []string{"WTFPL"},
+			cfg: configmodule.Policy{
+				DisallowedTypes:    []string{"Unknown"},
+				AllowedLicenses:    []string{"MPL-2.0"},
+				DisallowedLicenses: []string{"AGPL-3.0"},
+			},
+			want: checkPolicy{
+				disallowedTypes:    map[string]bool{"FORBIDDEN": true, "": true},
+				allowedLicenses:    map[string]bool{"MPL-2.0": true},
+				disallowedLicenses: map[string]bool{"WTFPL": true, "AGPL-3.0": true},
+			},
+		},
+		{
+			name: "allowed types disallow other types",
+			cfg: configmodule.Policy{
+				AllowedTypes: []string{"Notice", "Permissive", "Unencumbered"},
+			},
+			want: checkPolicy{
+				disallowedTypes: map[string]bool{
+					"FORBIDDEN":                        true,
+					"restricted":                       true,
+					"reciprocal":                       true,
+					"by_exception_only":                true,
+					configmodule.LicenseTypeCommercial: true,
+					"":                                 true,
+				},
+				allowedLicenses:    map[string]bool{},
+				disallowedLicenses: map[string]bool{},
+			},
+		},
+		{
+			name:            "invalid disallowed type",
+			disallowedTypes: []string{"Evil"},
+			wantErr:         true,
+		},
+		{
+			name:    "invalid allowed type",
+			cfg:     configmodule.Policy{AllowedTypes: []string{"Evil"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*flagDisallowedTypes, *flagAllowedLicenses, *flagDisallowedLicenses, *flagFailOnUnknown = tt.disallowedTypes, tt.allowedLicenses, tt.disallowedLicenses, tt.failOnUnknown
+			policy, err := newCheckPolicy(tt.cfg)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, policy)
+		})
+	}
+}
+
+// writesRecorder records each write separately.
+type writesRecorder struct {
+	writes []string
+}
+
+func (r *writesRecorder) Write(p []byte) (int, error) {
+	r.writes = append(r.writes, string(p))
+	return len(p), nil
+}
+
+func TestCheckLicenses_StreamsNdjson(t *testing.T) {
+	rows := []licenseRow{
+		{Module: "example.com/a", Version: "v1.0.0", SpdxId: "MIT", Url: "https://example.com/a/LICENSE"},
+		{Module: "example.com/b", Version: "v1.0.0", SpdxId: "AGPL-3.0", Url: "https://example.com/b/LICENSE"},
+		{Module: "example.com/c", Version: "v0.1.0", SpdxId: "WTFPL / Apache-2.0", Url: "https://example.com/c/LICENSE"},
+	}
+	policy := checkPolicy{
+		disallowedTypes:    map[string]bool{"FORBIDDEN": true},
+		allowedLicenses:    map[string]bool{},
+		disallowedLicenses: map[string]bool{},
+	}
+	var out writesRecorder
+	ndjson := newNdjsonWriter(&out)
+	var emitted []violation
+	violations, err := checkLicenses(rows, configmodule.LicensesConfig{}, policy, func(v violation) error {
+		// the previous violation is already written
+		assert.Len(t, out.writes, len(emitted))
+		emitted = append(emitted, v)
+		return ndjson.write(v)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, violations, emitted)
+	assert.Equal(t, []string{
+		`{"module":"example.com/b","version":"v1.0.0","license":"AGPL-3.0","type":"Forbidden","url":"https://example.com/b/LICENSE","reason":"disallowed license type Forbidden"}` + "\n",
+		`{"module":"example.com/c","version":"v0.1.0","license":"WTFPL","type":"Forbidden","url":"https://example.com/c/LICENSE","reason":"disallowed license type Forbidden"}` + "\n",
+	}, out.writes)
+}
+
+func TestCheckViolations(t *testing.T) {
+	defer func(failOnConflict bool) { *flagFailOnConflict = failOnConflict }(*flagFailOnConflict)
+	rows := []licenseRow{
+		{Module: "example.com/a", Version: "v1.0.0", SpdxId: "MIT / GPL-2.0", Url: "https://example.com/a/LICENSE"},
+		{Module: "example.com/b", Version: "v1.0.0", SpdxId: "AGPL-3.0", Url: "https://example.com/b/LICENSE"},
+	}
+	policy := checkPolicy{
+		disallowedTypes:    map[string]bool{"FORBIDDEN": true},
+		allowedLicenses:    map[string]bool{},
+		disallowedLicenses: map[string]bool{},
+	}
+	tests := []struct {
+		name           string
+		failOnConflict bool
+		want           []string
+	}{
+		{name: "policy", want: []string{"example.com/b AGPL-3.0 forbidden"}},
+		{
+			name:           "conflicts",
+			failOnConflict: true,
+			want:           []string{"example.com/b AGPL-3.0 forbidden", "example.com/a MIT conflict", "example.com/a GPL-2.0 conflict"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*flagFailOnConflict = tt.failOnConflict
+			var emitted []violation
+			violations, err := checkViolations(rows, configmodule.LicensesConfig{}, policy, func(v violation) error {
+				emitted = append(emitted, v)
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, violations, emitted)
+			got := make([]string, 0, len(violations))
+			for _, v := range violations {
+				got = append(got, fmt.Sprintf("%s %s %s", v.Row.Module, v.SpdxId, v.Rule))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCheckLicenses_Expressions(t *testing.T) {
+	rows := []licenseRow{
+		{Module: "example.com/or", SpdxId: "MIT OR Apache-2.0"},
+		{Module: "example.com/or-restricted", SpdxId: "MIT OR GPL-2.0-only"},
+		{Module: "example.com/all-restricted", SpdxId: "GPL-2.0-only OR GPL-3.0-only"},
+		{Module: "example.com/and", SpdxId: "MIT AND GPL-2.0-only"},
+		{Module: "example.com/exception", SpdxId: "GPL-2.0-only WITH Classpath-exception-2.0"},
+	}
+	disallowed, err := parseLicenseTypes([]string{"Restricted", "Unknown"})
+	require.NoError(t, err)
+	violations, err := checkLicenses(rows, configmodule.LicensesConfig{}, checkPolicy{disallowedTypes: disallowed}, nil)
+	require.NoError(t, err)
+	got := make([]string, 0, len(violations))
+	for _, v := range violations {
+		got = append(got, fmt.Sprintf("%s %s %s", v.Row.Module, v.SpdxId, v.Rule))
+	}
+	assert.Equal(t, []string{
+		"example.com/all-restricted GPL-2.0-only restricted",
+		"example.com/all-restricted GPL-3.0-only restricted",
+		"example.com/and GPL-2.0-only restricted",
+		"example.com/exception GPL-2.0-only restricted",
+	}, got)
+
+	assert.Equal(t, []checkResult{
+		{Module: "example.com/or", LicenseId: "MIT OR Apache-2.0", LicenseType: "Notice OR Notice", Status: statusOk},
+		{Module: "example.com/or-restricted", LicenseId: "MIT OR GPL-2.0-only", LicenseType: "Notice OR Restricted", Status: statusOk},
+		{Module: "example.com/all-restricted", LicenseId: "GPL-2.0-only OR GPL-3.0-only", LicenseType: "Restricted OR Restricted", Status: "restricted"},
+		{Module: "example.com/and", LicenseId: "MIT AND GPL-2.0-only", LicenseType: "Notice AND Restricted", Status: "restricted"},
+		{Module: "example.com/exception", LicenseId: "GPL-2.0-only WITH Classpath-exception-2.0", LicenseType: "Restricted", Status: "restricted"},
+	}, checkResults(rows, violations, configmodule.LicensesConfig{}))
+}
+
+func TestConflictingLicenses_Expressions(t *testing.T) {
+	rows := []licenseRow{
+		// MIT can be chosen, so there is no conflict with Apache-2.0.
+		{Module: "example.com/or", SpdxId: "MIT OR GPL-2.0-only / Apache-2.0"},
+		{Module: "example.com/exception", SpdxId: "GPL-2.0-only WITH Classpath-exception-2.0 / MIT"},
+	}
+	violations, err := conflictingLicenses(rows, configmodule.LicensesConfig{}, nil)
+	require.NoError(t, err)
+	const reason = "conflicting license types in module: GPL-2.0-only WITH Classpath-exception-2.0 (Restricted), MIT (Notice)"
+	assert.Equal(t, []violation{
+		{Rule: ruleConflict, Row: rows[1], SpdxId: "GPL-2.0-only WITH Classpath-exception-2.0", LicenseType: "Restricted", Reason: reason},
+		{Rule: ruleConflict, Row: rows[1], SpdxId: "MIT", LicenseType: "Notice", Reason: reason},
+	}, violations)
+}
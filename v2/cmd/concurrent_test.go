@@ -0,0 +1,89 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForEachConcurrently(t *testing.T) {
+	tests := []struct {
+		name        string
+		n           int
+		concurrency int
+	}{
+		{name: "no indexes", n: 0, concurrency: 2},
+		{name: "more indexes than workers", n: 20, concurrency: 3},
+		{name: "more workers than indexes", n: 2, concurrency: 8},
+		{name: "default concurrency", n: 20, concurrency: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			done := make([]int32, tt.n)
+			var running, maxRunning int32
+			var mu sync.Mutex
+			err := forEachConcurrently(context.Background(), tt.n, tt.concurrency, func(_ context.Context, i int) error {
+				r := atomic.AddInt32(&running, 1)
+				defer atomic.AddInt32(&running, -1)
+				mu.Lock()
+				if r > maxRunning {
+					maxRunning = r
+				}
+				mu.Unlock()
+				atomic.AddInt32(&done[i], 1)
+				return nil
+			})
+			require.NoError(t, err)
+			for i, count := range done {
+				assert.Equal(t, int32(1), count, "calls with index %v", i)
+			}
+			if tt.concurrency > 0 {
+				assert.LessOrEqual(t, maxRunning, int32(tt.concurrency))
+			}
+		})
+	}
+}
+
+func TestForEachConcurrently_Error(t *testing.T) {
+	errFailed := errors.New("failed")
+	var calls int32
+	err := forEachConcurrently(context.Background(), 100, 1, func(ctx context.Context, i int) error {
+		atomic.AddInt32(&calls, 1)
+		if i == 2 {
+			return errFailed
+		}
+		return nil
+	})
+	assert.Equal(t, errFailed, err)
+	// remaining indexes are skipped, at most one more is already sent to the
+	// worker
+	assert.LessOrEqual(t, calls, int32(4))
+}
+
+func TestForEachConcurrently_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := forEachConcurrently(ctx, 10, 2, func(ctx context.Context, i int) error {
+		return nil
+	})
+	assert.Equal(t, context.Canceled, err)
+}
@@ -15,22 +15,37 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
 
 	"github.com/google/go-licenses/v2/config"
 	configmodule "github.com/google/go-licenses/v2/config"
-	"github.com/google/go-licenses/v2/ghutils"
 	"github.com/google/go-licenses/v2/gocli"
-	"github.com/google/go-licenses/v2/goutils"
 	"github.com/google/go-licenses/v2/licenses"
-	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
-	"k8s.io/klog/v2"
 )
 
+// flag variables
+var csvIncludeGoVersion bool  // whether to add a column of go versions declared by modules
+var csvUnique bool            // whether to only output the set of unique licenses
+var csvIncludeType bool       // whether to add a column of license types
+var csvIncludeConfidence bool // whether to add a column of license match confidence
+var csvIncludePath bool       // whether to add a column of license file paths
+var csvIncludeLocal bool      // whether to add a column of whether modules are replaced by local directories
+var csvIncludeDeclared bool   // whether to add a column of whether licenses are declared instead of classified
+var csvFormat string          // output format, csv or json
+var csvTemplate string        // go template to output each license, overrides the csv format
+var csvNormalizeSpdx bool     // whether to replace deprecated SPDX IDs with their canonical forms
+var csvOutput string          // file to write the output to, instead of stdout
+
 // csvCmd represents the csv command
 var csvCmd = &cobra.Command{
 	Use:   "csv {<package>, --binary <binary_path>}",
@@ -42,251 +57,348 @@ replace human verification.
 You can manually override scan result for some modules using go-licenses.yaml,
 refer to documentation in https://github.com/Bobgy/go-licenses/tree/main/v2#config--output-examples`,
 	Args: cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: runE(func(cmd *cobra.Command, args []string) error {
 		binaryPath := args[0]
-		err := csvImp(context.Background(), binaryPath)
-		if err != nil {
-			klog.Exit(err)
-		}
-	},
+		return csvImp(context.Background(), []string{binaryPath})
+	}),
 }
-var flagBinary *bool
 
 func init() {
 	rootCmd.AddCommand(csvCmd)
-	flagBinary = csvCmd.Flags().BoolP("binary", "b", false, "scan a go binary instead of a package, the binary must be built using current go working dir in go modules mode")
+	addScanFlags(csvCmd)
+	csvCmd.Flags().StringVar(&csvFormat, "format", csvFormatCsv, "output format, one of csv or json")
+	csvCmd.Flags().StringVar(&csvTemplate, "template", "", "go text/template executed for each license, followed by a new line, it overrides the csv format, e.g. '{{.Module.Path}}\t{{.ID}}\t{{.Type}}'. Available fields: .Module.Path, .Module.Version, .Module.Local, .ID, .URL, .Type, .Confidence, .Declared, .Path, .LineStart, .LineEnd")
+	csvCmd.Flags().BoolVar(&csvIncludeType, "include_type", false, "add a column of license types after license names, e.g. Notice or Restricted, note the save command only accepts 3 columns")
+	csvCmd.Flags().BoolVar(&csvIncludeConfidence, "include_confidence", false, "add a column of the confidence of each license match between 0 and 1 after license types, empty for licenses not classified from license texts, note the save command only accepts 3 columns")
+	csvCmd.Flags().BoolVar(&csvIncludePath, "include_path", false, "add a column of the license file path relative to the module root after license confidence, with the lines of the licenses when they are known, e.g. LICENSE:3-27, empty for licenses configured without a license file, note the save command only accepts 3 columns")
+	csvCmd.Flags().BoolVar(&csvIncludeLocal, "include_local", false, "add a column of whether each module is replaced by a local directory after license paths, true or false, the license url is a local path then, note the save command only accepts 3 columns")
+	csvCmd.Flags().BoolVar(&csvIncludeDeclared, "include_declared", false, "add a column of whether each license is declared by the module, e.g. in a package comment, instead of classified from a license text, true or false, declared licenses are less reliable, note the save command only accepts 3 columns")
+	csvCmd.Flags().BoolVar(&csvIncludeGoVersion, "include_go_version", false, "add a column of the go version declared by each module's go.mod as the last column, note the save command only accepts 3 columns")
+	csvCmd.Flags().BoolVar(&csvNormalizeSpdx, "normalize_spdx", false, "replace deprecated SPDX IDs with their canonical forms, e.g. GPL-2.0 with GPL-2.0-only, the sbom command always does")
+	csvCmd.Flags().StringVar(&csvOutput, "output", "", "file to write the output to instead of stdout, it's only replaced after all licenses are scanned and written successfully")
+	csvCmd.Flags().BoolVar(&csvUnique, "unique", false, "only output the distinct licenses of all dependencies, with the number of modules using each license, only in the csv format without --template")
 }
 
-func csvImp(ctx context.Context, binaryOrImportPath string) (err error) {
-	config, err := configmodule.Load("")
-	if err != nil {
-		return err
-	}
+const (
+	csvFormatCsv  = "csv"
+	csvFormatJson = "json"
+)
 
-	if config.Module.LicenseDB.Path == "" {
-		config.Module.LicenseDB.Path, err = defaultLicenseDB()
-		if err != nil {
-			klog.Exit(fmt.Errorf("licenseDB.path is empty, also failed to get defaulut licenseDB path: %w", err))
-		}
-		klog.V(2).InfoS("Config: use default license DB")
+// csvJsonRow is a license in json output of the csv command.
+type csvJsonRow struct {
+	Module      string `json:"module"`
+	Version     string `json:"version"`
+	LicenseId   string `json:"license_id"`
+	LicenseUrl  string `json:"license_url"`
+	LicenseType string `json:"license_type"`
+	// confidence of the license match, omitted for licenses not classified
+	// from license texts
+	Confidence float64 `json:"confidence,omitempty"`
+	// license file path relative to the module root, and lines of the
+	// licenses in it, omitted when unknown
+	LicensePath      string `json:"license_path,omitempty"`
+	LicenseLineStart int    `json:"license_line_start,omitempty"`
+	LicenseLineEnd   int    `json:"license_line_end,omitempty"`
+	// whether the module is replaced by a local directory, license_url is a
+	// local path then
+	Local bool `json:"local,omitempty"`
+	// whether the license is declared by the module instead of classified
+	// from a license text, it's less reliable
+	Declared bool `json:"declared,omitempty"`
+}
+
+// csvTemplateModule is the module of a license in --template.
+type csvTemplateModule struct {
+	Path    string
+	Version string
+	Local   bool // whether the module is replaced by a local directory
+}
+
+// csvTemplateLicense is the data --template is executed with.
+type csvTemplateLicense struct {
+	Module csvTemplateModule
+	ID     string // SPDX ID, multiple licenses are joined by " / "
+	URL    string
+	Type   string // license type, e.g. Notice
+	// confidence of the license match between 0 and 1, 0 for licenses not
+	// classified from license texts
+	Confidence float64
+	// whether the license is declared by the module instead of classified
+	// from a license text
+	Declared bool
+	Path     string // license file path relative to the module root, may be empty
+	// lines of the licenses in the license file, 0 when unknown
+	LineStart int
+	LineEnd   int
+}
+
+func csvImp(ctx context.Context, binaryOrImportPaths []string) (err error) {
+	if csvFormat != csvFormatCsv && csvFormat != csvFormatJson {
+		return usageError(fmt.Errorf("invalid --format %q: must be one of %s, %s", csvFormat, csvFormatCsv, csvFormatJson))
 	}
-	klog.V(2).InfoS("Config: license DB path", "path", config.Module.LicenseDB.Path)
-	var mods []gocli.Module
-	if flagBinary != nil && *flagBinary {
-		mods, err = modsFromBinary(binaryOrImportPath, config)
-		if err != nil {
-			return err
-		}
-	} else {
-		mods, err = gocli.ListDeps(binaryOrImportPath)
+	// Distinct licenses are only output in the csv format.
+	if csvUnique && csvTemplate != "" {
+		return usageError(fmt.Errorf("--unique cannot be used with --template"))
+	}
+	if csvUnique && csvFormat != csvFormatCsv {
+		return usageError(fmt.Errorf("--unique cannot be used with --format %s", csvFormat))
+	}
+	var tmpl *template.Template
+	if csvTemplate != "" {
+		// Parse the template before scanning, so that mistakes are reported early.
+		tmpl, err = template.New("csv").Option("missingkey=error").Parse(csvTemplate)
 		if err != nil {
-			return err
+			return usageError(fmt.Errorf("invalid --template: %w", err))
 		}
-	}
-	klog.InfoS("Done: found dependencies", "count", len(mods))
-	if klog.V(3).Enabled() {
-		for _, goModule := range mods {
-			klog.InfoS("dependency", "module", goModule.Path, "version", goModule.Version, "Dir", goModule.Dir)
+		// Unknown fields are only reported when executing the template.
+		if err := tmpl.Execute(ioutil.Discard, csvTemplateLicense{}); err != nil {
+			return usageError(fmt.Errorf("invalid --template: %w", err))
 		}
 	}
-	f := os.Stdout // TODO: support writing to a file directly
+	config, err := loadConfig()
 	if err != nil {
-		return errors.Wrapf(err, "Creating license csv file")
+		return err
 	}
-	defer func() {
-		closeErr := f.Close()
-		if err == nil {
-			// When there are no other errors, surface close file error.
-			// Otherwise file content may not be flushed to disk successfully.
-			err = closeErr
+	rows, scanErr := scanLicenses(ctx, binaryOrImportPaths, config)
+	if rows == nil {
+		return scanErr
+	}
+	if csvNormalizeSpdx {
+		for i := range rows {
+			rows[i].SpdxId = licenses.NormalizeSpdxIds(rows[i].SpdxId)
 		}
-	}()
-	_, err = f.WriteString("# Generated by https://github.com/google/go-licenses/v2. DO NOT EDIT.\n")
-	if err != nil {
+	}
+	var buf bytes.Buffer
+	if err := writeCsv(&buf, rows, config.Licenses, tmpl); err != nil {
 		return err
 	}
-	licenseCount := 0
-	errorCount := 0
-	for _, goModule := range mods {
-		report := func(err error, args ...interface{}) {
-			errorCount = errorCount + 1
-			errorArgs := []interface{}{"module", goModule.Path}
-			errorArgs = append(errorArgs, args...)
-			klog.ErrorS(err, "Failed", errorArgs...)
-		}
-		var override configmodule.ModuleOverride
-		for _, o := range config.Module.Overrides {
-			if o.Name == goModule.Path {
-				override = o
-			}
-		}
-		// When override.Version == "", the override apply to any version.
-		if override.Version != "" && override.Version != goModule.Version {
-			report(fmt.Errorf("override version mismatch: found %s, but override is for %s", goModule.Version, override.Version))
-			continue
-		}
-		if override.Skip {
-			klog.InfoS("Skipped", "module", goModule.Path)
-			continue
-		}
-		repo, errGetGithubRepo := goutils.GetGithubRepo(goModule.Path)
-		// this is not immediately an error, because we might specify override.License.Url below
-		type licenseInfo struct {
-			spdxId        string // required
-			licensePath   string // optional, required when url is not supplied
-			url           string // optional
-			subModulePath string // optional
-			lineStart     int    // optional
-			lineEnd       int    // optional
+	if csvOutput == "" {
+		if _, err := os.Stdout.Write(buf.Bytes()); err != nil {
+			return fmt.Errorf("Failed to write csv: %w", err)
 		}
-		hasReportedGetGithubRepoErr := false
-		writeLicenseInfo := func(info licenseInfo) error {
-			if info.spdxId == "" {
-				return fmt.Errorf("failed writeLicenseInfo: info.spdxId required")
-			}
-			url := info.url
-			if url == "" {
-				if info.licensePath == "" {
-					return fmt.Errorf("failed writeLicenseInfo: info.licensePath required when info.url is empty")
-				}
-				if repo == nil && !hasReportedGetGithubRepoErr {
-					// now we need to use repo, so this becomes a fatal error
-					report(errGetGithubRepo)
-					hasReportedGetGithubRepoErr = true // only report once
-					// when repo == nil, repo.RemoteUrl has fallback behavior to use local path,
-					// so keep running to show more information to debug.
-				}
-				licensePath := info.licensePath
-				if info.subModulePath != "" && info.subModulePath != "." {
-					licensePath = info.subModulePath + "/" + info.licensePath
-				}
-				url, err = repo.RemoteUrl(ghutils.RemoteUrlArgs{
-					Path:      licensePath,
-					Version:   goModule.Version,
-					LineStart: info.lineStart,
-					LineEnd:   info.lineEnd,
-				})
-				if err != nil {
-					return err
-				}
-			}
-			moduleString := goModule.Path
-			if info.subModulePath != "" {
-				moduleString = moduleString + "/" + info.subModulePath
-			}
-			_, err := f.WriteString(fmt.Sprintf(
-				"%s, %s, %s\n",
-				moduleString,
-				url,
-				info.spdxId))
+		// Modules that failed scanning are reported after writing all the
+		// licenses found.
+		return scanErr
+	}
+	if scanErr != nil {
+		// The file is only replaced by complete output.
+		return scanErr
+	}
+	return writeFileAtomic(csvOutput, buf.Bytes())
+}
+
+// writeCsv writes rows to w in the output format of flags, executing tmpl
+// for each row when it's not nil.
+func writeCsv(w io.Writer, rows []licenseRow, cfg configmodule.LicensesConfig, tmpl *template.Template) error {
+	if tmpl != nil {
+		for _, row := range rows {
+			err := tmpl.Execute(w, newCsvTemplateLicense(row, cfg))
 			if err != nil {
+				return fmt.Errorf("Failed to execute --template for %s: %w", row.Module, err)
+			}
+			if _, err := io.WriteString(w, "\n"); err != nil {
 				return fmt.Errorf("Failed to write string: %w", err)
 			}
-			licenseCount = licenseCount + 1
-			return nil
 		}
-
-		if override.License.SpdxId != "" {
-			license := override.License
-			if license.Path == "" && license.Url == "" {
-				report(fmt.Errorf("At least one of override.license.Path and override.license.Url is required"))
-				continue
-			}
-			klog.V(4).InfoS("License overridden", "module", goModule.Path, "version", goModule.Version, "Dir", goModule.Dir)
-			klog.V(5).InfoS("Override config", "override", fmt.Sprintf("%+v", override))
-			err := writeLicenseInfo(licenseInfo{
-				url:         license.Url,
-				licensePath: license.Path,
-				spdxId:      license.SpdxId,
-				lineStart:   license.LineStart,
-				lineEnd:     license.LineEnd,
-			})
+		return nil
+	}
+	if csvFormat == csvFormatJson {
+		jsonRows := make([]csvJsonRow, 0, len(rows))
+		for _, row := range rows {
+			jsonRows = append(jsonRows, newCsvJsonRow(row, cfg))
+		}
+		return writeJSON(w, jsonRows)
+	}
+	_, err := io.WriteString(w, "# Generated by https://github.com/google/go-licenses/v2. DO NOT EDIT.\n")
+	if err != nil {
+		return err
+	}
+	if csvUnique {
+		for _, count := range uniqueLicenses(rows) {
+			_, err := fmt.Fprintf(w, "%s, %v\n", count.SpdxId, count.Modules)
 			if err != nil {
-				return err
-			}
-			for _, subModule := range override.SubModules {
-				license := subModule.License
-				if len(subModule.Path) == 0 || len(license.Path) == 0 || len(license.SpdxId) == 0 {
-					report(fmt.Errorf("override.subModule: path, license.path and license.spdxId are required: subModule=%+v", subModule))
-					continue
-				}
-				err := writeLicenseInfo(licenseInfo{
-					url:           license.Url,
-					licensePath:   license.Path,
-					spdxId:        license.SpdxId,
-					lineStart:     license.LineStart,
-					lineEnd:       license.LineEnd,
-					subModulePath: subModule.Path,
-				})
-				if err != nil {
-					return err
-				}
+				return fmt.Errorf("Failed to write string: %w", err)
 			}
-			continue
 		}
-
-		klog.V(4).InfoS("Scanning", "module", goModule.Path, "version", goModule.Version, "Dir", goModule.Dir)
-		fileLicenses, err := licenses.ScanDir(goModule.Dir, licenses.ScanDirOptions{ExcludePaths: override.ExcludePaths, DbPath: config.Module.LicenseDB.Path})
+		return nil
+	}
+	for _, row := range rows {
+		_, err := io.WriteString(w, csvLine(row, cfg)+"\n")
 		if err != nil {
-			report(err)
-			continue
+			return fmt.Errorf("Failed to write string: %w", err)
 		}
-		if len(fileLicenses) == 0 {
-			report(errors.Errorf("licenses not found"))
-			continue
+	}
+	return nil
+}
+
+// writeFileAtomic writes content to a temp file in the directory of path,
+// and renames it to path, so that readers never see a partially written
+// file. The permissions of an existing file at path are kept.
+func writeFileAtomic(path string, content []byte) error {
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("Failed to write %s: %w", path, err)
+	}
+	// Removing the temp file fails harmlessly after it's renamed.
+	defer os.Remove(f.Name())
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return fmt.Errorf("Failed to write %s: %w", path, err)
+	}
+	// Errors of writes buffered by the OS may only be reported by Close.
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("Failed to write %s: %w", path, err)
+	}
+	if err := os.Chmod(f.Name(), perm); err != nil {
+		return fmt.Errorf("Failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("Failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// csvLine formats row as a line of csv output, with the optional columns
+// enabled by flags.
+func csvLine(row licenseRow, cfg configmodule.LicensesConfig) string {
+	line := fmt.Sprintf("%s, %s, %s", row.Module, row.Url, row.SpdxId)
+	if csvIncludeType {
+		line = fmt.Sprintf("%s, %s", line, displayLicenseTypes(row.SpdxId, cfg))
+	}
+	if csvIncludeConfidence {
+		confidence := ""
+		if row.Confidence > 0 {
+			confidence = fmt.Sprintf("%.2f", row.Confidence)
 		}
+		line = fmt.Sprintf("%s, %s", line, confidence)
+	}
+	if csvIncludePath {
+		line = fmt.Sprintf("%s, %s", line, licenseLocation(row))
+	}
+	if csvIncludeLocal {
+		line = fmt.Sprintf("%s, %v", line, row.Local)
+	}
+	if csvIncludeDeclared {
+		line = fmt.Sprintf("%s, %v", line, row.Declared)
+	}
+	if csvIncludeGoVersion {
+		line = fmt.Sprintf("%s, %s", line, row.GoVersion)
+	}
+	return line
+}
 
-		for _, file := range fileLicenses {
-			spdxIds := make([]string, 0)
-			for _, license := range file.Licenses {
-				// We need the joinedSpdxId to be deterministic,
-				// because we want to verify found licenses are
-				// the same as what people have verified manually
-				// last time.
-				// If we use map[string]bool, we cannot guarantee
-				// order.
-				// Although slightly inefficient, looping
-				// through the array to find whether a license
-				// is a new found does guarantee we are appending
-				// licenses into the array in a deterministic
-				// order.
-				found := false
-				for _, spdxId := range spdxIds {
-					if license.SpdxId == spdxId {
-						found = true
-					}
-				}
-				if !found {
-					spdxIds = append(spdxIds, license.SpdxId)
-				}
-			}
-			var joinedSpdxId = ""
-			for _, spdxId := range spdxIds {
-				if joinedSpdxId == "" {
-					joinedSpdxId = spdxId
-				} else {
-					joinedSpdxId = joinedSpdxId + " / " + spdxId
-				}
+// newCsvJsonRow converts row to a license in json output of the csv command.
+func newCsvJsonRow(row licenseRow, cfg configmodule.LicensesConfig) csvJsonRow {
+	return csvJsonRow{
+		Module:           row.Module,
+		Version:          row.Version,
+		LicenseId:        row.SpdxId,
+		LicenseUrl:       row.Url,
+		LicenseType:      displayLicenseTypes(row.SpdxId, cfg),
+		Confidence:       row.Confidence,
+		LicensePath:      row.Path,
+		LicenseLineStart: row.LineStart,
+		LicenseLineEnd:   row.LineEnd,
+		Local:            row.Local,
+		Declared:         row.Declared,
+	}
+}
+
+// newCsvTemplateLicense converts row to the data --template is executed with.
+func newCsvTemplateLicense(row licenseRow, cfg configmodule.LicensesConfig) csvTemplateLicense {
+	return csvTemplateLicense{
+		Module:     csvTemplateModule{Path: row.Module, Version: row.Version, Local: row.Local},
+		ID:         row.SpdxId,
+		URL:        row.Url,
+		Type:       displayLicenseTypes(row.SpdxId, cfg),
+		Confidence: row.Confidence,
+		Declared:   row.Declared,
+		Path:       row.Path,
+		LineStart:  row.LineStart,
+		LineEnd:    row.LineEnd,
+	}
+}
+
+// licenseLocation returns the license file path of row, with the lines of
+// the licenses when they are known, e.g. LICENSE:3-27.
+func licenseLocation(row licenseRow) string {
+	if row.Path == "" || row.LineStart == 0 {
+		return row.Path
+	}
+	return fmt.Sprintf("%s:%v-%v", row.Path, row.LineStart, row.LineEnd)
+}
+
+// displayLicenseTypes returns types of licenses in spdxIds joined by "/", in
+// the same format, e.g. "Notice / Restricted". Licenses in SPDX expressions
+// are replaced by their types, e.g. "Notice OR Restricted" for
+// "MIT OR GPL-2.0-only".
+func displayLicenseTypes(spdxIds string, cfg configmodule.LicensesConfig) string {
+	types := make([]string, 0)
+	for _, part := range strings.Split(spdxIds, "/") {
+		types = append(types, expressionTypes(spdxExpression(part), cfg).String())
+	}
+	return strings.Join(types, " / ")
+}
+
+// expressionTypes returns expression with each license replaced by its
+// displayed type, license exceptions are dropped.
+func expressionTypes(expression *licenses.Expression, cfg configmodule.LicensesConfig) *licenses.Expression {
+	if expression.Operator == "" {
+		return &licenses.Expression{SpdxId: displayLicenseType(licenseType(expression.SpdxId, cfg))}
+	}
+	types := &licenses.Expression{Operator: expression.Operator}
+	for _, operand := range expression.Operands {
+		types.Operands = append(types.Operands, expressionTypes(operand, cfg))
+	}
+	return types
+}
+
+// displayLicenseType returns a license type in title case, or Unknown when
+// the type is unknown.
+func displayLicenseType(t string) string {
+	if t == "" {
+		return string(Unknown)
+	}
+	return strings.ToUpper(t[:1]) + strings.ToLower(t[1:])
+}
+
+// licenseCount is the number of modules using a license.
+type licenseCount struct {
+	SpdxId  string
+	Modules int
+}
+
+// uniqueLicenses returns distinct licenses in rows sorted by SPDX ID, with
+// the number of modules using each license. A module with multiple licenses
+// is counted once for each of them.
+func uniqueLicenses(rows []licenseRow) []licenseCount {
+	modules := make(map[string]map[string]bool)
+	for _, row := range rows {
+		for _, part := range strings.Split(row.SpdxId, "/") {
+			spdxId := strings.TrimSpace(part)
+			if spdxId == "" {
+				continue
 			}
-			klog.V(3).InfoS("License", "module", goModule.Path, "SpdxId", joinedSpdxId, "path", filepath.Join(goModule.Dir, file.Path))
-			writeLicenseInfo(licenseInfo{
-				spdxId:      joinedSpdxId,
-				licensePath: file.Path,
-			})
-			if err != nil {
-				return err
+			if modules[spdxId] == nil {
+				modules[spdxId] = make(map[string]bool)
 			}
+			modules[spdxId][row.Module] = true
 		}
 	}
-	if errorCount > 0 {
-		return fmt.Errorf("Failed to scan licenses for %v module(s)", errorCount)
+	counts := make([]licenseCount, 0, len(modules))
+	for spdxId, mods := range modules {
+		counts = append(counts, licenseCount{SpdxId: spdxId, Modules: len(mods)})
 	}
-	klog.InfoS("Done: scan licenses of dependencies", "licenseCount", licenseCount, "moduleCount", len(mods))
-	return nil
+	sort.Slice(counts, func(i, j int) bool { return counts[i].SpdxId < counts[j].SpdxId })
+	return counts
 }
 
-func modsFromBinary(binaryPath string, cfg *config.GoModLicensesConfig) ([]gocli.Module, error) {
-	metadata, err := gocli.ExtractBinaryMetadata(binaryPath)
+func modsFromBinary(binaryPaths []string, cfg *config.GoModLicensesConfig) ([]gocli.Module, error) {
+	metadata, err := gocli.ExtractBinaryMetadataInDir(flagDir, binaryPaths...)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,402 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"text/template"
+
+	configmodule "github.com/google/go-licenses/v2/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExcludes(t *testing.T) {
+	tests := []struct {
+		name     string
+		excludes *[]string
+		want     map[string]bool
+		wantErr  bool
+	}{
+		{name: "nil", excludes: nil, want: map[string]bool{}},
+		{
+			name:     "modules at versions",
+			excludes: &[]string{"github.com/a/b@v1.0.0", "github.com/a/c@v0.0.0-20210108172934-dcfadaf1a8b1"},
+			want:     map[string]bool{"github.com/a/b@v1.0.0": true, "github.com/a/c@v0.0.0-20210108172934-dcfadaf1a8b1": true},
+		},
+		{name: "no version", excludes: &[]string{"github.com/a/b"}, wantErr: true},
+		{name: "empty version", excludes: &[]string{"github.com/a/b@"}, wantErr: true},
+		{name: "empty module", excludes: &[]string{"@v1.0.0"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseExcludes(tt.excludes)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCsvLine_GoVersion(t *testing.T) {
+	defer func(include bool) { csvIncludeGoVersion = include }(csvIncludeGoVersion)
+	row := licenseRow{Module: "example.com/a", Url: "https://example.com/a/LICENSE", SpdxId: "MIT", GoVersion: "1.16"}
+	tests := []struct {
+		name             string
+		includeGoVersion bool
+		row              licenseRow
+		want             string
+	}{
+		{name: "default", row: row, want: "example.com/a, https://example.com/a/LICENSE, MIT"},
+		{name: "go version", includeGoVersion: true, row: row, want: "example.com/a, https://example.com/a/LICENSE, MIT, 1.16"},
+		{
+			name:             "no go directive",
+			includeGoVersion: true,
+			row:              licenseRow{Module: "example.com/b", Url: "https://example.com/b/LICENSE", SpdxId: "MIT"},
+			want:             "example.com/b, https://example.com/b/LICENSE, MIT, ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			csvIncludeGoVersion = tt.includeGoVersion
+			assert.Equal(t, tt.want, csvLine(tt.row, configmodule.LicensesConfig{}))
+		})
+	}
+}
+
+func TestUniqueLicenses(t *testing.T) {
+	tests := []struct {
+		name string
+		rows []licenseRow
+		want []licenseCount
+	}{
+		{name: "no rows", rows: nil, want: []licenseCount{}},
+		{
+			name: "sorted by SPDX ID",
+			rows: []licenseRow{
+				{Module: "example.com/a", SpdxId: "MIT"},
+				{Module: "example.com/b", SpdxId: "Apache-2.0"},
+				{Module: "example.com/c", SpdxId: "MIT"},
+			},
+			want: []licenseCount{{SpdxId: "Apache-2.0", Modules: 1}, {SpdxId: "MIT", Modules: 2}},
+		},
+		{
+			name: "module with multiple licenses",
+			rows: []licenseRow{
+				{Module: "example.com/a", SpdxId: "MIT / Apache-2.0"},
+				{Module: "example.com/b", SpdxId: "MIT"},
+			},
+			want: []licenseCount{{SpdxId: "Apache-2.0", Modules: 1}, {SpdxId: "MIT", Modules: 2}},
+		},
+		{
+			name: "module counted once for each license",
+			rows: []licenseRow{
+				{Module: "example.com/a", SpdxId: "MIT"},
+				{Module: "example.com/a", SpdxId: "MIT"},
+				{Module: "example.com/b", SpdxId: ""},
+			},
+			want: []licenseCount{{SpdxId: "MIT", Modules: 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, uniqueLicenses(tt.rows))
+		})
+	}
+}
+
+func TestCsvLine_Type(t *testing.T) {
+	defer func(include bool) { csvIncludeType = include }(csvIncludeType)
+	defer func(include bool) { csvIncludeGoVersion = include }(csvIncludeGoVersion)
+	cfg := configmodule.LicensesConfig{}
+	cfg.Types.Overrides = []configmodule.LicenseTypeOverride{{SpdxId: "LicenseRef-Acme", Type: "notice"}}
+	tests := []struct {
+		name             string
+		includeGoVersion bool
+		row              licenseRow
+		want             string
+	}{
+		{name: "single", row: licenseRow{Module: "example.com/a", Url: "u", SpdxId: "GPL-2.0"}, want: "example.com/a, u, GPL-2.0, Restricted"},
+		{name: "multiple", row: licenseRow{Module: "example.com/a", Url: "u", SpdxId: "MIT / MPL-2.0"}, want: "example.com/a, u, MIT / MPL-2.0, Notice / Reciprocal"},
+		{name: "overridden", row: licenseRow{Module: "example.com/a", Url: "u", SpdxId: "LicenseRef-Acme"}, want: "example.com/a, u, LicenseRef-Acme, Notice"},
+		{name: "unknown", row: licenseRow{Module: "example.com/a", Url: "u", SpdxId: "LicenseRef-Other"}, want: "example.com/a, u, LicenseRef-Other, Unknown"},
+		{
+			name:             "before go version",
+			includeGoVersion: true,
+			row:              licenseRow{Module: "example.com/a", Url: "u", SpdxId: "MIT", GoVersion: "1.16"},
+			want:             "example.com/a, u, MIT, Notice, 1.16",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			csvIncludeType = true
+			csvIncludeGoVersion = tt.includeGoVersion
+			assert.Equal(t, tt.want, csvLine(tt.row, cfg))
+		})
+	}
+}
+
+func TestNewCsvJsonRow(t *testing.T) {
+	defer func(compact bool) { flagCompact = compact }(flagCompact)
+	flagCompact = true
+	rows := []csvJsonRow{
+		newCsvJsonRow(licenseRow{Module: "example.com/a", Version: "v1.0.0", Url: "https://example.com/a/LICENSE", SpdxId: "MIT / GPL-2.0"}, configmodule.LicensesConfig{}),
+		newCsvJsonRow(licenseRow{Module: "example.com/b", Version: "v0.1.0", Url: "https://example.com/b/LICENSE", SpdxId: "LicenseRef-Acme"}, configmodule.LicensesConfig{}),
+	}
+	var buf bytes.Buffer
+	require.NoError(t, writeJSON(&buf, rows))
+	assert.JSONEq(t, `[
+		{"module": "example.com/a", "version": "v1.0.0", "license_id": "MIT / GPL-2.0", "license_url": "https://example.com/a/LICENSE", "license_type": "Notice / Restricted"},
+		{"module": "example.com/b", "version": "v0.1.0", "license_id": "LicenseRef-Acme", "license_url": "https://example.com/b/LICENSE", "license_type": "Unknown"}
+	]`, buf.String())
+}
+
+func TestCsvImp_InvalidFormat(t *testing.T) {
+	defer func(format string) { csvFormat = format }(csvFormat)
+	csvFormat = "xml"
+	err := csvImp(context.Background(), []string{"example.com/main"})
+	assert.EqualError(t, err, `invalid --format "xml": must be one of csv, json`)
+}
+
+func TestNewCsvTemplateLicense(t *testing.T) {
+	tmpl := template.Must(template.New("csv").Parse("{{.Module.Path}}@{{.Module.Version}}\t{{.ID}}\t{{.Type}}\t{{.URL}}"))
+	row := licenseRow{Module: "example.com/a", Version: "v1.0.0", Url: "https://example.com/a/LICENSE", SpdxId: "MIT / MPL-2.0"}
+	var buf bytes.Buffer
+	require.NoError(t, tmpl.Execute(&buf, newCsvTemplateLicense(row, configmodule.LicensesConfig{})))
+	assert.Equal(t, "example.com/a@v1.0.0\tMIT / MPL-2.0\tNotice / Reciprocal\thttps://example.com/a/LICENSE", buf.String())
+}
+
+func TestCsvImp_InvalidTemplate(t *testing.T) {
+	defer func(tmpl string) { csvTemplate = tmpl }(csvTemplate)
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{name: "syntax", template: "{{.ID", want: "invalid --template: template: csv:1: unclosed action"},
+		{name: "unknown field", template: "{{.License}}", want: "invalid --template: template: csv:1:2: executing \"csv\" at <.License>: can't evaluate field License in type cmd.csvTemplateLicense"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			csvTemplate = tt.template
+			err := csvImp(context.Background(), []string{"example.com/main"})
+			assert.EqualError(t, err, tt.want)
+		})
+	}
+}
+
+func TestCsvLine_Path(t *testing.T) {
+	defer func(include bool) { csvIncludePath = include }(csvIncludePath)
+	defer func(include bool) { csvIncludeGoVersion = include }(csvIncludeGoVersion)
+	csvIncludePath = true
+	csvIncludeGoVersion = true
+	tests := []struct {
+		name string
+		row  licenseRow
+		want string
+	}{
+		{name: "lines", row: licenseRow{Module: "example.com/a", Url: "u", SpdxId: "MIT", Path: "LICENSE", LineStart: 3, LineEnd: 27, GoVersion: "1.16"}, want: "example.com/a, u, MIT, LICENSE:3-27, 1.16"},
+		{name: "whole file", row: licenseRow{Module: "example.com/a", Url: "u", SpdxId: "MIT", Path: "LICENSE", GoVersion: "1.16"}, want: "example.com/a, u, MIT, LICENSE, 1.16"},
+		{name: "configured url", row: licenseRow{Module: "example.com/a", Url: "u", SpdxId: "MIT", GoVersion: "1.16"}, want: "example.com/a, u, MIT, , 1.16"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, csvLine(tt.row, configmodule.LicensesConfig{}))
+		})
+	}
+}
+
+func TestNewCsvJsonRow_Path(t *testing.T) {
+	row := licenseRow{Module: "example.com/a", Version: "v1.0.0", Url: "u", SpdxId: "MIT", Path: "sub/LICENSE", LineStart: 3, LineEnd: 27}
+	var buf bytes.Buffer
+	require.NoError(t, writeJSON(&buf, newCsvJsonRow(row, configmodule.LicensesConfig{})))
+	assert.JSONEq(t, `{"module": "example.com/a", "version": "v1.0.0", "license_id": "MIT", "license_url": "u", "license_type": "Notice", "license_path": "sub/LICENSE", "license_line_start": 3, "license_line_end": 27}`, buf.String())
+
+	tmpl := template.Must(template.New("csv").Parse("{{.Path}}:{{.LineStart}}-{{.LineEnd}}"))
+	buf.Reset()
+	require.NoError(t, tmpl.Execute(&buf, newCsvTemplateLicense(row, configmodule.LicensesConfig{})))
+	assert.Equal(t, "sub/LICENSE:3-27", buf.String())
+}
+
+func TestCsvLine_Local(t *testing.T) {
+	defer func(includeLocal bool) { csvIncludeLocal = includeLocal }(csvIncludeLocal)
+	csvIncludeLocal = true
+	rows := []licenseRow{
+		{Module: "example.com/m", Url: "https://example.com/m/LICENSE", SpdxId: "MIT"},
+		{Module: "example.com/local", Url: "local/LICENSE", SpdxId: "MIT", Local: true},
+	}
+	var lines []string
+	for _, row := range rows {
+		lines = append(lines, csvLine(row, configmodule.LicensesConfig{}))
+	}
+	assert.Equal(t, []string{
+		"example.com/m, https://example.com/m/LICENSE, MIT, false",
+		"example.com/local, local/LICENSE, MIT, true",
+	}, lines)
+}
+
+func TestNewCsvJsonRow_Local(t *testing.T) {
+	row := newCsvJsonRow(licenseRow{Module: "example.com/local", Url: "local/LICENSE", SpdxId: "MIT", Local: true}, configmodule.LicensesConfig{})
+	assert.True(t, row.Local)
+}
+
+func TestNewCsvTemplateLicense_Local(t *testing.T) {
+	license := newCsvTemplateLicense(licenseRow{Module: "example.com/local", Url: "local/LICENSE", SpdxId: "MIT", Local: true}, configmodule.LicensesConfig{})
+	assert.True(t, license.Module.Local)
+}
+
+func TestCsvLine_Declared(t *testing.T) {
+	defer func(includeDeclared bool) { csvIncludeDeclared = includeDeclared }(csvIncludeDeclared)
+	csvIncludeDeclared = true
+	rows := []licenseRow{
+		{Module: "example.com/m", Url: "https://example.com/m/LICENSE", SpdxId: "MIT", Confidence: 1},
+		{Module: "example.com/declared", Url: "https://example.com/declared/doc.go", SpdxId: "Apache-2.0", Declared: true},
+	}
+	var lines []string
+	for _, row := range rows {
+		lines = append(lines, csvLine(row, configmodule.LicensesConfig{}))
+	}
+	assert.Equal(t, []string{
+		"example.com/m, https://example.com/m/LICENSE, MIT, false",
+		"example.com/declared, https://example.com/declared/doc.go, Apache-2.0, true",
+	}, lines)
+}
+
+func TestNewCsvJsonRow_Declared(t *testing.T) {
+	var buf bytes.Buffer
+	rows := []csvJsonRow{
+		newCsvJsonRow(licenseRow{Module: "example.com/m", Version: "v1.0.0", SpdxId: "MIT"}, configmodule.LicensesConfig{}),
+		newCsvJsonRow(licenseRow{Module: "example.com/declared", Version: "v1.0.0", SpdxId: "Apache-2.0", Declared: true}, configmodule.LicensesConfig{}),
+	}
+	require.NoError(t, json.NewEncoder(&buf).Encode(rows))
+	assert.JSONEq(t, `[
+		{"module": "example.com/m", "version": "v1.0.0", "license_id": "MIT", "license_url": "", "license_type": "Notice"},
+		{"module": "example.com/declared", "version": "v1.0.0", "license_id": "Apache-2.0", "license_url": "", "license_type": "Notice", "declared": true}
+	]`, buf.String())
+}
+
+func TestNewCsvTemplateLicense_Declared(t *testing.T) {
+	license := newCsvTemplateLicense(licenseRow{Module: "example.com/declared", SpdxId: "Apache-2.0", Declared: true}, configmodule.LicensesConfig{})
+	assert.True(t, license.Declared)
+}
+
+func TestDisplayLicenseTypes(t *testing.T) {
+	tests := map[string]string{
+		"MIT":                                  "Notice",
+		"MIT / GPL-2.0":                        "Notice / Restricted",
+		"MIT OR Apache-2.0":                    "Notice OR Notice",
+		"Apache-2.0 OR (MIT AND GPL-2.0-only)": "Notice OR (Notice AND Restricted)",
+		"GPL-2.0-only WITH Classpath-exception-2.0": "Restricted",
+		"LicenseRef-Acme": "Unknown",
+		"":                "Unknown",
+	}
+	for spdxIds, want := range tests {
+		assert.Equal(t, want, displayLicenseTypes(spdxIds, configmodule.LicensesConfig{}), spdxIds)
+	}
+}
+
+func TestWriteCsv(t *testing.T) {
+	defer func(format string, unique bool) { csvFormat, csvUnique = format, unique }(csvFormat, csvUnique)
+	rows := []licenseRow{
+		{Module: "example.com/a", Version: "v1.0.0", Url: "https://example.com/a/LICENSE", SpdxId: "MIT"},
+		{Module: "example.com/b", Version: "v1.0.0", Url: "https://example.com/b/LICENSE", SpdxId: "MIT"},
+	}
+	tests := []struct {
+		name     string
+		format   string
+		unique   bool
+		template string
+		want     string
+	}{
+		{
+			name:   "csv",
+			format: csvFormatCsv,
+			want: "# Generated by https://github.com/google/go-licenses/v2. DO NOT EDIT.\n" +
+				"example.com/a, https://example.com/a/LICENSE, MIT\n" +
+				"example.com/b, https://example.com/b/LICENSE, MIT\n",
+		},
+		{
+			name:   "unique",
+			format: csvFormatCsv,
+			unique: true,
+			want:   "# Generated by https://github.com/google/go-licenses/v2. DO NOT EDIT.\nMIT, 2\n",
+		},
+		{name: "template", format: csvFormatCsv, template: "{{.Module.Path}} {{.ID}}", want: "example.com/a MIT\nexample.com/b MIT\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			csvFormat, csvUnique = tt.format, tt.unique
+			var tmpl *template.Template
+			if tt.template != "" {
+				tmpl = template.Must(template.New("csv").Parse(tt.template))
+			}
+			var buf bytes.Buffer
+			require.NoError(t, writeCsv(&buf, rows, configmodule.LicensesConfig{}, tmpl))
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "licenses.csv")
+	require.NoError(t, ioutil.WriteFile(path, []byte("old"), 0600))
+
+	require.NoError(t, writeFileAtomic(path, []byte("new")))
+	content, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(content))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	// The permissions of the replaced file are kept.
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	// Only the file is left, the temp file is renamed.
+	entries, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	err = writeFileAtomic(filepath.Join(dir, "missing", "licenses.csv"), []byte("new"))
+	assert.Error(t, err)
+}
+
+func TestCsvImp_Unique(t *testing.T) {
+	defer func(format, tmpl string, unique bool) { csvFormat, csvTemplate, csvUnique = format, tmpl, unique }(csvFormat, csvTemplate, csvUnique)
+	tests := []struct {
+		name     string
+		format   string
+		template string
+		want     string
+	}{
+		{name: "json", format: csvFormatJson, want: "--unique cannot be used with --format json"},
+		{name: "template", format: csvFormatCsv, template: "{{.ID}}", want: "--unique cannot be used with --template"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			csvFormat, csvTemplate, csvUnique = tt.format, tt.template, true
+			err := csvImp(context.Background(), []string{"example.com/main"})
+			assert.EqualError(t, err, tt.want)
+			assert.Equal(t, ExitUsage, exitCode(err))
+		})
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/google/go-licenses/v2/config"
+	"github.com/google/go-licenses/v2/dict"
+)
+
+// compliance decisions recorded for audit
+const (
+	decisionApproved       = "approved"
+	decisionRequiresSource = "requires-source"
+	decisionForbidden      = "forbidden"
+)
+
+// who made a compliance decision
+const (
+	decidedByClassifier = "licenseclassifier"
+	decidedByConfig     = "config"
+)
+
+// decisionsReport is a durable record of compliance decisions made by the
+// save command, together with inputs and policy used to make them.
+type decisionsReport struct {
+	Timestamp time.Time        `json:"timestamp"`
+	Inputs    []decisionInput  `json:"inputs"`
+	Decisions []moduleDecision `json:"decisions"`
+}
+
+type decisionInput struct {
+	Path   string `json:"path"`
+	Sha256 string `json:"sha256"`
+}
+
+type moduleDecision struct {
+	Module    string `json:"module"`
+	License   string `json:"license"`
+	Url       string `json:"url"`
+	Decision  string `json:"decision"`
+	DecidedBy string `json:"decidedBy"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// exportDecisions writes compliance decisions of every record in info to
+// path as json. inputPaths are files the decisions are based on, their
+// content hashes are recorded, so reviewers can verify the inputs later.
+func exportDecisions(path string, info []*dict.LicenseRecord, cfg config.GoModLicensesConfig, inputPaths ...string) error {
+	report := decisionsReport{
+		Timestamp: time.Now().UTC(),
+		Inputs:    make([]decisionInput, 0, len(inputPaths)),
+		Decisions: make([]moduleDecision, 0, len(info)),
+	}
+	for _, inputPath := range inputPaths {
+		content, err := ioutil.ReadFile(inputPath)
+		if err != nil {
+			return fmt.Errorf("Failed to read decision input %q: %w", inputPath, err)
+		}
+		report.Inputs = append(report.Inputs, decisionInput{
+			Path:   inputPath,
+			Sha256: fmt.Sprintf("%x", sha256.Sum256(content)),
+		})
+	}
+	for _, record := range info {
+		decision := moduleDecision{
+			Module:    record.Module,
+			License:   record.Type,
+			Url:       record.DownaloadUrl,
+			DecidedBy: decidedByClassifier,
+		}
+		for _, part := range strings.Split(record.Type, "/") {
+			spdxId := strings.TrimSpace(part)
+			for _, override := range cfg.Licenses.Types.Overrides {
+				if override.SpdxId == spdxId {
+					decision.DecidedBy = decidedByConfig
+				}
+			}
+		}
+		reqType, err := requirementType(record.Type, cfg.Licenses)
+		switch {
+		case err != nil:
+			decision.Decision = decisionForbidden
+			decision.Reason = err.Error()
+		case reqType == RedistributeSource:
+			decision.Decision = decisionRequiresSource
+		case reqType == RedistributeNotice:
+			decision.Decision = decisionApproved
+		default:
+			decision.Decision = decisionForbidden
+			decision.Reason = "unknown license type"
+		}
+		report.Decisions = append(report.Decisions, decision)
+	}
+	var content bytes.Buffer
+	if err := writeJSON(&content, report); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, content.Bytes(), permFileCurrentUser); err != nil {
+		return fmt.Errorf("Failed to export decisions to %q: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-licenses/v2/config"
+	"github.com/google/go-licenses/v2/dict"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportDecisions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "decisions_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	csvPath := filepath.Join(dir, "licenses.csv")
+	require.NoError(t, ioutil.WriteFile(csvPath, []byte("content"), 0644))
+	info := []*dict.LicenseRecord{
+		{Module: "example.com/mit", Type: "MIT", DownaloadUrl: "https://example.com/mit/LICENSE"},
+		{Module: "example.com/mpl", Type: "MPL-2.0", DownaloadUrl: "https://example.com/mpl/LICENSE"},
+		{Module: "example.com/internal", Type: "LicenseRef-Internal", DownaloadUrl: "https://example.com/internal/LICENSE"},
+		{Module: "example.com/acme", Type: "LicenseRef-Acme", DownaloadUrl: "https://example.com/acme/LICENSE"},
+	}
+	var cfg config.GoModLicensesConfig
+	cfg.Licenses.Types.Overrides = []config.LicenseTypeOverride{{SpdxId: "LicenseRef-Internal", Type: "notice"}}
+	path := filepath.Join(dir, "decisions.json")
+
+	require.NoError(t, exportDecisions(path, info, cfg, csvPath))
+
+	content, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	var report decisionsReport
+	require.NoError(t, json.Unmarshal(content, &report))
+	assert.False(t, report.Timestamp.IsZero())
+	assert.Equal(t, []decisionInput{{
+		Path:   csvPath,
+		Sha256: "ed7002b439e9ac845f22357d822bac1444730fbdb6016d3ec9432297b9ec9f73",
+	}}, report.Inputs)
+	assert.Equal(t, []moduleDecision{
+		{Module: "example.com/mit", License: "MIT", Url: "https://example.com/mit/LICENSE", Decision: decisionApproved, DecidedBy: decidedByClassifier},
+		{Module: "example.com/mpl", License: "MPL-2.0", Url: "https://example.com/mpl/LICENSE", Decision: decisionRequiresSource, DecidedBy: decidedByClassifier},
+		{Module: "example.com/internal", License: "LicenseRef-Internal", Url: "https://example.com/internal/LICENSE", Decision: decisionApproved, DecidedBy: decidedByConfig},
+		{Module: "example.com/acme", License: "LicenseRef-Acme", Url: "https://example.com/acme/LICENSE", Decision: decisionForbidden, DecidedBy: decidedByClassifier, Reason: "unknown license type"},
+	}, report.Decisions)
+}
+
+func TestExportDecisions_MissingInput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "decisions_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	err = exportDecisions(filepath.Join(dir, "decisions.json"), nil, config.GoModLicensesConfig{}, filepath.Join(dir, "missing.csv"))
+	assert.Error(t, err)
+}
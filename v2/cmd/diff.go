@@ -0,0 +1,198 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	configmodule "github.com/google/go-licenses/v2/config"
+	"github.com/google/go-licenses/v2/dict"
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff <BASELINE_CSV_PATH> {<package>, --binary <binary_path>}",
+	Short: "Compare dependency licenses of a go package or a built go binary with a baseline csv",
+	Long: `"go-licenses diff" scans licenses the same way as "go-licenses csv", and compares
+them with a baseline csv previously generated by "go-licenses csv", e.g. one checked into
+source control. It reports added modules, removed modules and modules whose licenses
+changed, and fails only when forbidden or unknown licenses are newly introduced, so that
+pre-existing issues do not fail CI.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runE(func(cmd *cobra.Command, args []string) error {
+		return diffImp(context.Background(), args[0], args[1])
+	}),
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	addScanFlags(diffCmd)
+}
+
+// kinds of license changes of a module
+const (
+	changeAdded   = "added"
+	changeRemoved = "removed"
+	changeChanged = "changed"
+)
+
+// licenseChange is a module whose licenses differ from the baseline.
+type licenseChange struct {
+	Kind   string
+	Module string
+	// SPDX IDs of licenses in the baseline and the current scan, sorted,
+	// empty for added and removed modules respectively
+	Before []string
+	After  []string
+	// SPDX IDs of licenses that are not in the baseline of the module and
+	// are forbidden or unknown
+	Introduced []string
+}
+
+func diffImp(ctx context.Context, baselinePath string, binaryOrImportPath string) error {
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	// Load the baseline before scanning, so that mistakes are reported early.
+	content, err := ioutil.ReadFile(baselinePath)
+	if err != nil {
+		return usageError(fmt.Errorf("Failed to read baseline, path=%q: %w", baselinePath, err))
+	}
+	// Urls of the main module or local modules may be empty, and they are
+	// not compared.
+	baseline, err := dict.LoadLicenseRecordsWithOptions(bytes.NewReader(content), dict.LoadOptions{AllowEmptyUrl: true})
+	if err != nil {
+		return err
+	}
+	rows, scanErr := scanLicenses(ctx, []string{binaryOrImportPath}, config)
+	if rows == nil {
+		return scanErr
+	}
+	changes := diffLicenses(baseline, rows, config.Licenses)
+	introduced := 0
+	for _, change := range changes {
+		switch change.Kind {
+		case changeAdded:
+			fmt.Printf("+ %s %s\n", change.Module, describeLicenses(change.After, config.Licenses))
+		case changeRemoved:
+			fmt.Printf("- %s %s\n", change.Module, describeLicenses(change.Before, config.Licenses))
+		default:
+			fmt.Printf("~ %s %s -> %s\n", change.Module, describeLicenses(change.Before, config.Licenses), describeLicenses(change.After, config.Licenses))
+		}
+		for _, spdxId := range change.Introduced {
+			klog.ErrorS(fmt.Errorf("newly introduced license type %s", displayLicenseType(licenseType(spdxId, config.Licenses))), "Failed", "module", change.Module, "license", spdxId)
+		}
+		introduced = introduced + len(change.Introduced)
+	}
+	klog.InfoS("Done: diff", "changeCount", len(changes))
+	if introduced > 0 {
+		return violationError(fmt.Errorf("Found %v newly introduced forbidden or unknown license(s) compared with %s", introduced, baselinePath))
+	}
+	return scanErr
+}
+
+// diffLicenses compares licenses found in rows with the baseline, and returns
+// changes sorted by module.
+func diffLicenses(baseline []*dict.LicenseRecord, rows []licenseRow, cfg configmodule.LicensesConfig) []licenseChange {
+	before := make(map[string]map[string]bool)
+	for _, record := range baseline {
+		addSpdxIds(before, record.Module, record.Type)
+	}
+	after := make(map[string]map[string]bool)
+	for _, row := range rows {
+		addSpdxIds(after, row.Module, row.SpdxId)
+	}
+	modules := make([]string, 0, len(before)+len(after))
+	for module := range before {
+		modules = append(modules, module)
+	}
+	for module := range after {
+		if _, ok := before[module]; !ok {
+			modules = append(modules, module)
+		}
+	}
+	sort.Strings(modules)
+	changes := make([]licenseChange, 0)
+	for _, module := range modules {
+		change := licenseChange{
+			Module: module,
+			Before: sortedKeys(before[module]),
+			After:  sortedKeys(after[module]),
+		}
+		_, inBefore := before[module]
+		_, inAfter := after[module]
+		switch {
+		case !inBefore:
+			change.Kind = changeAdded
+		case !inAfter:
+			change.Kind = changeRemoved
+		case strings.Join(change.Before, "/") != strings.Join(change.After, "/"):
+			change.Kind = changeChanged
+		default:
+			continue
+		}
+		for _, spdxId := range change.After {
+			if before[module][spdxId] {
+				continue
+			}
+			t := licenseType(spdxId, cfg)
+			if t == "" || t == "FORBIDDEN" {
+				change.Introduced = append(change.Introduced, spdxId)
+			}
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// addSpdxIds adds SPDX IDs in spdxIds joined by "/" to licenses of module.
+func addSpdxIds(licenses map[string]map[string]bool, module string, spdxIds string) {
+	if licenses[module] == nil {
+		licenses[module] = make(map[string]bool)
+	}
+	for _, part := range strings.Split(spdxIds, "/") {
+		if spdxId := strings.TrimSpace(part); spdxId != "" {
+			licenses[module][spdxId] = true
+		}
+	}
+}
+
+// sortedKeys returns keys of set in ascending order.
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// describeLicenses returns SPDX IDs with their types, e.g.
+// "MIT (Notice) / GPL-2.0 (Restricted)".
+func describeLicenses(spdxIds []string, cfg configmodule.LicensesConfig) string {
+	described := make([]string, 0, len(spdxIds))
+	for _, spdxId := range spdxIds {
+		described = append(described, fmt.Sprintf("%s (%s)", spdxId, displayLicenseType(licenseType(spdxId, cfg))))
+	}
+	return strings.Join(described, " / ")
+}
@@ -0,0 +1,77 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	configmodule "github.com/google/go-licenses/v2/config"
+	"github.com/google/go-licenses/v2/dict"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffLicenses(t *testing.T) {
+	// Urls of the main module may be empty in the baseline.
+	baseline, err := dict.LoadLicenseRecordsWithOptions(strings.NewReader(`# Generated by https://github.com/google/go-licenses/v2. DO NOT EDIT.
+example.com/main, , MIT
+example.com/same, https://example.com/same/LICENSE, MIT
+example.com/removed, https://example.com/removed/LICENSE, MIT
+example.com/changed, https://example.com/changed/LICENSE, MIT / AGPL-3.0
+`), dict.LoadOptions{AllowEmptyUrl: true})
+	require.NoError(t, err)
+	rows := []licenseRow{
+		{Module: "example.com/main", SpdxId: "MIT"},
+		{Module: "example.com/same", SpdxId: "MIT"},
+		{Module: "example.com/changed", SpdxId: "AGPL-3.0 / Apache-2.0"},
+		{Module: "example.com/added", SpdxId: "Custom-1.0"},
+	}
+
+	changes := diffLicenses(baseline, rows, configmodule.LicensesConfig{})
+
+	// Forbidden licenses in the baseline of a module are not introduced again.
+	assert.Equal(t, []licenseChange{
+		{Kind: changeAdded, Module: "example.com/added", Before: []string{}, After: []string{"Custom-1.0"}, Introduced: []string{"Custom-1.0"}},
+		{Kind: changeChanged, Module: "example.com/changed", Before: []string{"AGPL-3.0", "MIT"}, After: []string{"AGPL-3.0", "Apache-2.0"}},
+		{Kind: changeRemoved, Module: "example.com/removed", Before: []string{"MIT"}, After: []string{}},
+	}, changes)
+}
+
+func TestDiffLicenses_TypeOverride(t *testing.T) {
+	cfg := configmodule.LicensesConfig{}
+	cfg.Types.Overrides = append(cfg.Types.Overrides, configmodule.LicenseTypeOverride{SpdxId: "Custom-1.0", Type: "NOTICE"})
+	rows := []licenseRow{{Module: "example.com/added", SpdxId: "Custom-1.0"}}
+
+	changes := diffLicenses(nil, rows, cfg)
+
+	require.Len(t, changes, 1)
+	assert.Empty(t, changes[0].Introduced)
+}
+
+func TestLoadLicenseRecords_EmptyUrl(t *testing.T) {
+	const csv = "example.com/main, , MIT\n"
+	_, err := dict.LoadLicenseRecords(strings.NewReader(csv))
+	assert.Error(t, err)
+	records, err := dict.LoadLicenseRecordsWithOptions(strings.NewReader(csv), dict.LoadOptions{AllowEmptyUrl: true})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "example.com/main", records[0].Module)
+	assert.Equal(t, "MIT", records[0].Type)
+}
+
+func TestDescribeLicenses(t *testing.T) {
+	assert.Equal(t, "Apache-2.0 (Notice) / Custom-1.0 (Unknown)", describeLicenses([]string{"Apache-2.0", "Custom-1.0"}, configmodule.LicensesConfig{}))
+}
@@ -0,0 +1,106 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/google/go-licenses/v2/ghutils"
+	"github.com/spf13/cobra"
+)
+
+// flags shared by commands that download license texts
+var flagGithubToken *string
+var flagDownloadRetries *int
+var flagDownloadConcurrency *int
+
+// DefaultDownloadConcurrency is the default number of license texts
+// downloaded concurrently. Downloads wait for the network, so it does not
+// depend on the number of CPUs.
+const DefaultDownloadConcurrency = 8
+
+// addDownloadFlags adds flags shared by commands that download license texts
+// to cmd.
+func addDownloadFlags(cmd *cobra.Command) {
+	if flagGithubToken == nil {
+		flagGithubToken = new(string)
+		flagDownloadRetries = new(int)
+		flagDownloadConcurrency = new(int)
+	}
+	cmd.Flags().StringVar(flagGithubToken, "github_token", "", "github token sent when downloading license texts from github to raise its rate limits, defaults to the GITHUB_TOKEN environment variable")
+	cmd.Flags().IntVar(flagDownloadRetries, "download_retries", 3, "number of retries with exponential backoff when downloading a license text fails with a server error or is rate limited")
+	cmd.Flags().IntVar(flagDownloadConcurrency, "download_concurrency", DefaultDownloadConcurrency, "number of license texts downloaded concurrently, output is in the same order regardless")
+}
+
+// downloadConcurrency returns the number of license texts downloaded
+// concurrently, DefaultDownloadConcurrency when concurrency is not positive.
+func downloadConcurrency(concurrency int) int {
+	if concurrency <= 0 {
+		return DefaultDownloadConcurrency
+	}
+	return concurrency
+}
+
+// newDownloader returns a downloader of license texts configured by the
+// download flags. Each license url is downloaded once by the downloader.
+func newDownloader() *ghutils.Downloader {
+	token := *flagGithubToken
+	if token == "" {
+		token = os.Getenv("GITHUB_TOKEN")
+	}
+	return &ghutils.Downloader{
+		Retries: *flagDownloadRetries,
+		Token:   token,
+	}
+}
+
+// newHostRestrictedDownloader returns a downloader like newDownloader, which
+// also refuses redirects to hosts not matching allowedHosts. All hosts are
+// allowed when there are no patterns.
+func newHostRestrictedDownloader(allowedHosts []string) *ghutils.Downloader {
+	download := newDownloader()
+	if len(allowedHosts) > 0 {
+		download.CheckRedirect = func(url string) error {
+			return checkLicenseHost(url, allowedHosts)
+		}
+	}
+	return download
+}
+
+// checkLicenseHost returns an error when the host of licenseUrl does not
+// match any of the allowed host patterns, e.g. *.googlesource.com. All hosts
+// are allowed when there are no patterns.
+func checkLicenseHost(licenseUrl string, allowedHosts []string) error {
+	if len(allowedHosts) == 0 {
+		return nil
+	}
+	u, err := url.Parse(licenseUrl)
+	if err != nil {
+		return fmt.Errorf("invalid license url %q: %w", licenseUrl, err)
+	}
+	host := strings.ToLower(u.Hostname())
+	if host != "" {
+		for _, pattern := range allowedHosts {
+			if matched, _ := path.Match(strings.ToLower(pattern), host); matched {
+				return nil
+			}
+		}
+	}
+	return violationError(fmt.Errorf("license url %s is not allowed: host %q does not match any of module.allowedLicenseHosts %q", licenseUrl, host, allowedHosts))
+}
@@ -0,0 +1,72 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckLicenseHost(t *testing.T) {
+	allowedHosts := []string{"github.com", "*.googlesource.com"}
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{url: "https://github.com/google/go-licenses/blob/v1.0.0/LICENSE", allowed: true},
+		{url: "https://GitHub.com/google/go-licenses/blob/v1.0.0/LICENSE", allowed: true},
+		{url: "https://go.googlesource.com/text/+/refs/tags/v0.3.5/LICENSE", allowed: true},
+		{url: "https://github.com.evil.example/LICENSE", allowed: false},
+		{url: "https://example.com/LICENSE", allowed: false},
+		{url: "LICENSE", allowed: false},
+	}
+	for _, tt := range tests {
+		err := checkLicenseHost(tt.url, allowedHosts)
+		assert.Equal(t, tt.allowed, err == nil, "checkLicenseHost(%q): %v", tt.url, err)
+	}
+	assert.Nil(t, checkLicenseHost("https://example.com/LICENSE", nil), "all hosts are allowed by default")
+}
+
+func TestHostRestrictedDownloader_Redirect(t *testing.T) {
+	allowed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "license text")
+	}))
+	defer allowed.Close()
+	// localhost is another host than 127.0.0.1 of the servers
+	disallowedUrl := strings.Replace(allowed.URL, "127.0.0.1", "localhost", 1) + "/LICENSE"
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := allowed.URL + "/LICENSE"
+		if r.URL.Path == "/disallowed" {
+			target = disallowedUrl
+		}
+		http.Redirect(w, r, target, http.StatusFound)
+	}))
+	defer redirect.Close()
+
+	download := newHostRestrictedDownloader([]string{"127.0.0.1"})
+	content, err := download.SmartDownload(redirect.URL + "/allowed")
+	require.Nil(t, err)
+	assert.Equal(t, "license text", content)
+	_, err = download.SmartDownload(redirect.URL + "/disallowed")
+	require.NotNil(t, err, "should refuse redirects to hosts that are not allowed")
+	assert.Contains(t, err.Error(), "is not allowed")
+	assert.Equal(t, ExitViolation, exitCode(err))
+}
@@ -0,0 +1,121 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+
+	"github.com/google/go-licenses/v2/gocli"
+	"github.com/google/go-licenses/v2/licenses"
+	"github.com/pkg/errors"
+)
+
+const classifierModule = "github.com/google/licenseclassifier/v2"
+
+// evidence is the metadata sidecar of a classified license file, so that
+// auditors can verify the classification independently.
+type evidence struct {
+	Module              string          `json:"module"`
+	Version             string          `json:"version"`
+	Path                string          `json:"path"` // path of the license file in the module
+	Declared            bool            `json:"declared,omitempty"`
+	Readme              bool            `json:"readme,omitempty"` // whether the license is found in a README
+	Licenses            []evidenceMatch `json:"licenses"`
+	Classifier          string          `json:"classifier"`          // classifier module path and version
+	ClassifierLicenseDB string          `json:"classifierLicenseDB"` // license DB used by the classifier
+}
+
+// evidenceMatch is a license found in the license file.
+type evidenceMatch struct {
+	SpdxId     string  `json:"spdxId"`
+	Confidence float64 `json:"confidence"`
+	StartLine  int     `json:"startLine"`
+	EndLine    int     `json:"endLine"`
+	Language   string  `json:"language,omitempty"`
+}
+
+// writeEvidence copies a classified license file of a module to
+// <dir>/<module>@<version>/<path>, or <dir>/<module>/<path> when the module
+// doesn't have a version, with classification details written to
+// <path>.json next to it.
+func writeEvidence(dir string, goModule gocli.Module, file licenses.File, licenseDB string) error {
+	moduleDir := filepath.Join(dir, goModule.Path)
+	if goModule.Version != "" {
+		moduleDir = moduleDir + "@" + goModule.Version
+	}
+	dest := filepath.Join(moduleDir, filepath.FromSlash(file.Path))
+	if strings.HasPrefix(file.Path, "../") {
+		// a license in a parent folder of the module
+		dest = filepath.Join(moduleDir, path.Base(file.Path))
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), permDirCurrentUser); err != nil {
+		return errors.Wrapf(err, "Failed to mkdir %s", filepath.Dir(dest))
+	}
+	content, err := ioutil.ReadFile(filepath.Join(goModule.Dir, file.Path))
+	if err != nil {
+		return errors.Wrapf(err, "Failed to read %s", file.Path)
+	}
+	if err := ioutil.WriteFile(dest, content, permFileCurrentUser); err != nil {
+		return errors.Wrapf(err, "Failed to write %s", dest)
+	}
+	matches := make([]evidenceMatch, 0, len(file.Licenses))
+	for _, found := range file.Licenses {
+		matches = append(matches, evidenceMatch{
+			SpdxId:     found.SpdxId,
+			Confidence: found.Confidence,
+			StartLine:  found.StartLine,
+			EndLine:    found.EndLine,
+			Language:   found.Language,
+		})
+	}
+	var buf bytes.Buffer
+	err = writeJSON(&buf, evidence{
+		Module:              goModule.Path,
+		Version:             goModule.Version,
+		Path:                file.Path,
+		Declared:            file.Declared,
+		Readme:              file.Readme,
+		Licenses:            matches,
+		Classifier:          classifierVersion(),
+		ClassifierLicenseDB: licenseDB,
+	})
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(dest+".json", buf.Bytes(), permFileCurrentUser); err != nil {
+		return errors.Wrapf(err, "Failed to write %s.json", dest)
+	}
+	return nil
+}
+
+// classifierVersion returns the license classifier module path and version
+// this binary is built with.
+func classifierVersion() string {
+	version := "unknown"
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Path == classifierModule {
+				version = dep.Version
+			}
+		}
+	}
+	return classifierModule + "@" + version
+}
@@ -0,0 +1,77 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-licenses/v2/gocli"
+	"github.com/google/go-licenses/v2/licenses"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteEvidence(t *testing.T) {
+	defer func(compact bool) { flagCompact = compact }(flagCompact)
+	flagCompact = true
+	moduleDir, err := ioutil.TempDir("", "evidence_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(moduleDir)
+	writeFiles(t, moduleDir, "sub/LICENSE")
+	file := licenses.File{
+		Path:     "sub/LICENSE",
+		Licenses: []licenses.Found{{SpdxId: "MIT", Confidence: 0.98, StartLine: 1, EndLine: 21}},
+	}
+	tests := []struct {
+		name    string
+		version string
+		dest    string
+	}{
+		{name: "versioned", version: "v1.2.0", dest: "example.com/a@v1.2.0/sub/LICENSE"},
+		{name: "no version", version: "", dest: "example.com/a/sub/LICENSE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "evidence_test")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+			goModule := gocli.Module{Path: "example.com/a", Version: tt.version, Dir: moduleDir}
+
+			require.NoError(t, writeEvidence(dir, goModule, file, "/licenses"))
+			assert.ElementsMatch(t, []string{tt.dest, tt.dest + ".json"}, listFiles(t, dir))
+			content, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(tt.dest)))
+			require.NoError(t, err)
+			assert.Equal(t, "sub/LICENSE", string(content))
+			metadata, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(tt.dest)+".json"))
+			require.NoError(t, err)
+			assert.JSONEq(t, `{
+				"module": "example.com/a",
+				"version": "`+tt.version+`",
+				"path": "sub/LICENSE",
+				"licenses": [{"spdxId": "MIT", "confidence": 0.98, "startLine": 1, "endLine": 21}],
+				"classifier": "`+classifierVersion()+`",
+				"classifierLicenseDB": "/licenses"
+			}`, string(metadata))
+		})
+	}
+}
+
+func TestClassifierVersion(t *testing.T) {
+	assert.True(t, strings.HasPrefix(classifierVersion(), classifierModule+"@"), classifierVersion())
+}
@@ -0,0 +1,92 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+)
+
+// Exit codes of go-licenses commands, so that CI can react differently to a
+// policy violation and e.g. a transient network failure.
+const (
+	// The command succeeded.
+	ExitOK = 0
+	// Some licenses violate the policy, e.g. forbidden or unknown licenses.
+	ExitViolation = 1
+	// Invalid arguments, flags or config.
+	ExitUsage = 2
+	// Failed to scan, download or save licenses, e.g. because of a network
+	// failure.
+	ExitScanError = 3
+)
+
+// exitError is an error that exits a command with a specific exit code.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// violationError marks err as a policy violation.
+func violationError(err error) error {
+	return &exitError{code: ExitViolation, err: err}
+}
+
+// usageError marks err as invalid arguments, flags or config.
+func usageError(err error) error {
+	return &exitError{code: ExitUsage, err: err}
+}
+
+// runE adapts a command implementation to cobra.Command.RunE. Errors without
+// an exit code are scan errors, so that errors returned by cobra itself, e.g.
+// for missing arguments, are distinguished as usage errors by exitCode.
+func runE(run func(cmd *cobra.Command, args []string) error) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		// Usage is only relevant to errors before running the command.
+		cmd.SilenceUsage = true
+		err := run(cmd, args)
+		if err == nil {
+			return nil
+		}
+		var exitErr *exitError
+		if errors.As(err, &exitErr) {
+			return err
+		}
+		return &exitError{code: ExitScanError, err: err}
+	}
+}
+
+// exitCode returns the exit code for an error returned by a command.
+func exitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+	// Errors not returned by runE are returned by cobra before running the
+	// command, e.g. unknown flags or a wrong number of arguments.
+	return ExitUsage
+}
@@ -0,0 +1,48 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "success", err: nil, want: ExitOK},
+		{name: "violation", err: violationError(errors.New("forbidden")), want: ExitViolation},
+		{name: "wrapped violation", err: fmt.Errorf("check: %w", violationError(errors.New("forbidden"))), want: ExitViolation},
+		{name: "usage", err: usageError(errors.New("invalid flag")), want: ExitUsage},
+		{name: "scan error", err: errors.New("network failure"), want: ExitScanError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			err := runE(func(*cobra.Command, []string) error { return tt.err })(cmd, nil)
+			assert.Equal(t, tt.want, exitCode(err))
+			assert.True(t, cmd.SilenceUsage)
+		})
+	}
+	// errors of cobra itself are returned before running a command
+	assert.Equal(t, ExitUsage, exitCode(errors.New("unknown flag: --foo")))
+}
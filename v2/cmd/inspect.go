@@ -0,0 +1,100 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	configmodule "github.com/google/go-licenses/v2/config"
+	"github.com/google/go-licenses/v2/gocli"
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+)
+
+// inspectCmd represents the inspect command
+var inspectCmd = &cobra.Command{
+	Use:   "inspect <module>@<version>",
+	Short: "Scan licenses of a module version downloaded from the go module proxy",
+	Long: `"go-licenses inspect" downloads a module version, e.g. golang.org/x/text@v0.3.5,
+using "go mod download" into a temp dir, scans its licenses the same way as
+"go-licenses csv", and removes the temp dir. The module does not need to be a
+dependency of the current dir, e.g. to review licenses before adopting it.
+The version may also be a query like latest.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runE(func(cmd *cobra.Command, args []string) error {
+		return inspectImp(context.Background(), args[0])
+	}),
+}
+
+func init() {
+	rootCmd.AddCommand(inspectCmd)
+	addScanFlags(inspectCmd)
+	// Flags listing dependencies do not apply to a downloaded module.
+	for _, name := range []string{"binary", "build_tags", "goos", "goarch", "include_test_deps"} {
+		inspectCmd.Flags().MarkHidden(name)
+	}
+}
+
+func inspectImp(ctx context.Context, moduleVersion string) error {
+	config, err := loadInspectConfig()
+	if err != nil {
+		return err
+	}
+	modCache, err := ioutil.TempDir("", "go-licenses-inspect")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := os.RemoveAll(modCache); err != nil {
+			klog.Warningf("Failed to remove temp dir %s: %v", modCache, err)
+		}
+	}()
+	rows, _, scanErr := scanModulesWithMissing(ctx, config, func() ([]gocli.Module, error) {
+		mod, err := gocli.DownloadModule(moduleVersion, modCache)
+		if err != nil {
+			return nil, err
+		}
+		klog.InfoS("Done: download module", "module", mod.Path, "version", mod.Version)
+		return []gocli.Module{*mod}, nil
+	}, nil)
+	if rows == nil {
+		return scanErr
+	}
+	for _, row := range rows {
+		fmt.Printf("%s, %s, %s, %s\n", row.Module, row.Url, row.SpdxId, displayLicenseTypes(row.SpdxId, config.Licenses))
+	}
+	return scanErr
+}
+
+// loadInspectConfig loads the config like other commands, but uses defaults
+// when go-licenses.yaml is not found and --config is not specified, because
+// inspected modules are not related to the current dir.
+func loadInspectConfig() (*configmodule.GoModLicensesConfig, error) {
+	if cfgFile == "" {
+		path := configmodule.DefaultConfigPath
+		if flagDir != "" {
+			path = filepath.Join(flagDir, path)
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			klog.V(2).InfoS("Config: not found, using defaults", "path", path)
+			return &configmodule.GoModLicensesConfig{}, nil
+		}
+	}
+	return loadConfig()
+}
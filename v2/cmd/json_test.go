@@ -0,0 +1,52 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteJSON(t *testing.T) {
+	defer func(compact bool) { flagCompact = compact }(flagCompact)
+	v := map[string]interface{}{"module": "example.com/a", "licenses": []string{"MIT"}}
+	tests := []struct {
+		name    string
+		compact bool
+		want    string
+	}{
+		{
+			name:    "indented",
+			compact: false,
+			want:    "{\n  \"licenses\": [\n    \"MIT\"\n  ],\n  \"module\": \"example.com/a\"\n}\n",
+		},
+		{
+			name:    "compact",
+			compact: true,
+			want:    "{\"licenses\":[\"MIT\"],\"module\":\"example.com/a\"}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flagCompact = tt.compact
+			var out bytes.Buffer
+			require.NoError(t, writeJSON(&out, v))
+			assert.Equal(t, tt.want, out.String())
+		})
+	}
+}
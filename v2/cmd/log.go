@@ -0,0 +1,155 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/klog/v2"
+)
+
+const (
+	logFormatText = "text"
+	logFormatJson = "json"
+)
+
+// setupLogging configures klog for --log_format.
+func setupLogging() error {
+	switch logFormat {
+	case logFormatText:
+		return nil
+	case logFormatJson:
+		klog.SetLogger(newJsonLogger(os.Stderr))
+		return nil
+	default:
+		return fmt.Errorf("invalid --log_format %q: must be one of %s, %s", logFormat, logFormatText, logFormatJson)
+	}
+}
+
+// jsonLogger is a logr.Logger writing each log as a json line of fields, with
+// the key value pairs of structured logs, e.g. klog.InfoS, as fields.
+// Verbosity is filtered by klog using -v, before logs reach the logger.
+type jsonLogger struct {
+	out    *jsonOutput
+	name   string
+	level  int
+	values []interface{}
+}
+
+// jsonOutput serializes writes of loggers derived from the same logger.
+type jsonOutput struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+var _ logr.Logger = &jsonLogger{}
+
+func newJsonLogger(w io.Writer) *jsonLogger {
+	return &jsonLogger{out: &jsonOutput{w: w}}
+}
+
+func (l *jsonLogger) Enabled() bool {
+	return true
+}
+
+func (l *jsonLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.write("info", nil, msg, keysAndValues)
+}
+
+func (l *jsonLogger) Error(err error, msg string, keysAndValues ...interface{}) {
+	l.write("error", err, msg, keysAndValues)
+}
+
+func (l *jsonLogger) V(level int) logr.Logger {
+	derived := *l
+	derived.level += level
+	return &derived
+}
+
+func (l *jsonLogger) WithValues(keysAndValues ...interface{}) logr.Logger {
+	derived := *l
+	derived.values = append(append([]interface{}{}, l.values...), keysAndValues...)
+	return &derived
+}
+
+func (l *jsonLogger) WithName(name string) logr.Logger {
+	derived := *l
+	if derived.name != "" {
+		name = derived.name + "." + name
+	}
+	derived.name = name
+	return &derived
+}
+
+func (l *jsonLogger) write(level string, err error, msg string, keysAndValues []interface{}) {
+	record := make(map[string]interface{})
+	addFields(record, l.values)
+	addFields(record, keysAndValues)
+	record["ts"] = time.Now().UTC().Format(time.RFC3339Nano)
+	record["level"] = level
+	// Unstructured logs, e.g. klog.Infof, end with a new line.
+	record["msg"] = strings.TrimSuffix(msg, "\n")
+	if l.level > 0 {
+		record["v"] = l.level
+	}
+	if l.name != "" {
+		record["logger"] = l.name
+	}
+	if err != nil {
+		record["err"] = err.Error()
+	}
+	line, marshalErr := json.Marshal(record)
+	if marshalErr != nil {
+		line, _ = json.Marshal(map[string]interface{}{
+			"ts":    record["ts"],
+			"level": "error",
+			"msg":   fmt.Sprintf("Failed to marshal log %q: %v", record["msg"], marshalErr),
+		})
+	}
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	l.out.w.Write(append(line, '\n'))
+}
+
+// addFields adds key value pairs to record. Keys of the record itself, like
+// msg, are prefixed by an underscore, so that they are not overwritten.
+// Errors and values implementing fmt.Stringer are written as strings.
+func addFields(record map[string]interface{}, keysAndValues []interface{}) {
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key := fmt.Sprint(keysAndValues[i])
+		switch key {
+		case "ts", "level", "msg", "v", "logger":
+			key = "_" + key
+		}
+		var value interface{}
+		if i+1 < len(keysAndValues) {
+			value = keysAndValues[i+1]
+		}
+		switch v := value.(type) {
+		case error:
+			value = v.Error()
+		case fmt.Stringer:
+			value = v.String()
+		}
+		record[key] = value
+	}
+}
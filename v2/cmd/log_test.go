@@ -0,0 +1,82 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/klog/v2"
+)
+
+func TestJsonLogger(t *testing.T) {
+	var out bytes.Buffer
+	klog.SetLogger(newJsonLogger(&out))
+	defer klog.SetLogger(nil)
+
+	klog.InfoS("Done: found dependencies", "count", 3, "msg", "shadowed")
+	klog.Warningf("%s has an empty version", "example.com/m")
+	klog.ErrorS(errors.New("not found"), "Failed to download", "url", "https://example.com/LICENSE", "backoff", time.Second)
+	klog.Error("unstructured error")
+	klog.V(100).InfoS("Filtered by -v")
+
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n") {
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &record), line)
+		_, err := time.Parse(time.RFC3339Nano, record["ts"].(string))
+		assert.NoError(t, err)
+		delete(record, "ts")
+		records = append(records, record)
+	}
+	assert.Equal(t, []map[string]interface{}{
+		{"level": "info", "msg": "Done: found dependencies", "count": 3.0, "_msg": "shadowed"},
+		// klog passes warnings to its logger as info logs
+		{"level": "info", "msg": "example.com/m has an empty version"},
+		{"level": "error", "msg": "Failed to download", "err": "not found", "url": "https://example.com/LICENSE", "backoff": "1s"},
+		{"level": "error", "msg": "unstructured error"},
+	}, records)
+}
+
+func TestJsonLogger_Derived(t *testing.T) {
+	var out bytes.Buffer
+	logger := newJsonLogger(&out).WithName("scan").WithValues("module", "example.com/m").V(2).WithName("download")
+
+	logger.Info("Retrying download", "attempt", 1)
+
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &record))
+	delete(record, "ts")
+	assert.Equal(t, map[string]interface{}{
+		"level":   "info",
+		"msg":     "Retrying download",
+		"v":       2.0,
+		"logger":  "scan.download",
+		"module":  "example.com/m",
+		"attempt": 1.0,
+	}, record)
+}
+
+func TestSetupLogging_InvalidFormat(t *testing.T) {
+	defer func(format string) { logFormat = format }(logFormat)
+	logFormat = "xml"
+	assert.EqualError(t, setupLogging(), `invalid --log_format "xml": must be one of text, json`)
+}
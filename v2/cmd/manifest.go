@@ -0,0 +1,109 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+const defaultManifestSubPath = "manifest.json"
+
+// saveManifest records what the save command saved, so that the next run can
+// find out what changed.
+type saveManifest struct {
+	Modules []manifestEntry `json:"modules"`
+}
+
+type manifestEntry struct {
+	Module string `json:"module"`
+	// version of the module in `go list -m all`, empty when it's unknown
+	Version string `json:"version,omitempty"`
+	License string `json:"license"`
+	Url     string `json:"url"`
+	// compliance requirement of the license, DistributeSource or
+	// DistributeNotice
+	Action string `json:"action,omitempty"`
+	// path relative to the save path where source code of the module is
+	// saved, or where its license is saved when source code is not saved
+	Path string `json:"path,omitempty"`
+	// sha256 of the downloaded license text
+	Sha256 string `json:"sha256"`
+	// where source code of the module is redistributed instead of the save
+	// path, when configured as satisfied externally
+	ExternalSource string `json:"externalSource,omitempty"`
+	// whether the license is commercial, and its special obligations
+	Commercial  bool   `json:"commercial,omitempty"`
+	Obligations string `json:"obligations,omitempty"`
+}
+
+func contentSha256(content string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(content)))
+}
+
+// loadManifest loads the manifest saved in savePath by a previous run.
+// Returns nil without an error when there isn't one.
+func loadManifest(savePath string) (*saveManifest, error) {
+	path := filepath.Join(savePath, defaultManifestSubPath)
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read manifest %q: %w", path, err)
+	}
+	var manifest saveManifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf("Failed to parse manifest %q: %w", path, err)
+	}
+	return &manifest, nil
+}
+
+func writeManifest(savePath string, manifest *saveManifest) error {
+	path := filepath.Join(savePath, defaultManifestSubPath)
+	var content bytes.Buffer
+	if err := writeJSON(&content, manifest); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, content.Bytes(), permFileCurrentUser); err != nil {
+		return fmt.Errorf("Failed to write manifest %q: %w", path, err)
+	}
+	return nil
+}
+
+// changedLicenses returns entries of current whose license text changed since
+// previous. Modules that are new in current are not considered changed.
+func changedLicenses(previous, current *saveManifest) []manifestEntry {
+	changed := make([]manifestEntry, 0)
+	if previous == nil || current == nil {
+		return changed
+	}
+	previousHashes := make(map[string]string)
+	for _, entry := range previous.Modules {
+		previousHashes[entry.Module] = entry.Sha256
+	}
+	for _, entry := range current.Modules {
+		hash, ok := previousHashes[entry.Module]
+		if ok && hash != entry.Sha256 {
+			changed = append(changed, entry)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,65 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangedLicenses(t *testing.T) {
+	previous := &saveManifest{Modules: []manifestEntry{
+		{Module: "example.com/a", Sha256: "aaa"},
+		{Module: "example.com/b", Sha256: "bbb"},
+		{Module: "example.com/removed", Sha256: "ccc"},
+	}}
+	tests := []struct {
+		name     string
+		previous *saveManifest
+		current  *saveManifest
+		want     []manifestEntry
+	}{
+		{
+			name:     "no previous manifest",
+			previous: nil,
+			current:  &saveManifest{Modules: []manifestEntry{{Module: "example.com/a", Sha256: "aaa"}}},
+			want:     []manifestEntry{},
+		},
+		{
+			name:     "unchanged",
+			previous: previous,
+			current:  &saveManifest{Modules: []manifestEntry{{Module: "example.com/a", Sha256: "aaa"}, {Module: "example.com/b", Sha256: "bbb"}}},
+			want:     []manifestEntry{},
+		},
+		{
+			name:     "changed license text",
+			previous: previous,
+			current:  &saveManifest{Modules: []manifestEntry{{Module: "example.com/a", Sha256: "aaa"}, {Module: "example.com/b", Sha256: "changed"}}},
+			want:     []manifestEntry{{Module: "example.com/b", Sha256: "changed"}},
+		},
+		{
+			name:     "new modules are not changed",
+			previous: previous,
+			current:  &saveManifest{Modules: []manifestEntry{{Module: "example.com/new", Sha256: "ddd"}}},
+			want:     []manifestEntry{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, changedLicenses(tt.previous, tt.current))
+		})
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+)
+
+// flag variables
+var pruneCmdSavePath string // the save path to prune
+var pruneDryRun bool        // only report what would be pruned
+
+// pruneCmd represents the prune command
+var pruneCmd = &cobra.Command{
+	Use:   "prune {<package>, --binary <binary_path>} --save_path <NOTICES>",
+	Short: "Remove notices and source code of modules that are no longer dependencies",
+	Long: `"go-licenses prune" lists current dependencies of a go package or a built go binary,
+and removes license notices and source code folders saved by "go-licenses save" for
+modules that are no longer dependencies.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runE(func(cmd *cobra.Command, args []string) error {
+		binaryOrImportPath := args[0]
+		return pruneImp(binaryOrImportPath, pruneCmdSavePath, pruneDryRun)
+	}),
+}
+
+func init() {
+	rootCmd.AddCommand(pruneCmd)
+	addScanFlags(pruneCmd)
+	pruneCmd.Flags().StringVar(&pruneCmdSavePath, "save_path", "NOTICES", "Directory previously written by the save command")
+	if err := pruneCmd.MarkFlagFilename("save_path"); err != nil {
+		klog.Fatal(err)
+	}
+	pruneCmd.Flags().BoolVar(&pruneDryRun, "dry-run", false, "Only report what would be pruned, without removing anything.")
+}
+
+// licenseHeaderRegexp matches the header line of each module in licenses.txt.
+var licenseHeaderRegexp = regexp.MustCompile(`^============= (.+) =============$`)
+
+func pruneImp(binaryOrImportPath string, savePath string, dryRun bool) error {
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	mods, err := listModules([]string{binaryOrImportPath}, config)
+	if err != nil {
+		return err
+	}
+	modules := make(map[string]bool)
+	for _, mod := range mods {
+		modules[mod.Path] = true
+	}
+	// isCurrent reports whether a saved module, which may be a sub module, is
+	// still a dependency.
+	isCurrent := func(module string) bool {
+		for dep := range modules {
+			if module == dep || strings.HasPrefix(module, dep+"/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	prunedSrc, err := pruneSrc(filepath.Join(savePath, defaultSrcPath), modules, dryRun)
+	if err != nil {
+		return err
+	}
+	for _, path := range prunedSrc {
+		fmt.Printf("pruned source: %s\n", path)
+	}
+	prunedNotices, err := pruneNotices(filepath.Join(savePath, defaultLicenseSubPath), isCurrent, dryRun)
+	if err != nil {
+		return err
+	}
+	for _, module := range prunedNotices {
+		fmt.Printf("pruned notice: %s\n", module)
+	}
+	manifest, err := loadManifest(savePath)
+	if err != nil {
+		return err
+	}
+	if manifest != nil && !dryRun {
+		entries := make([]manifestEntry, 0, len(manifest.Modules))
+		for _, entry := range manifest.Modules {
+			if isCurrent(entry.Module) {
+				entries = append(entries, entry)
+			}
+		}
+		manifest.Modules = entries
+		if err := writeManifest(savePath, manifest); err != nil {
+			return err
+		}
+	}
+	klog.InfoS("Done: prune", "sourceCount", len(prunedSrc), "noticeCount", len(prunedNotices), "dryRun", dryRun)
+	return nil
+}
+
+// pruneNotices removes notices of modules that are not current from the
+// licenses file. Returns pruned modules, the file is not modified when dryRun
+// is true.
+func pruneNotices(licensePath string, isCurrent func(module string) bool, dryRun bool) ([]string, error) {
+	pruned := make([]string, 0)
+	content, err := ioutil.ReadFile(licensePath)
+	if os.IsNotExist(err) {
+		return pruned, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read %s", licensePath)
+	}
+	var kept strings.Builder
+	keep := true
+	for _, line := range strings.SplitAfter(string(content), "\n") {
+		if matches := licenseHeaderRegexp.FindStringSubmatch(strings.TrimSuffix(line, "\n")); matches != nil {
+			module := matches[1]
+			keep = isCurrent(module)
+			if !keep {
+				pruned = append(pruned, module)
+			}
+		}
+		if keep {
+			kept.WriteString(line)
+		}
+	}
+	if dryRun || len(pruned) == 0 {
+		return pruned, nil
+	}
+	if err := ioutil.WriteFile(licensePath, []byte(kept.String()), permFileCurrentUser); err != nil {
+		return nil, errors.Wrapf(err, "Failed to write %s", licensePath)
+	}
+	return pruned, nil
+}
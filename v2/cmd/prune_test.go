@@ -0,0 +1,126 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPruneNotices(t *testing.T) {
+	const notices = "============= example.com/a =============\n" +
+		"https://example.com/a/LICENSE\n\nMIT\n\n" +
+		"============= example.com/old =============\n" +
+		"https://example.com/old/LICENSE\n\nApache-2.0\n\n" +
+		"============= example.com/b =============\n" +
+		"https://example.com/b/LICENSE\n\nBSD-3-Clause\n"
+	current := map[string]bool{"example.com/a": true, "example.com/b": true}
+	tests := []struct {
+		name        string
+		isCurrent   func(module string) bool
+		dryRun      bool
+		wantPruned  []string
+		wantNotices string
+	}{
+		{
+			name:        "all current",
+			isCurrent:   func(string) bool { return true },
+			wantPruned:  []string{},
+			wantNotices: notices,
+		},
+		{
+			name:       "prune",
+			isCurrent:  func(module string) bool { return current[module] },
+			wantPruned: []string{"example.com/old"},
+			wantNotices: "============= example.com/a =============\n" +
+				"https://example.com/a/LICENSE\n\nMIT\n\n" +
+				"============= example.com/b =============\n" +
+				"https://example.com/b/LICENSE\n\nBSD-3-Clause\n",
+		},
+		{
+			name:        "dry run",
+			isCurrent:   func(module string) bool { return current[module] },
+			dryRun:      true,
+			wantPruned:  []string{"example.com/old"},
+			wantNotices: notices,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "prune_test")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+			licensePath := filepath.Join(dir, defaultLicenseSubPath)
+			require.NoError(t, ioutil.WriteFile(licensePath, []byte(notices), 0644))
+
+			pruned, err := pruneNotices(licensePath, tt.isCurrent, tt.dryRun)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantPruned, pruned)
+			content, err := ioutil.ReadFile(licensePath)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantNotices, string(content))
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		pruned, err := pruneNotices(filepath.Join(os.TempDir(), "prune_test_missing", defaultLicenseSubPath), nil, false)
+		require.NoError(t, err)
+		assert.Empty(t, pruned)
+	})
+}
+
+func TestPruneSrc(t *testing.T) {
+	modules := map[string]bool{"github.com/a/b": true}
+	tests := []struct {
+		name       string
+		dryRun     bool
+		wantPruned []string
+		wantKept   []string
+	}{
+		{
+			name:       "prune",
+			wantPruned: []string{"github.com/a/old", "github.com/c"},
+			wantKept:   []string{"github.com/a/b/LICENSE", "github.com/a/b/sub/main.go"},
+		},
+		{
+			name:       "dry run",
+			dryRun:     true,
+			wantPruned: []string{"github.com/a/old", "github.com/c"},
+			wantKept:   []string{"github.com/a/b/LICENSE", "github.com/a/b/sub/main.go", "github.com/a/old/main.go", "github.com/c/main.go"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srcPath, err := ioutil.TempDir("", "prune_test")
+			require.NoError(t, err)
+			defer os.RemoveAll(srcPath)
+			writeFiles(t, srcPath, "github.com/a/b/LICENSE", "github.com/a/b/sub/main.go", "github.com/a/old/main.go", "github.com/c/main.go")
+
+			pruned, err := pruneSrc(srcPath, modules, tt.dryRun)
+			require.NoError(t, err)
+			wantPruned := make([]string, 0, len(tt.wantPruned))
+			for _, p := range tt.wantPruned {
+				wantPruned = append(wantPruned, filepath.Join(srcPath, filepath.FromSlash(p)))
+			}
+			assert.Equal(t, wantPruned, pruned)
+			assert.ElementsMatch(t, tt.wantKept, listFiles(t, srcPath))
+		})
+	}
+}
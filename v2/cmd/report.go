@@ -0,0 +1,225 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	configmodule "github.com/google/go-licenses/v2/config"
+	"github.com/google/go-licenses/v2/licenses"
+	"github.com/spf13/cobra"
+)
+
+const (
+	reportFormatJson     = "json"
+	reportFormatMarkdown = "markdown"
+)
+
+// flag variables
+var reportFormat string // output format of the report
+
+// reportCmd represents the report command
+var reportCmd = &cobra.Command{
+	Use:   "report {<package>, --binary <binary_path>} --format {json,markdown}",
+	Short: "Generate a report with full details of dependency licenses",
+	Long: `"go-licenses report" scans licenses of dependencies the same way as "go-licenses csv",
+and writes a single json document with scan metadata, every module with its version,
+all licenses found in each module, and the compliance requirement of each module.
+It's intended as the machine-readable input of downstream tools.
+With --format markdown, a human-readable table of modules and their licenses grouped by
+license type is written instead, e.g. for release notes.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runE(func(cmd *cobra.Command, args []string) error {
+		return reportImp(context.Background(), args[0])
+	}),
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+	addScanFlags(reportCmd)
+	reportCmd.Flags().StringVar(&reportFormat, "format", reportFormatJson, fmt.Sprintf("output format of the report, one of %s, %s", reportFormatJson, reportFormatMarkdown))
+}
+
+type licensesReport struct {
+	Metadata reportMetadata `json:"metadata"`
+	Modules  []reportModule `json:"modules"`
+}
+
+type reportMetadata struct {
+	Tool                string    `json:"tool"`       // tool module path and version
+	Classifier          string    `json:"classifier"` // classifier module path and version
+	ConfidenceThreshold float64   `json:"confidenceThreshold"`
+	Timestamp           time.Time `json:"timestamp"`
+}
+
+type reportModule struct {
+	Module      string          `json:"module"`
+	Version     string          `json:"version"`
+	Licenses    []reportLicense `json:"licenses"`
+	Requirement ComplianceReq   `json:"requirement"`
+	// licenses that apply for compliance, e.g. MIT when a module is licensed
+	// under MIT OR GPL-2.0-only
+	EffectiveLicense string `json:"effectiveLicense"`
+}
+
+type reportLicense struct {
+	SpdxId     string  `json:"spdxId"`
+	Type       string  `json:"type"`
+	Path       string  `json:"path,omitempty"`
+	Url        string  `json:"url"`
+	Confidence float64 `json:"confidence,omitempty"`
+}
+
+func reportImp(ctx context.Context, binaryOrImportPath string) error {
+	if reportFormat != reportFormatJson && reportFormat != reportFormatMarkdown {
+		return usageError(fmt.Errorf("invalid --format %q: must be one of %s, %s", reportFormat, reportFormatJson, reportFormatMarkdown))
+	}
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	rows, scanErr := scanLicenses(ctx, []string{binaryOrImportPath}, config)
+	if rows == nil {
+		return scanErr
+	}
+	if reportFormat == reportFormatMarkdown {
+		if err := writeMarkdownReport(os.Stdout, reportModules(rows, config.Licenses)); err != nil {
+			return err
+		}
+		return scanErr
+	}
+	report := licensesReport{
+		Metadata: reportMetadata{
+			Tool:                toolVersion(),
+			Classifier:          classifierVersion(),
+			ConfidenceThreshold: licenses.DefaultConfidenceThreshold,
+			Timestamp:           time.Now().UTC(),
+		},
+		Modules: reportModules(rows, config.Licenses),
+	}
+	if err := writeJSON(os.Stdout, report); err != nil {
+		return err
+	}
+	// Modules that failed scanning are reported after writing the report.
+	return scanErr
+}
+
+// reportModules groups licenses in rows by module, in the order modules first
+// appear in rows.
+func reportModules(rows []licenseRow, cfg configmodule.LicensesConfig) []reportModule {
+	modules := make([]reportModule, 0)
+	index := make(map[string]int)
+	for _, row := range rows {
+		i, ok := index[row.Module]
+		if !ok {
+			i = len(modules)
+			index[row.Module] = i
+			modules = append(modules, reportModule{Module: row.Module, Version: row.Version})
+		}
+		for _, part := range strings.Split(row.SpdxId, "/") {
+			spdxId := strings.TrimSpace(part)
+			t := licenseType(spdxId, cfg)
+			if t == "" {
+				t = string(Unknown)
+			}
+			modules[i].Licenses = append(modules[i].Licenses, reportLicense{
+				SpdxId:     spdxId,
+				Type:       t,
+				Path:       row.Path,
+				Url:        row.Url,
+				Confidence: row.Confidence,
+			})
+		}
+	}
+	for i := range modules {
+		spdxIds := make([]string, 0, len(modules[i].Licenses))
+		for _, license := range modules[i].Licenses {
+			spdxIds = append(spdxIds, license.SpdxId)
+		}
+		requirement, err := requirementType(strings.Join(spdxIds, " / "), cfg)
+		if err != nil {
+			requirement = Unknown
+		}
+		modules[i].Requirement = requirement
+		modules[i].EffectiveLicense = effectiveLicense(strings.Join(spdxIds, " / "), cfg)
+	}
+	return modules
+}
+
+// writeMarkdownReport writes a markdown table of licenses of modules, sorted
+// by license type, then by module. A summary of the number of modules using
+// each license type is written first.
+func writeMarkdownReport(w io.Writer, modules []reportModule) error {
+	type markdownRow struct {
+		licenseType string
+		module      reportModule
+		license     reportLicense
+	}
+	rows := make([]markdownRow, 0)
+	// license type -> modules using it
+	typeModules := make(map[string]map[string]bool)
+	for _, module := range modules {
+		for _, license := range module.Licenses {
+			t := displayLicenseType(license.Type)
+			rows = append(rows, markdownRow{licenseType: t, module: module, license: license})
+			if typeModules[t] == nil {
+				typeModules[t] = make(map[string]bool)
+			}
+			typeModules[t][module.Module] = true
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].licenseType != rows[j].licenseType {
+			return rows[i].licenseType < rows[j].licenseType
+		}
+		return rows[i].module.Module < rows[j].module.Module
+	})
+	types := make([]string, 0, len(typeModules))
+	for t := range typeModules {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	summary := make([]string, 0, len(types))
+	for _, t := range types {
+		summary = append(summary, fmt.Sprintf("%v %s", len(typeModules[t]), t))
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%v modules: %s\n\n", len(modules), strings.Join(summary, ", "))
+	fmt.Fprintln(bw, "| Module | Version | License | Type | URL |")
+	fmt.Fprintln(bw, "| --- | --- | --- | --- | --- |")
+	cell := func(text string) string {
+		return strings.ReplaceAll(text, "|", "\\|")
+	}
+	for _, row := range rows {
+		fmt.Fprintf(bw, "| %s | %s | %s | %s | %s |\n", cell(row.module.Module), cell(row.module.Version), cell(row.license.SpdxId), row.licenseType, cell(row.license.Url))
+	}
+	return bw.Flush()
+}
+
+// toolVersion returns the module path and version of this binary.
+func toolVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		return info.Main.Path + "@" + info.Main.Version
+	}
+	return "github.com/google/go-licenses/v2@unknown"
+}
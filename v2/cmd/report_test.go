@@ -0,0 +1,93 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	configmodule "github.com/google/go-licenses/v2/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportModules(t *testing.T) {
+	rows := []licenseRow{
+		{Module: "example.com/a", Version: "v1.0.0", SpdxId: "MIT", Path: "LICENSE", Url: "https://example.com/a/LICENSE", Confidence: 0.98},
+		{Module: "example.com/b", Version: "v0.2.0", SpdxId: "MPL-2.0 / MIT", Path: "LICENSE", Url: "https://example.com/b/LICENSE", Confidence: 0.9},
+		{Module: "example.com/a", Version: "v1.0.0", SpdxId: "Apache-2.0", Path: "third_party/LICENSE", Url: "https://example.com/a/third_party/LICENSE", Confidence: 0.95},
+		{Module: "example.com/c", Version: "v3.0.0", SpdxId: "LicenseRef-Acme", Url: "https://example.com/c/LICENSE"},
+	}
+	want := []reportModule{
+		{
+			Module:  "example.com/a",
+			Version: "v1.0.0",
+			Licenses: []reportLicense{
+				{SpdxId: "MIT", Type: "notice", Path: "LICENSE", Url: "https://example.com/a/LICENSE", Confidence: 0.98},
+				{SpdxId: "Apache-2.0", Type: "notice", Path: "third_party/LICENSE", Url: "https://example.com/a/third_party/LICENSE", Confidence: 0.95},
+			},
+			Requirement:      RedistributeNotice,
+			EffectiveLicense: "MIT / Apache-2.0",
+		},
+		{
+			Module:  "example.com/b",
+			Version: "v0.2.0",
+			Licenses: []reportLicense{
+				{SpdxId: "MPL-2.0", Type: "reciprocal", Path: "LICENSE", Url: "https://example.com/b/LICENSE", Confidence: 0.9},
+				{SpdxId: "MIT", Type: "notice", Path: "LICENSE", Url: "https://example.com/b/LICENSE", Confidence: 0.9},
+			},
+			Requirement:      RedistributeSource,
+			EffectiveLicense: "MPL-2.0 / MIT",
+		},
+		{
+			Module:           "example.com/c",
+			Version:          "v3.0.0",
+			Licenses:         []reportLicense{{SpdxId: "LicenseRef-Acme", Type: "Unknown", Url: "https://example.com/c/LICENSE"}},
+			Requirement:      Unknown,
+			EffectiveLicense: "LicenseRef-Acme",
+		},
+	}
+	assert.Equal(t, want, reportModules(rows, configmodule.LicensesConfig{}))
+}
+
+func TestReportImp_InvalidFormat(t *testing.T) {
+	defer func(format string) { reportFormat = format }(reportFormat)
+	reportFormat = "yaml"
+	err := reportImp(context.Background(), "example.com/main")
+	assert.EqualError(t, err, `invalid --format "yaml": must be one of json, markdown`)
+}
+
+func TestWriteMarkdownReport(t *testing.T) {
+	modules := []reportModule{
+		{Module: "example.com/b", Version: "v1.0.0", Licenses: []reportLicense{{SpdxId: "MPL-2.0", Type: "reciprocal", Url: "https://example.com/b/LICENSE"}}},
+		{Module: "example.com/a|pipe", Version: "v0.1.0", Licenses: []reportLicense{
+			{SpdxId: "MIT", Type: "notice", Url: "https://example.com/a/LICENSE"},
+			{SpdxId: "Apache-2.0", Type: "notice", Url: "https://example.com/a/NOTICE"},
+		}},
+		{Module: "example.com/c", Version: "v2.0.0", Licenses: []reportLicense{{SpdxId: "BSD-3-Clause", Type: "notice", Url: "https://example.com/c/LICENSE"}}},
+	}
+	var buf bytes.Buffer
+	require.NoError(t, writeMarkdownReport(&buf, modules))
+	assert.Equal(t, `3 modules: 2 Notice, 1 Reciprocal
+
+| Module | Version | License | Type | URL |
+| --- | --- | --- | --- | --- |
+| example.com/a\|pipe | v0.1.0 | MIT | Notice | https://example.com/a/LICENSE |
+| example.com/a\|pipe | v0.1.0 | Apache-2.0 | Notice | https://example.com/a/NOTICE |
+| example.com/c | v2.0.0 | BSD-3-Clause | Notice | https://example.com/c/LICENSE |
+| example.com/b | v1.0.0 | MPL-2.0 | Reciprocal | https://example.com/b/LICENSE |
+`, buf.String())
+}
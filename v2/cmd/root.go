@@ -16,26 +16,50 @@ package cmd
 
 import (
 	"flag"
+	"path/filepath"
 
+	configmodule "github.com/google/go-licenses/v2/config"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"k8s.io/klog/v2"
 )
 
 var cfgFile string
+var flagCompact bool
+var warnOverrides bool
+var flagDir string
+var logFormat string
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "go-licenses",
 	Short: "go-licenses -- a license workflows CLI tool",
 	Long: `go-licenses is a CLI tool for Go that automates license workflows.
-It helps find licenses of your dependencies and comply with them.`,
+It helps find licenses of your dependencies and comply with them.
+
+Exit codes:
+  0  success
+  1  license policy violation, e.g. forbidden or unknown licenses
+  2  usage error, e.g. invalid arguments, flags or config
+  3  scan error, e.g. failed to download a license text`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return setupLogging()
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
-func Execute() {
-	cobra.CheckErr(rootCmd.Execute())
+// It returns the exit code of the command, one of ExitOK, ExitViolation,
+// ExitUsage or ExitScanError.
+func Execute() int {
+	// Errors are logged here instead, so that they respect --log_format.
+	rootCmd.SilenceErrors = true
+	err := rootCmd.Execute()
+	if err != nil {
+		klog.Error(err)
+	}
+	klog.Flush()
+	return exitCode(err)
 }
 
 func init() {
@@ -51,5 +75,26 @@ func init() {
 	// Here you will define your flags and configuration settings.
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
-	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.go-licenses.yaml)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is go-licenses.yaml in current dir, or in --dir)")
+	rootCmd.PersistentFlags().StringVar(&flagDir, "dir", "", "directory of the go module to run go commands in, instead of the current dir, e.g. when scanning a module of a multi-module repo. Relative package paths like ./... are relative to it, other paths are not")
+	rootCmd.PersistentFlags().BoolVar(&warnOverrides, "warn_overrides", false, "log every license type override in config that relaxes a license to a less strict type, for legal review")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log_format", logFormatText, "format of logs written to stderr, text or json, json writes a line of fields for each log, e.g. {\"level\":\"info\",\"msg\":\"...\",\"ts\":\"...\"}, level is info or error, klog logs warnings as info, with key value pairs of structured logs as fields")
+	rootCmd.PersistentFlags().BoolVar(&flagCompact, "compact", false, "write json output as a single line instead of indented")
+}
+
+// loadConfig loads the config file of the --config flag, or go-licenses.yaml
+// in --dir when it's not specified.
+func loadConfig() (*configmodule.GoModLicensesConfig, error) {
+	path := cfgFile
+	if path == "" && flagDir != "" {
+		path = filepath.Join(flagDir, configmodule.DefaultConfigPath)
+	}
+	config, err := configmodule.Load(path)
+	if err != nil {
+		return nil, usageError(err)
+	}
+	if warnOverrides {
+		warnRelaxedOverrides(config.Licenses)
+	}
+	return config, nil
 }
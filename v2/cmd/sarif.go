@@ -0,0 +1,153 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+)
+
+// A minimal subset of SARIF 2.1.0 used to report check results.
+// Spec: https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationUri string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	Id               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleId     string                `json:"ruleId"`
+	Level      string                `json:"level"`
+	Message    sarifMessage          `json:"message"`
+	Locations  []sarifLocation       `json:"locations"`
+	Properties sarifResultProperties `json:"properties"`
+}
+
+// sarifResultProperties is the property bag of a result, so that tools can
+// group results by license.
+type sarifResultProperties struct {
+	Module      string `json:"module"`
+	Version     string `json:"version"`
+	License     string `json:"license"`
+	LicenseType string `json:"licenseType"`
+	Url         string `json:"url"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	Uri string `json:"uri"`
+}
+
+// writeSarif writes violations as a SARIF report. There is one rule per
+// violated rule of the check, e.g. forbidden or unknown license types, the
+// license of each result is in its message and properties. Every violation is
+// a result located at go.mod, because that's where dependencies are declared.
+func writeSarif(w io.Writer, violations []violation) error {
+	rules := make([]sarifRule, 0)
+	ruleIndex := make(map[string]bool)
+	results := make([]sarifResult, 0, len(violations))
+	for _, v := range violations {
+		if !ruleIndex[v.Rule] {
+			ruleIndex[v.Rule] = true
+			rules = append(rules, sarifRule{
+				Id:               v.Rule,
+				ShortDescription: sarifMessage{Text: describeRule(v)},
+			})
+		}
+		results = append(results, sarifResult{
+			RuleId:  v.Rule,
+			Level:   "error",
+			Message: sarifMessage{Text: fmt.Sprintf("%s@%s uses license %s: %s: %s", v.Row.Module, v.Row.Version, v.SpdxId, v.Reason, v.Row.Url)},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{Uri: "go.mod"},
+				},
+			}},
+			Properties: sarifResultProperties{
+				Module:      v.Row.Module,
+				Version:     v.Row.Version,
+				License:     v.SpdxId,
+				LicenseType: v.LicenseType,
+				Url:         v.Row.Url,
+			},
+		})
+	}
+	log := sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "go-licenses",
+				InformationUri: "https://github.com/google/go-licenses",
+				Rules:          rules,
+			}},
+			Results: results,
+		}},
+	}
+	if err := writeJSON(w, log); err != nil {
+		return fmt.Errorf("Failed to write sarif report: %w", err)
+	}
+	return nil
+}
+
+// describeRule returns the description of the rule a violation violates.
+func describeRule(v violation) string {
+	switch v.Rule {
+	case ruleCommercial:
+		return "Commercial license that is not allowed"
+	case ruleConflict:
+		return "Licenses with conflicting compliance requirements in a module"
+	case ruleDisallowedLicense:
+		return "Disallowed license"
+	default:
+		return fmt.Sprintf("License of disallowed type %s", v.LicenseType)
+	}
+}
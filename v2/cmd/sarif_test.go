@@ -0,0 +1,75 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteSarif(t *testing.T) {
+	violations := []violation{
+		{
+			Rule:        "forbidden",
+			Row:         licenseRow{Module: "example.com/a", Version: "v1.0.0", Url: "https://example.com/a/LICENSE", SpdxId: "AGPL-3.0"},
+			SpdxId:      "AGPL-3.0",
+			LicenseType: "Forbidden",
+			Reason:      "disallowed license type Forbidden",
+		},
+		{
+			Rule:        "forbidden",
+			Row:         licenseRow{Module: "example.com/b", Version: "v0.2.0", Url: "https://example.com/b/LICENSE", SpdxId: "WTFPL"},
+			SpdxId:      "WTFPL",
+			LicenseType: "Forbidden",
+			Reason:      "disallowed license type Forbidden",
+		},
+		{
+			Rule:        "unknown",
+			Row:         licenseRow{Module: "example.com/c", Version: "v1.1.0", Url: "https://example.com/c/LICENSE", SpdxId: "LicenseRef-Acme"},
+			SpdxId:      "LicenseRef-Acme",
+			LicenseType: "Unknown",
+			Reason:      "disallowed license type Unknown",
+		},
+		{
+			Rule:        ruleDisallowedLicense,
+			Row:         licenseRow{Module: "example.com/d", Version: "v2.0.0", Url: "https://example.com/d/LICENSE", SpdxId: "MPL-2.0"},
+			SpdxId:      "MPL-2.0",
+			LicenseType: "Reciprocal",
+			Reason:      "disallowed license MPL-2.0",
+		},
+	}
+	tests := []struct {
+		name       string
+		violations []violation
+		golden     string
+	}{
+		{name: "violations", violations: violations, golden: "testdata/check.sarif"},
+		{name: "no violations", violations: []violation{}, golden: "testdata/check_empty.sarif"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			require.NoError(t, writeSarif(&out, tt.violations))
+
+			golden, err := ioutil.ReadFile(tt.golden)
+			require.NoError(t, err)
+			assert.Equal(t, string(golden), out.String())
+		})
+	}
+}
@@ -15,19 +15,22 @@
 package cmd
 
 import (
-	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/google/go-licenses/v2/config"
+	configmodule "github.com/google/go-licenses/v2/config"
 	"github.com/google/go-licenses/v2/dict"
 	"github.com/google/go-licenses/v2/ghutils"
 	"github.com/google/go-licenses/v2/gocli"
+	"github.com/google/go-licenses/v2/licenses"
 	"github.com/google/licenseclassifier"
 	"github.com/otiai10/copy"
 	"github.com/pkg/errors"
@@ -36,8 +39,17 @@ import (
 )
 
 // flag variables
-var savePath string        // where to save files required for license compliance
-var overwriteSavePath bool // if the save path already exists, shall we overwrite?
+var savePath string            // where to save files required for license compliance
+var overwriteSavePath bool     // if the save path already exists, shall we overwrite?
+var mergeSavePath bool         // if the save path already exists, shall we update it in place?
+var pruneSavePath bool         // when merging, shall we remove source folders of modules no longer required?
+var exportDecisionsPath string // where to export compliance decisions for audit, optional
+var saveArchive string         // archive format to save to instead of a directory, optional
+var strictSave bool            // fail when downloaded license texts do not match their licenses
+var offlineSave bool           // read license texts from local module dirs instead of downloading them
+var estimateSave bool          // only print the estimated size of source code to save
+var checkOnlySave bool         // only decide whether all modules comply, without saving anything
+var unknownAction string       // what to do with modules whose licenses are unknown
 
 // saveCmd represents the save command
 var saveCmd = &cobra.Command{
@@ -45,39 +57,116 @@ var saveCmd = &cobra.Command{
 	Short: "Save licenses and source code locally",
 	Long:  `Save full license text and source code locally to be compliant depending on license requirements.`,
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		csvPath := args[0]
-		config, err := config.Load("")
-		defer klog.Flush()
-		if err != nil {
-			klog.ErrorS(err, "Failed: load config")
-			os.Exit(1)
+	RunE: runE(func(cmd *cobra.Command, args []string) error {
+		return saveImp(args[0])
+	}),
+}
+
+func saveImp(csvPath string) error {
+	if checkOnlySave && estimateSave {
+		return usageError(fmt.Errorf("--check_only and --estimate cannot be used at the same time"))
+	}
+	if unknownAction != unknownActionFail && unknownAction != unknownActionWarn && unknownAction != unknownActionSkip {
+		return usageError(fmt.Errorf("invalid --unknown_action %q: must be one of %s, %s, %s", unknownAction, unknownActionFail, unknownActionWarn, unknownActionSkip))
+	}
+	if pruneSavePath && !mergeSavePath {
+		return usageError(fmt.Errorf("--prune can only be used with --merge"))
+	}
+	if overwriteSavePath && mergeSavePath {
+		return usageError(fmt.Errorf("--force and --merge cannot be used at the same time"))
+	}
+	if saveArchive != "" {
+		if mergeSavePath {
+			return usageError(fmt.Errorf("--archive and --merge cannot be used at the same time"))
 		}
-		info, err := loadInfo(csvPath)
+		if !isArchivePath(savePath + "." + saveArchive) {
+			return usageError(fmt.Errorf("invalid --archive %q: must be one of tar.gz, tgz, zip", saveArchive))
+		}
+	}
+	config, err := loadConfig()
+	if err != nil {
+		return errors.Wrap(err, "Failed to load config")
+	}
+	info, err := loadInfo(csvPath)
+	if err != nil {
+		return usageError(err)
+	}
+	// Decide how to comply with licenses before any side effects, so
+	// that nothing is changed when some modules cannot comply.
+	plan, err := planCompliance(info, *config)
+	if err != nil {
+		return err
+	}
+	if checkOnlySave {
+		klog.InfoS("Done: check compliance, nothing is saved", "moduleCount", len(plan))
+		return nil
+	}
+	if estimateSave {
+		return errors.Wrap(printEstimate(plan), "Failed to estimate size")
+	}
+	if exportDecisionsPath != "" {
+		configPath := cfgFile
+		if configPath == "" {
+			configPath = configmodule.DefaultConfigPath
+		}
+		err = exportDecisions(exportDecisionsPath, info, *config, csvPath, configPath)
 		if err != nil {
-			klog.ErrorS(err, "Failed: load license info csv")
-			os.Exit(1)
+			return errors.Wrap(err, "Failed to export decisions")
 		}
-		if overwriteSavePath {
-			if err := os.RemoveAll(savePath); err != nil {
-				klog.Fatal(err)
-			}
+	}
+	if err := useDefaultLicenseDB(config); err != nil {
+		return err
+	}
+	verifier, err := licenses.NewContentClassifier(config.Module.LicenseDB.Path, 0)
+	if err != nil {
+		return errors.Wrap(err, "Failed to load license DB")
+	}
+	if saveArchive != "" {
+		archivePath := savePath + "." + saveArchive
+		if _, err := os.Stat(archivePath); err == nil && !overwriteSavePath {
+			return usageError(fmt.Errorf("%s already exists", archivePath))
 		}
+		return complyWithLicenses(plan, *config, savePath, saveOptions{
+			archive:     archivePath,
+			verifier:    verifier,
+			strict:      strictSave,
+			offline:     offlineSave,
+			download:    newHostRestrictedDownloader(config.Module.AllowedLicenseHosts),
+			concurrency: *flagDownloadConcurrency,
+		})
+	}
+	// Load the previous manifest before the save path may be deleted,
+	// so that we can detect license text changes.
+	previousManifest, err := loadManifest(savePath)
+	if err != nil {
+		return errors.Wrap(err, "Failed to load previous manifest")
+	}
+	if overwriteSavePath {
+		if err := os.RemoveAll(savePath); err != nil {
+			return err
+		}
+	}
 
+	if !mergeSavePath {
 		// Check that the save path doesn't exist, otherwise it'd end up with a mix of
 		// existing files and the output of this command.
 		if d, err := os.Open(savePath); err == nil {
 			d.Close()
-			klog.Fatal(fmt.Errorf("%s already exists", savePath))
+			return usageError(fmt.Errorf("%s already exists", savePath))
 		} else if !os.IsNotExist(err) {
-			klog.Fatal(err)
-		}
-		err = complyWithLicenses(info, *config, savePath)
-		if err != nil {
-			klog.ErrorS(err, "Failed: comply with licenses")
-			os.Exit(1)
+			return err
 		}
-	},
+	}
+	return complyWithLicenses(plan, *config, savePath, saveOptions{
+		merge:            mergeSavePath,
+		prune:            pruneSavePath,
+		previousManifest: previousManifest,
+		verifier:         verifier,
+		strict:           strictSave,
+		offline:          offlineSave,
+		download:         newHostRestrictedDownloader(config.Module.AllowedLicenseHosts),
+		concurrency:      *flagDownloadConcurrency,
+	})
 }
 
 func init() {
@@ -89,10 +178,26 @@ func init() {
 		klog.Fatal(err)
 	}
 	saveCmd.Flags().BoolVar(&overwriteSavePath, "force", false, "Delete the destination directory if it already exists.")
+	saveCmd.Flags().BoolVar(&mergeSavePath, "merge", false, "Update the destination directory in place if it already exists, instead of failing.")
+	saveCmd.Flags().BoolVar(&pruneSavePath, "prune", false, "When used with --merge, remove source folders of modules that no longer need to be redistributed.")
+	saveCmd.Flags().StringVar(&saveArchive, "archive", "", "Save to an archive <save_path>.<archive> instead of a directory, one of tar.gz, tgz or zip. --force overwrites an existing archive.")
+	saveCmd.Flags().BoolVar(&estimateSave, "estimate", false, "Only print the size of source code each module requires to redistribute and the total, without downloading or saving anything.")
+	saveCmd.Flags().BoolVar(&checkOnlySave, "check_only", false, "Only decide how each module complies with its licenses, and fail when any module cannot comply, e.g. because of a rejected license or missing source code, without downloading or saving anything.")
+	saveCmd.Flags().StringVar(&unknownAction, "unknown_action", unknownActionFail, "What to do with modules whose license types are unknown: fail, warn to report them and save other modules, or skip to silently save other modules. Modules with forbidden or disallowed commercial licenses always fail.")
+	saveCmd.Flags().BoolVar(&offlineSave, "offline", false, "Read license texts from local module dirs, e.g. in the module cache, instead of downloading them. They're only downloaded when not found locally.")
+	saveCmd.Flags().BoolVar(&strictSave, "strict", false, "Fail instead of warning when licenses classified in a downloaded license text differ from the licenses in the csv, e.g. because the url points to a wrong or outdated file.")
+	saveCmd.Flags().StringVar(&exportDecisionsPath, "export_decisions", "", "Export a timestamped json record of the compliance decision for each module to this file, for audit.")
 
+	addDownloadFlags(saveCmd)
 	rootCmd.AddCommand(saveCmd)
 }
 
+const (
+	unknownActionFail = "fail"
+	unknownActionWarn = "warn"
+	unknownActionSkip = "skip"
+)
+
 const defaultLicenseSubPath = "licenses.txt"
 const defaultSrcPath = "src"
 
@@ -109,129 +214,779 @@ const (
 	Unknown ComplianceReq = "Unknown"
 	// We need to redistribute the entire source directory to be compliant,
 	// example licenses: GPL, MPL, etc.
-	RedistributeSource ComplianceReq = "DistributeSource"
+	RedistributeSource ComplianceReq = config.RequirementDistributeSource
 	// We need to redistribute full text license and a copyright notice to be
 	// compliant: most other licenses.
-	RedistributeNotice ComplianceReq = "DistributeNotice"
+	RedistributeNotice ComplianceReq = config.RequirementDistributeNotice
 )
 
 // Determines compliance requirement type of a license, returns ComplianceReq.
 // license can be a list of licenses like "Apache-2.0 / MIT", this method returns
-// strictest ComplianceReq type. The license names should be SPDX ID format.
+// strictest ComplianceReq type. Each license can also be an SPDX license
+// expression like "Apache-2.0 OR MIT", the least restrictive branch of OR and
+// the strictest operand of AND are used. Unknown is returned if any license is
+// unknown. The license names should be SPDX ID format.
 func requirementType(license string, cfg config.LicensesConfig) (ComplianceReq, error) {
 	// By default, we distribute notice for any licenses.
 	requirement := RedistributeNotice
 	for _, part := range strings.Split(license, "/") {
-		spdxId := strings.TrimSpace(part)
-		if spdxId == "" {
+		if strings.TrimSpace(part) == "" {
 			return Unknown, fmt.Errorf("Empty SPDX ID in %q", license)
 		}
+		expression, err := licenses.ParseExpression(part)
+		if err != nil {
+			return Unknown, err
+		}
+		for _, spdxId := range expression.SpdxIds() {
+			if _, known := spdxIdRequirement(spdxId, cfg); !known {
+				// Any unknown license type is not allowed, so we return
+				// unknown. Types can be configured in
+				// licenses.types.requirements.
+				return Unknown, nil
+			}
+		}
+		requirement = stricterRequirement(requirement, expressionRequirement(expression, cfg))
+	}
+	return requirement, nil
+}
 
-		licenseType := licenseclassifier.LicenseType(spdxId)
-		for _, override := range cfg.Types.Overrides {
-			if override.SpdxId == spdxId {
-				licenseType = override.Type
+// hasUnknownLicenseType reports whether a license rejected by requirementType
+// is rejected only because types of some licenses in it are unknown, rather
+// than because of forbidden types or commercial licenses that are not
+// allowed.
+func hasUnknownLicenseType(license string, cfg config.LicensesConfig) bool {
+	unknown := false
+	for _, part := range strings.Split(license, "/") {
+		expression, err := licenses.ParseExpression(part)
+		if err != nil {
+			return false
+		}
+		for _, spdxId := range expression.SpdxIds() {
+			requirement, known := spdxIdRequirement(spdxId, cfg)
+			if !known && licenseType(spdxId, cfg) == "" {
+				unknown = true
+			} else if requirement == Unknown {
+				return false
 			}
 		}
-		switch licenseType {
-		case "restricted", "reciprocal":
-			requirement = RedistributeSource
-		case "notice", "permissive", "unencumbered":
-			// No special handling.
-		default:
-			// Any unknown license type is not allowed, so we return unknown.
-			// TODO: allow user configurable license type dictionary.
-			return Unknown, nil
+	}
+	return unknown
+}
+
+// expressionRequirement returns compliance requirement type of an SPDX
+// license expression, all licenses in it should be known.
+func expressionRequirement(expression *licenses.Expression, cfg config.LicensesConfig) ComplianceReq {
+	switch expression.Operator {
+	case licenses.OperatorAnd:
+		requirement := RedistributeNotice
+		for _, operand := range expression.Operands {
+			requirement = stricterRequirement(requirement, expressionRequirement(operand, cfg))
 		}
+		return requirement
+	case licenses.OperatorOr:
+		// We can choose any of the licenses, so choose the least restrictive one.
+		requirement := Unknown
+		for _, operand := range expression.Operands {
+			operandRequirement := expressionRequirement(operand, cfg)
+			if stricterRequirement(requirement, operandRequirement) == requirement {
+				requirement = operandRequirement
+			}
+		}
+		return requirement
+	default:
+		requirement, _ := spdxIdRequirement(expression.SpdxId, cfg)
+		return requirement
 	}
-	return requirement, nil
 }
 
-func complyWithLicenses(info []*dict.LicenseRecord, config config.GoModLicensesConfig, savePath string) error {
-	noticesPath := savePath
-	licensePath := filepath.Join(noticesPath, defaultLicenseSubPath)
-	srcPath := filepath.Join(noticesPath, defaultSrcPath)
-	moduleDict, err := gocli.ListModules()
-	if err != nil {
-		return errors.Wrap(err, "Failed to list modules")
+// spdxIdRequirement returns compliance requirement type of a single license,
+// and whether the license type is known. Requirements of license types in
+// config take precedence over built-in types.
+func spdxIdRequirement(spdxId string, cfg config.LicensesConfig) (ComplianceReq, bool) {
+	t := licenseType(spdxId, cfg)
+	if requirement, ok := cfg.Types.Requirements[t]; ok && t != "" {
+		return ComplianceReq(requirement), true
 	}
+	switch t {
+	case "restricted", "reciprocal":
+		return RedistributeSource, true
+	case "notice", "permissive", "unencumbered":
+		return RedistributeNotice, true
+	case config.LicenseTypeCommercial:
+		if !cfg.Commercial.Allowed {
+			// The license is known, but we cannot comply with it.
+			return Unknown, true
+		}
+		// Allowed commercial licenses are tracked like notices, their
+		// special obligations are recorded in the manifest.
+		return RedistributeNotice, true
+	default:
+		return Unknown, false
+	}
+}
 
-	err = os.RemoveAll(srcPath)
+// spdxExpression parses a license in SPDX ID format, which can also be an
+// SPDX license expression. A license that is not a valid expression is
+// returned as a single license, so that it's still looked up by its ID.
+func spdxExpression(license string) *licenses.Expression {
+	expression, err := licenses.ParseExpression(license)
 	if err != nil {
-		return errors.Wrapf(err, "Failed to remove all in %s", srcPath)
+		return &licenses.Expression{SpdxId: strings.TrimSpace(license)}
 	}
-	err = os.MkdirAll(path.Dir(licensePath), permDirCurrentUser)
-	if err != nil {
-		return errors.Wrapf(err, "Failed to mkdir %s", path.Dir(licensePath))
+	return expression
+}
+
+// effectiveLicense returns licenses joined by "/" that apply for compliance,
+// licenses in SPDX expressions combined by OR are resolved to the least
+// restrictive choice, e.g. "MIT" for "MIT OR GPL-2.0-only". Licenses are
+// returned unchanged when they are not valid SPDX expressions.
+func effectiveLicense(license string, cfg config.LicensesConfig) string {
+	parts := make([]string, 0)
+	for _, part := range strings.Split(license, "/") {
+		expression, err := licenses.ParseExpression(part)
+		if err != nil {
+			return license
+		}
+		parts = append(parts, effectiveExpression(expression, cfg).String())
 	}
-	f, err := os.Create(licensePath)
-	if err != nil {
-		return errors.Wrapf(err, "Failed to create %s", licensePath)
+	return strings.Join(parts, " / ")
+}
+
+// effectiveExpression resolves operands combined by OR in expression to the
+// least restrictive one, the first one wins on ties.
+func effectiveExpression(expression *licenses.Expression, cfg config.LicensesConfig) *licenses.Expression {
+	switch expression.Operator {
+	case licenses.OperatorAnd:
+		effective := &licenses.Expression{Operator: licenses.OperatorAnd}
+		for _, operand := range expression.Operands {
+			effective.Operands = append(effective.Operands, effectiveExpression(operand, cfg))
+		}
+		return effective
+	case licenses.OperatorOr:
+		var choice *licenses.Expression
+		requirement := Unknown
+		for _, operand := range expression.Operands {
+			operandRequirement := expressionRequirement(operand, cfg)
+			if choice == nil || operandRequirement != requirement && stricterRequirement(requirement, operandRequirement) == requirement {
+				choice = operand
+				requirement = operandRequirement
+			}
+		}
+		return effectiveExpression(choice, cfg)
+	default:
+		return expression
 	}
-	defer f.Close()
-	w := bufio.NewWriter(f)
+}
 
+// stricterRequirement returns the stricter one of two compliance requirement
+// types, Unknown is the strictest, because we cannot comply with it.
+func stricterRequirement(a ComplianceReq, b ComplianceReq) ComplianceReq {
+	strictness := map[ComplianceReq]int{
+		RedistributeNotice: 0,
+		RedistributeSource: 1,
+		Unknown:            2,
+	}
+	if strictness[b] > strictness[a] {
+		return b
+	}
+	return a
+}
+
+// licenseType returns type of a license in SPDX ID format, license type
+// overrides in config take precedence. Returns "" for unknown licenses.
+func licenseType(spdxId string, cfg config.LicensesConfig) string {
+	licenseType := licenseclassifier.LicenseType(spdxId)
+	if licenseType == "" {
+		// The classifier only knows the deprecated forms of some SPDX IDs,
+		// e.g. GPL-2.0 instead of GPL-2.0-only.
+		for deprecated, canonical := range licenses.DeprecatedSpdxIds {
+			if canonical == spdxId && licenseclassifier.LicenseType(deprecated) != "" {
+				licenseType = licenseclassifier.LicenseType(deprecated)
+			}
+		}
+	}
+	for _, override := range cfg.Types.Overrides {
+		if override.SpdxId == spdxId || licenses.NormalizeSpdxId(override.SpdxId) == licenses.NormalizeSpdxId(spdxId) {
+			licenseType = override.Type
+		}
+	}
+	return licenseType
+}
+
+// warnRelaxedOverrides logs license type overrides that relax a license to a
+// less strict type than the classifier's, so that they can be audited.
+func warnRelaxedOverrides(cfg config.LicensesConfig) {
+	for _, override := range cfg.Types.Overrides {
+		original := licenseclassifier.LicenseType(override.SpdxId)
+		before := typeStrictness(original, cfg)
+		after := typeStrictness(override.Type, cfg)
+		if before < 0 || after < 0 || after >= before {
+			continue
+		}
+		klog.Warningf("License type override relaxes %s from %s to %s", override.SpdxId, displayLicenseType(original), displayLicenseType(override.Type))
+	}
+}
+
+// typeStrictness ranks a license type from the least strict, or returns -1
+// when the type cannot be ranked, e.g. unknown or commercial types.
+func typeStrictness(t string, cfg config.LicensesConfig) int {
+	switch t {
+	case "unencumbered":
+		return 0
+	case "permissive":
+		return 1
+	case "notice":
+		return 2
+	case "reciprocal":
+		return 3
+	case "restricted":
+		return 4
+	case "FORBIDDEN":
+		return 5
+	}
+	// Configured types rank the same as built-in types with the same
+	// requirement.
+	switch cfg.Types.Requirements[t] {
+	case config.RequirementDistributeNotice:
+		return typeStrictness("notice", cfg)
+	case config.RequirementDistributeSource:
+		return typeStrictness("reciprocal", cfg)
+	default:
+		return -1
+	}
+}
+
+// externalSource returns the configured url where source code of a module is
+// redistributed externally, or "" when not configured. module may be a sub
+// module of a configured module.
+func externalSource(module string, cfg config.GoModLicensesConfig) string {
+	for _, override := range cfg.Module.Overrides {
+		if override.Name == module || strings.HasPrefix(module, override.Name+"/") {
+			if override.ExternalSource != "" {
+				return override.ExternalSource
+			}
+		}
+	}
+	return ""
+}
+
+// isCommercial reports whether any license in spdxIds, joined by "/", is
+// commercial. Each part can be an SPDX license expression.
+func isCommercial(spdxIds string, cfg config.LicensesConfig) bool {
+	for _, part := range strings.Split(spdxIds, "/") {
+		ids := []string{strings.TrimSpace(part)}
+		if expression, err := licenses.ParseExpression(part); err == nil {
+			ids = expression.SpdxIds()
+		}
+		for _, spdxId := range ids {
+			if licenseType(spdxId, cfg) == config.LicenseTypeCommercial {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// obligations returns the configured special obligations of a module's
+// license, or "" when not configured.
+func obligations(module string, cfg config.GoModLicensesConfig) string {
+	for _, override := range cfg.Module.Overrides {
+		if override.Name == module || strings.HasPrefix(module, override.Name+"/") {
+			if override.Obligations != "" {
+				return override.Obligations
+			}
+		}
+	}
+	return ""
+}
+
+// saveOptions controls how complyWithLicenses writes to savePath.
+type saveOptions struct {
+	// Update an existing savePath in place. Source folders of modules in
+	// info are replaced, other existing content is kept.
+	merge bool
+	// When merging, remove source folders of modules not in info.
+	prune bool
+	// Manifest of the previous run, used to report license text changes.
+	// It's nil when there wasn't a previous run.
+	previousManifest *saveManifest
+	// Write an archive at this path instead of savePath, if not empty.
+	archive string
+	// Classifies downloaded license texts to verify they match licenses in
+	// info, verification is skipped when it's nil.
+	verifier *licenses.ContentClassifier
+	// Fail instead of warning when downloaded license texts do not match.
+	strict bool
+	// Read license texts from local module dirs when they exist, instead of
+	// downloading them.
+	offline bool
+	// Downloads license texts, a downloader without retries is used when
+	// it's nil.
+	download *ghutils.Downloader
+	// Number of license texts downloaded concurrently, defaults to
+	// DefaultDownloadConcurrency when it's not positive.
+	concurrency int
+}
+
+// complianceItem is how a module complies with its licenses.
+type complianceItem struct {
+	record  *dict.LicenseRecord
+	reqType ComplianceReq
+	// url where source code of the module is redistributed externally, if any
+	externalSource string
+	// module source dir to copy, when source code needs to be redistributed
+	// and it's not redistributed externally
+	srcDir string
+	// module version in `go list -m all`, empty when it's unknown
+	version string
+}
+
+// planCompliance decides how each module complies with its licenses, sorted
+// by module. It has no side effects, so that nothing is downloaded or
+// written when any module has a rejected license or cannot comply.
+func planCompliance(info []*dict.LicenseRecord, config config.GoModLicensesConfig) ([]complianceItem, error) {
+	// Sort licenses by module, so that licenses.txt is stable regardless of
+	// the order in the csv.
+	info = append([]*dict.LicenseRecord{}, info...)
+	sort.SliceStable(info, func(i, j int) bool { return info[i].Module < info[j].Module })
+	plan := make([]complianceItem, 0, len(info))
 	modulesWithBadLicenses := make([]*dict.LicenseRecord, 0)
 	for _, record := range info {
 		reqType, err := requirementType(record.Type, config.Licenses)
 		if err != nil {
-			return fmt.Errorf("%s: license=%q: %w", record.Module, record.Type, err)
+			return nil, fmt.Errorf("%s: license=%q: %w", record.Module, record.Type, err)
 		}
 		switch reqType {
-		case RedistributeSource:
-			// Copy the entire source directory for the library.
-			moduleRecord, exists := moduleDict[record.Module]
-			if !exists {
-				// TODO: try if any parent module exists in moduleDict.
-				return errors.Errorf("%s: Cannot find module in `go list -m all`", record.Module)
-			}
-			if moduleRecord.Dir == "" {
-				return errors.Errorf(
-					"%s: Module Dir is empty in `go list -m -json %s`. Please run `go mod download` before running `go-licenses save`.",
-					record.Module, record.Module,
-				)
-			}
-			if err := copySrc(moduleRecord.Dir, filepath.Join(srcPath, record.Module)); err != nil {
-				return errors.Wrapf(err, "%s: Failed to copy source dir from %s to %s", record.Module, moduleRecord.Dir, srcPath)
-			}
-		case RedistributeNotice:
-			// No special handling.
+		case RedistributeSource, RedistributeNotice:
+			plan = append(plan, complianceItem{
+				record:         record,
+				reqType:        reqType,
+				externalSource: externalSource(record.Module, config),
+			})
 		default:
+			if unknownAction != unknownActionFail && hasUnknownLicenseType(record.Type, config.Licenses) {
+				if unknownAction == unknownActionWarn {
+					klog.Warningf("%s: rejected unknown license type of %q, the module is not saved", record.Module, record.Type)
+				} else {
+					klog.V(2).InfoS("Skipped module with unknown license type", "module", record.Module, "license", record.Type)
+				}
+				continue
+			}
 			modulesWithBadLicenses = append(modulesWithBadLicenses, record)
 		}
-		if len(modulesWithBadLicenses) > 0 {
-			// if we find bad licenses, we only need to report all moodules with
-			// bad licenses.
+	}
+	if len(modulesWithBadLicenses) > 0 {
+		// if we find bad licenses, we only need to report all moodules with
+		// bad licenses.
+		for _, module := range modulesWithBadLicenses {
+			klog.ErrorS(fmt.Errorf("unknown license type"), "Rejected license", "module", module.Module, "license", module.Type)
+		}
+		return nil, violationError(fmt.Errorf("%v modules has rejected licenses", len(modulesWithBadLicenses)))
+	}
+	// Validate license urls before anything is downloaded, so that a
+	// compromised csv cannot make us reach out to unexpected domains.
+	disallowedUrls := 0
+	for _, item := range plan {
+		if err := checkLicenseHost(item.record.DownaloadUrl, config.Module.AllowedLicenseHosts); err != nil {
+			klog.ErrorS(err, "Rejected license url", "module", item.record.Module)
+			disallowedUrls = disallowedUrls + 1
+		}
+	}
+	if disallowedUrls > 0 {
+		return nil, violationError(fmt.Errorf("%v modules have license urls of hosts not in module.allowedLicenseHosts", disallowedUrls))
+	}
+	var moduleDict map[string]gocli.Module
+	for i := range plan {
+		item := &plan[i]
+		if item.reqType != RedistributeSource {
 			continue
 		}
-		licenseContent, err := ghutils.SmartDownload(record.DownaloadUrl)
+		if item.externalSource != "" {
+			klog.InfoS("Source redistribution satisfied externally, skip copying source", "module", item.record.Module, "source", item.externalSource)
+			continue
+		}
+		if moduleDict == nil {
+			var err error
+			moduleDict, err = gocli.ListModulesInDir(flagDir)
+			if err != nil {
+				return nil, errors.Wrap(err, "Failed to list modules")
+			}
+		}
+		moduleRecord, exists := findModule(moduleDict, item.record.Module)
+		if !exists {
+			return nil, errors.Errorf("%s: Cannot find module or any of its parent modules in `go list -m all`", item.record.Module)
+		}
+		if moduleRecord.Path != item.record.Module {
+			klog.InfoS("Module not found, saving source code of its parent module", "module", item.record.Module, "parent", moduleRecord.Path)
+		}
+		if moduleRecord.Dir == "" {
+			return nil, errors.Errorf(
+				"%s: Module Dir is empty in `go list -m -json %s`. Please run `go mod download` before running `go-licenses save`.",
+				item.record.Module, moduleRecord.Path,
+			)
+		}
+		item.srcDir = moduleRecord.Dir
+	}
+	// Versions are only recorded in the manifest, so failing to list modules
+	// is not an error when no source code needs to be saved.
+	if moduleDict == nil && len(plan) > 0 {
+		var err error
+		moduleDict, err = gocli.ListModulesInDir(flagDir)
+		if err != nil {
+			klog.Warningf("Failed to list modules, module versions are not recorded: %v", err)
+		}
+	}
+	for i := range plan {
+		if moduleRecord, exists := findModule(moduleDict, plan[i].record.Module); exists {
+			plan[i].version = moduleRecord.Version
+		}
+	}
+	return plan, nil
+}
+
+// readLocalLicense reads the license text of record from its local copy,
+// i.e. the file at a local path, or the file in the module dir listed in
+// moduleDict that a github url points to. The local path is empty when the
+// file is not found locally.
+func readLocalLicense(record *dict.LicenseRecord, moduleDict map[string]gocli.Module) (content string, localPath string, err error) {
+	url := record.DownaloadUrl
+	if !strings.Contains(url, "://") {
+		// modules replaced by local dirs have license paths instead of urls
+		return readLicenseFile(url, 0, 0)
+	}
+	mod, exists := findModule(moduleDict, record.Module)
+	if !exists || mod.Dir == "" {
+		return "", "", nil
+	}
+	downloadUrl, lineStart, lineEnd, err := ghutils.GithubDownloadUrl(url)
+	if err != nil || downloadUrl == "" {
+		// not a github url, there's no way to find its path in the module
+		return "", "", err
+	}
+	// https://github.com/<owner>/<repo>/raw/<revision>/<path in repo>, the
+	// revision may contain slashes, e.g. gopls/v0.7.0, and the module may be
+	// in a sub folder of the repo, so we try suffixes of the path from the
+	// longest, e.g. gopls/LICENSE and then LICENSE.
+	segments := strings.Split(downloadUrl, "/")
+	if len(segments) < 8 {
+		return "", "", nil
+	}
+	for i := 7; i < len(segments); i++ {
+		content, localPath, err := readLicenseFile(filepath.Join(mod.Dir, filepath.FromSlash(strings.Join(segments[i:], "/"))), lineStart, lineEnd)
+		if err != nil || localPath != "" {
+			return content, localPath, err
+		}
+	}
+	return "", "", nil
+}
+
+// licensePathInModule returns localPath, the license file of record that is
+// read locally, relative to its module root, or as it is when it's not in the
+// module, e.g. a license path of a module replaced by a local directory.
+func licensePathInModule(record *dict.LicenseRecord, localPath string, moduleDict map[string]gocli.Module) string {
+	if mod, exists := findModule(moduleDict, record.Module); exists && mod.Dir != "" {
+		if rel, err := filepath.Rel(mod.Dir, localPath); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.ToSlash(localPath)
+}
+
+// readLicenseFile reads lines from lineStart to lineEnd of the file at path,
+// or all of it when lineStart is 0. The returned path is empty when the file
+// does not exist.
+func readLicenseFile(path string, lineStart int, lineEnd int) (content string, localPath string, err error) {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return "", "", nil
+	}
+	fileBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", "", err
+	}
+	content, err = ghutils.SelectLines(string(fileBytes), lineStart, lineEnd)
+	if err != nil {
+		return "", "", errors.Wrapf(err, "Failed to read %s", path)
+	}
+	return content, path, nil
+}
+
+// verifyLicense returns an error when licenses classified in content,
+// downloaded from the url of record, do not include all licenses of record.
+// Licenses not known to the classifier, e.g. LicenseRef-*, are not verified.
+// Deprecated SPDX IDs match their canonical forms, e.g. the classifier finds
+// GPL-2.0 for GPL-2.0-only.
+func verifyLicense(record *dict.LicenseRecord, content string, verifier *licenses.ContentClassifier) error {
+	classified := make(map[string]bool)
+	for _, found := range verifier.Classify([]byte(content)) {
+		classified[licenses.NormalizeSpdxId(found.SpdxId)] = true
+	}
+	missing := make([]string, 0)
+	for _, part := range strings.Split(record.Type, "/") {
+		for _, spdxId := range spdxExpression(part).SpdxIds() {
+			if !strings.HasPrefix(spdxId, "LicenseRef-") && !classified[licenses.NormalizeSpdxId(spdxId)] {
+				missing = append(missing, spdxId)
+			}
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	found := sortedKeys(classified)
+	if len(found) == 0 {
+		found = []string{"none"}
+	}
+	return fmt.Errorf("license text of %s does not match %s, classified licenses: %s", record.DownaloadUrl, strings.Join(missing, ", "), strings.Join(found, ", "))
+}
+
+// findModule returns the module in moduleDict with path modulePath, or its
+// closest parent module when modulePath is a sub module, e.g. example.com/foo
+// for example.com/foo/cmd/bar.
+func findModule(moduleDict map[string]gocli.Module, modulePath string) (gocli.Module, bool) {
+	for p := modulePath; p != "." && p != "/"; p = path.Dir(p) {
+		if mod, exists := moduleDict[p]; exists {
+			return mod, true
+		}
+	}
+	return gocli.Module{}, false
+}
+
+// complyWithLicenses saves licenses and source code of modules in plan to
+// savePath, or to the archive in opts. Licenses are downloaded before anything
+// is written, so that a failed download does not leave partial output.
+func complyWithLicenses(plan []complianceItem, config config.GoModLicensesConfig, savePath string, opts saveOptions) (err error) {
+	download := opts.download
+	if download == nil {
+		download = &ghutils.Downloader{}
+	}
+	licenseContents := make([]string, len(plan))
+	// license file paths relative to module roots, when license texts are
+	// read locally
+	licensePaths := make([]string, len(plan))
+	var moduleDict map[string]gocli.Module
+	if opts.offline && len(plan) > 0 {
+		moduleDict, err = gocli.ListModulesInDir(flagDir)
 		if err != nil {
-			return errors.Wrapf(err, "%s", record.Module)
+			return errors.Wrap(err, "Failed to list modules")
 		}
-		mustWrite := func(text string) {
-			_, err := w.WriteString(text)
+	}
+	// License texts are fetched concurrently, and stored by index, so that
+	// licenses.txt is in the same order as plan.
+	err = forEachConcurrently(context.Background(), len(plan), downloadConcurrency(opts.concurrency), func(_ context.Context, i int) error {
+		item := plan[i]
+		if opts.offline {
+			licenseContent, localPath, err := readLocalLicense(item.record, moduleDict)
 			if err != nil {
-				klog.Exit(fmt.Errorf("Failed to write license to %q: %w", licensePath, err))
+				return errors.Wrapf(err, "%s", item.record.Module)
+			}
+			if localPath != "" {
+				klog.Infof("%s: Read %s", item.record.Module, localPath)
+				licenseContents[i] = licenseContent
+				licensePaths[i] = licensePathInModule(item.record, localPath, moduleDict)
+				return nil
 			}
+			klog.Warningf("%s: license text of %s not found locally, downloading it", item.record.Module, item.record.DownaloadUrl)
+		}
+		licenseContent, err := download.SmartDownload(item.record.DownaloadUrl)
+		if err != nil {
+			return errors.Wrapf(err, "%s", item.record.Module)
+		}
+		klog.Infof("%s: Downloaded %s", item.record.Module, item.record.DownaloadUrl)
+		licenseContents[i] = licenseContent
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if opts.verifier != nil {
+		mismatches := 0
+		for i, item := range plan {
+			if err := verifyLicense(item.record, licenseContents[i], opts.verifier); err != nil {
+				klog.Warningf("%s: %v", item.record.Module, err)
+				mismatches = mismatches + 1
+			}
+		}
+		if mismatches > 0 && opts.strict {
+			return violationError(fmt.Errorf("%v downloaded license text(s) do not match their licenses", mismatches))
 		}
+	}
+
+	var out saveOutput
+	if opts.archive != "" {
+		out, err = newArchiveOutput(opts.archive)
+		if err != nil {
+			return err
+		}
+	} else {
+		srcPath := filepath.Join(savePath, defaultSrcPath)
+		if opts.merge {
+			err := removeSrc(plan, srcPath, opts.prune)
+			if err != nil {
+				return err
+			}
+		} else {
+			err := os.RemoveAll(srcPath)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to remove all in %s", srcPath)
+			}
+		}
+		if err := os.MkdirAll(savePath, permDirCurrentUser); err != nil {
+			return errors.Wrapf(err, "Failed to mkdir %s", savePath)
+		}
+		out = &dirOutput{root: savePath}
+	}
+	defer func() {
+		if err != nil {
+			out.discard()
+		}
+	}()
+
+	var w bytes.Buffer
+	manifest := &saveManifest{Modules: make([]manifestEntry, 0)}
+	for i, item := range plan {
+		record := item.record
+		outputPath := defaultLicenseSubPath
+		if item.srcDir != "" {
+			// Copy the entire source directory for the library.
+			outputPath = path.Join(defaultSrcPath, record.Module)
+			if err := out.copyDir(item.srcDir, outputPath); err != nil {
+				return errors.Wrapf(err, "%s: Failed to copy source dir from %s", record.Module, item.srcDir)
+			}
+		}
+		licenseContent := licenseContents[i]
 		// Despite license type, we always put its notice and license in a single licenses.txt file.
-		mustWrite(fmt.Sprintf("============= %s =============\n", record.Module))
-		mustWrite(fmt.Sprintf("%s\n\n", record.DownaloadUrl))
-		mustWrite(string(licenseContent))
-		mustWrite("\n\n")
-		klog.Infof("%s: Downloaded %s", record.Module, record.DownaloadUrl)
+		fmt.Fprintf(&w, "============= %s =============\n", record.Module)
+		fmt.Fprintf(&w, "%s\n", record.DownaloadUrl)
+		if licensePaths[i] != "" {
+			fmt.Fprintf(&w, "Path: %s\n", licensePaths[i])
+		}
+		w.WriteString("\n")
+		w.WriteString(licenseContent)
+		w.WriteString("\n\n")
+		entry := manifestEntry{
+			Module:  record.Module,
+			Version: item.version,
+			License: record.Type,
+			Url:     record.DownaloadUrl,
+			Action:  string(item.reqType),
+			Path:    outputPath,
+			Sha256:  contentSha256(licenseContent),
+		}
+		if item.reqType == RedistributeSource {
+			entry.ExternalSource = item.externalSource
+		}
+		if isCommercial(record.Type, config.Licenses) {
+			entry.Commercial = true
+			entry.Obligations = obligations(record.Module, config)
+		}
+		manifest.Modules = append(manifest.Modules, entry)
 	}
-	if len(modulesWithBadLicenses) > 0 {
-		for _, module := range modulesWithBadLicenses {
-			klog.ErrorS(fmt.Errorf("unknown license type"), "module", module.Module, "license", module.Type)
+	if err := out.writeFile(defaultLicenseSubPath, w.Bytes()); err != nil {
+		return err
+	}
+	var manifestContent bytes.Buffer
+	if err := writeJSON(&manifestContent, manifest); err != nil {
+		return err
+	}
+	if err := out.writeFile(defaultManifestSubPath, manifestContent.Bytes()); err != nil {
+		return err
+	}
+	if err := out.close(); err != nil {
+		return err
+	}
+	for _, entry := range changedLicenses(opts.previousManifest, manifest) {
+		// A changed license text may require updating notices that have
+		// already been distributed.
+		klog.Warningf("%s: license text changed since last save, distributed notices may need an update: %s", entry.Module, entry.Url)
+	}
+	return nil
+}
+
+// removeSrc removes existing source folders of modules that need source
+// redistribution, so that they can be copied again without stale files.
+// When prune is true, source folders of all other modules are removed too.
+func removeSrc(plan []complianceItem, srcPath string, prune bool) error {
+	srcModules := make(map[string]bool)
+	for _, item := range plan {
+		if item.srcDir != "" {
+			srcModules[item.record.Module] = true
+		}
+	}
+	for module := range srcModules {
+		dir := filepath.Join(srcPath, module)
+		if err := os.RemoveAll(dir); err != nil {
+			return errors.Wrapf(err, "Failed to remove all in %s", dir)
+		}
+	}
+	if !prune {
+		return nil
+	}
+	_, err := pruneSrc(srcPath, srcModules, false)
+	return err
+}
+
+// pruneSrc removes files and folders in srcPath that do not belong to modules.
+// A folder belongs to a module when it's the module's folder, or it's inside
+// the module's folder. Parent folders of modules are kept. Returns removed
+// paths, nothing is removed when dryRun is true.
+func pruneSrc(srcPath string, modules map[string]bool, dryRun bool) ([]string, error) {
+	belongs := func(dir string) bool {
+		for module := range modules {
+			if dir == module || strings.HasPrefix(dir, module+"/") {
+				return true
+			}
+		}
+		return false
+	}
+	isAncestor := func(dir string) bool {
+		for module := range modules {
+			if strings.HasPrefix(module, dir+"/") {
+				return true
+			}
 		}
-		return fmt.Errorf("%v modules has rejected licenses", len(modulesWithBadLicenses))
+		return false
 	}
-	err = w.Flush()
+	pruned := make([]string, 0)
+	err := filepath.Walk(srcPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == srcPath {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if path == srcPath {
+			return nil
+		}
+		rel, err := filepath.Rel(srcPath, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if belongs(rel) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() && isAncestor(rel) {
+			return nil
+		}
+		pruned = append(pruned, path)
+		if !dryRun {
+			klog.InfoS("Pruned", "path", path)
+			if err := os.RemoveAll(path); err != nil {
+				return err
+			}
+		}
+		if info.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
 	if err != nil {
-		return errors.Wrapf(err, "Failed to flush")
+		return nil, errors.Wrapf(err, "Failed to prune %s", srcPath)
 	}
-	return nil
+	return pruned, nil
 }
 
 func loadInfo(path string) ([]*dict.LicenseRecord, error) {
@@ -242,6 +997,66 @@ func loadInfo(path string) ([]*dict.LicenseRecord, error) {
 	return dict.LoadLicenseRecords(bytes.NewReader(content))
 }
 
+// printEstimate prints the size of source code saved for each module in plan
+// and the total size.
+func printEstimate(plan []complianceItem) error {
+	var total int64
+	for _, item := range plan {
+		if item.srcDir == "" {
+			continue
+		}
+		size, err := srcSize(item.srcDir)
+		if err != nil {
+			return errors.Wrapf(err, "%s", item.record.Module)
+		}
+		total = total + size
+		fmt.Printf("%s\t%s\n", item.record.Module, formatSize(size))
+	}
+	fmt.Printf("total\t%s\n", formatSize(total))
+	return nil
+}
+
+// srcSize returns the total size of files copySrc copies from src.
+func srcSize(src string) (int64, error) {
+	var size int64
+	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		// the same as the Skip option of copySrc
+		if strings.HasSuffix(path, ".git") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Mode().IsRegular() {
+			size = size + info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, errors.Wrapf(err, "Failed to get size of %s", src)
+	}
+	return size, nil
+}
+
+// formatSize formats a size in bytes using binary units, e.g. 1.5 MiB.
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value := float64(size) / unit
+	for _, suffix := range []string{"KiB", "MiB", "GiB"} {
+		if value < unit {
+			return fmt.Sprintf("%.1f %s", value, suffix)
+		}
+		value = value / unit
+	}
+	return fmt.Sprintf("%.1f TiB", value)
+}
+
 func copySrc(src, dest string) error {
 	opt := copy.Options{
 		// Go module files are by default read-only, so we need to change perm on copy.
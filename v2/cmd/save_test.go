@@ -0,0 +1,432 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-licenses/v2/config"
+	"github.com/google/go-licenses/v2/dict"
+	"github.com/google/go-licenses/v2/gocli"
+	"github.com/google/go-licenses/v2/licenses"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/klog/v2"
+)
+
+// writeFiles writes files with slash separated paths relative to dir.
+func writeFiles(t *testing.T, dir string, files ...string) {
+	for _, file := range files {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(file), 0644))
+	}
+}
+
+// listFiles returns slash separated paths of files in dir, relative to dir.
+func listFiles(t *testing.T, dir string) []string {
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		files = append(files, filepath.ToSlash(rel))
+		return err
+	})
+	require.NoError(t, err)
+	return files
+}
+
+func TestRemoveSrc(t *testing.T) {
+	plan := []complianceItem{
+		{record: &dict.LicenseRecord{Module: "github.com/a/mpl", Type: "MPL-2.0"}, reqType: RedistributeSource, srcDir: "/go/pkg/mod/github.com/a/mpl"},
+		{record: &dict.LicenseRecord{Module: "github.com/a/mit", Type: "MIT"}, reqType: RedistributeNotice},
+	}
+	tests := []struct {
+		name  string
+		prune bool
+		kept  []string
+	}{
+		{name: "merge", prune: false, kept: []string{"README", "github.com/a/mit/main.go", "github.com/old/main.go"}},
+		{name: "prune", prune: true, kept: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srcPath, err := ioutil.TempDir("", "save_test")
+			require.NoError(t, err)
+			defer os.RemoveAll(srcPath)
+			writeFiles(t, srcPath, "github.com/a/mpl/stale.go", "github.com/a/mit/main.go", "github.com/old/main.go", "README")
+
+			require.NoError(t, removeSrc(plan, srcPath, tt.prune))
+			assert.ElementsMatch(t, tt.kept, listFiles(t, srcPath))
+		})
+	}
+}
+
+func TestRemoveSrc_KeepsParentsOfModules(t *testing.T) {
+	srcPath, err := ioutil.TempDir("", "save_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(srcPath)
+	writeFiles(t, srcPath, "github.com/a/mpl/stale.go")
+
+	plan := []complianceItem{{record: &dict.LicenseRecord{Module: "github.com/a/mpl", Type: "MPL-2.0"}, reqType: RedistributeSource, srcDir: "/go/pkg/mod/github.com/a/mpl"}}
+	require.NoError(t, removeSrc(plan, srcPath, true))
+	_, err = os.Stat(filepath.Join(srcPath, "github.com", "a"))
+	assert.NoError(t, err)
+}
+
+func TestExternalSource(t *testing.T) {
+	cfg := config.GoModLicensesConfig{}
+	cfg.Module.Overrides = []config.ModuleOverride{
+		{Name: "github.com/a/mpl", ExternalSource: "https://example.com/mpl-src.tar.gz"},
+		{Name: "github.com/a/mit"},
+	}
+	tests := []struct {
+		module string
+		want   string
+	}{
+		{module: "github.com/a/mpl", want: "https://example.com/mpl-src.tar.gz"},
+		{module: "github.com/a/mpl/v2", want: "https://example.com/mpl-src.tar.gz"},
+		{module: "github.com/a/mplx", want: ""},
+		{module: "github.com/a/mit", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.module, func(t *testing.T) {
+			assert.Equal(t, tt.want, externalSource(tt.module, cfg))
+		})
+	}
+}
+
+func TestRemoveSrc_ExternalSource(t *testing.T) {
+	srcPath, err := ioutil.TempDir("", "save_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(srcPath)
+	writeFiles(t, srcPath, "github.com/a/mpl/main.go", "github.com/b/mpl/main.go")
+	plan := []complianceItem{
+		{record: &dict.LicenseRecord{Module: "github.com/a/mpl", Type: "MPL-2.0"}, reqType: RedistributeSource, externalSource: "https://example.com/mpl-src.tar.gz"},
+		{record: &dict.LicenseRecord{Module: "github.com/b/mpl", Type: "MPL-2.0"}, reqType: RedistributeSource, srcDir: "/go/pkg/mod/github.com/b/mpl"},
+	}
+
+	// Only github.com/b/mpl is cleared for copying its source again.
+	require.NoError(t, removeSrc(plan, srcPath, false))
+	assert.Equal(t, []string{"github.com/a/mpl/main.go"}, listFiles(t, srcPath))
+}
+
+func TestCommercialObligations(t *testing.T) {
+	cfg := config.GoModLicensesConfig{}
+	cfg.Licenses.Types.Overrides = []config.LicenseTypeOverride{{SpdxId: "LicenseRef-Acme", Type: config.LicenseTypeCommercial}}
+	cfg.Module.Overrides = []config.ModuleOverride{{Name: "example.com/acme/sdk", Obligations: "Acme agreement 42"}}
+
+	assert.True(t, isCommercial("MIT / LicenseRef-Acme", cfg.Licenses))
+	assert.False(t, isCommercial("MIT", cfg.Licenses))
+	assert.Equal(t, "Acme agreement 42", obligations("example.com/acme/sdk", cfg))
+	assert.Equal(t, "Acme agreement 42", obligations("example.com/acme/sdk/v2", cfg))
+	assert.Equal(t, "", obligations("example.com/other", cfg))
+
+	reqType, err := requirementType("LicenseRef-Acme", cfg.Licenses)
+	require.NoError(t, err)
+	assert.Equal(t, Unknown, reqType, "not allowed commercial licenses")
+	cfg.Licenses.Commercial.Allowed = true
+	reqType, err = requirementType("LicenseRef-Acme", cfg.Licenses)
+	require.NoError(t, err)
+	assert.Equal(t, RedistributeNotice, reqType, "allowed commercial licenses")
+}
+
+func TestPlanCompliance(t *testing.T) {
+	cfg := config.GoModLicensesConfig{}
+	cfg.Module.Overrides = []config.ModuleOverride{{Name: "github.com/a/mpl", ExternalSource: "https://example.com/mpl-src.tar.gz"}}
+	mit := &dict.LicenseRecord{Module: "github.com/b/mit", Type: "MIT"}
+	mpl := &dict.LicenseRecord{Module: "github.com/a/mpl", Type: "MPL-2.0"}
+
+	plan, err := planCompliance([]*dict.LicenseRecord{mit, mpl}, cfg)
+	require.NoError(t, err)
+	assert.Equal(t, []complianceItem{
+		{record: mpl, reqType: RedistributeSource, externalSource: "https://example.com/mpl-src.tar.gz"},
+		{record: mit, reqType: RedistributeNotice},
+	}, plan)
+}
+
+func TestPlanCompliance_RejectedLicenses(t *testing.T) {
+	info := []*dict.LicenseRecord{
+		{Module: "github.com/a/mit", Type: "MIT"},
+		{Module: "github.com/b/acme", Type: "LicenseRef-Acme"},
+		{Module: "github.com/c/acme", Type: "MIT / LicenseRef-Acme"},
+	}
+	_, err := planCompliance(info, config.GoModLicensesConfig{})
+	assert.EqualError(t, err, "2 modules has rejected licenses")
+}
+
+func TestPlanCompliance_Version(t *testing.T) {
+	// Tests run in the v2 module, which requires testify.
+	record := &dict.LicenseRecord{Module: "github.com/stretchr/testify", Type: "MIT"}
+
+	plan, err := planCompliance([]*dict.LicenseRecord{record}, config.GoModLicensesConfig{})
+	require.NoError(t, err)
+	require.Len(t, plan, 1)
+	assert.Regexp(t, `^v\d+\.\d+\.\d+`, plan[0].version)
+}
+
+func TestComplyWithLicenses_Manifest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("license of " + r.URL.Path))
+	}))
+	defer server.Close()
+	src := t.TempDir()
+	writeFiles(t, src, "LICENSE")
+	plan := []complianceItem{
+		{record: &dict.LicenseRecord{Module: "example.com/mpl", Type: "MPL-2.0", DownaloadUrl: server.URL + "/mpl"}, reqType: RedistributeSource, srcDir: src, version: "v1.0.0"},
+		{record: &dict.LicenseRecord{Module: "example.com/mit", Type: "MIT", DownaloadUrl: server.URL + "/mit"}, reqType: RedistributeNotice},
+	}
+	savePath := filepath.Join(t.TempDir(), "notices")
+
+	require.NoError(t, complyWithLicenses(plan, config.GoModLicensesConfig{}, savePath, saveOptions{}))
+
+	manifest, err := loadManifest(savePath)
+	require.NoError(t, err)
+	require.Len(t, manifest.Modules, 2)
+	mpl, mit := manifest.Modules[0], manifest.Modules[1]
+	assert.Equal(t, "example.com/mpl", mpl.Module)
+	assert.Equal(t, "v1.0.0", mpl.Version)
+	assert.Equal(t, string(RedistributeSource), mpl.Action)
+	assert.Equal(t, "src/example.com/mpl", mpl.Path)
+	assert.Equal(t, "example.com/mit", mit.Module)
+	assert.Empty(t, mit.Version)
+	assert.Equal(t, string(RedistributeNotice), mit.Action)
+	assert.Equal(t, defaultLicenseSubPath, mit.Path)
+	assert.FileExists(t, filepath.Join(savePath, filepath.FromSlash(mpl.Path), "LICENSE"))
+}
+
+func TestSrcSize(t *testing.T) {
+	src := t.TempDir()
+	// Contents of files are their paths, .git is not copied.
+	writeFiles(t, src, "LICENSE", "pkg/a.go", ".git/config")
+
+	size, err := srcSize(src)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len("LICENSE")+len("pkg/a.go")), size)
+
+	_, err = srcSize(filepath.Join(src, "missing"))
+	assert.Error(t, err)
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := map[int64]string{
+		0:               "0 B",
+		1023:            "1023 B",
+		1536:            "1.5 KiB",
+		5 * 1024 * 1024: "5.0 MiB",
+		3 << 30:         "3.0 GiB",
+		2 << 40:         "2.0 TiB",
+	}
+	for size, want := range tests {
+		assert.Equal(t, want, formatSize(size), size)
+	}
+}
+
+func TestTypeStrictness(t *testing.T) {
+	cfg := config.LicensesConfig{}
+	cfg.Types.Requirements = map[string]string{"internal": config.RequirementDistributeSource}
+	assert.Less(t, typeStrictness("notice", cfg), typeStrictness("restricted", cfg))
+	assert.Less(t, typeStrictness("restricted", cfg), typeStrictness("FORBIDDEN", cfg))
+	assert.Equal(t, typeStrictness("reciprocal", cfg), typeStrictness("internal", cfg))
+	assert.Equal(t, -1, typeStrictness("", cfg))
+	assert.Equal(t, -1, typeStrictness(config.LicenseTypeCommercial, cfg))
+}
+
+func TestWarnRelaxedOverrides(t *testing.T) {
+	var buf bytes.Buffer
+	klog.LogToStderr(false)
+	klog.SetOutput(&buf)
+	defer func() {
+		klog.SetOutput(os.Stderr)
+		klog.LogToStderr(true)
+	}()
+	cfg := config.LicensesConfig{}
+	cfg.Types.Overrides = []config.LicenseTypeOverride{
+		{SpdxId: "AGPL-3.0", Type: "notice"},
+		{SpdxId: "GPL-2.0", Type: "permissive"},
+		// stricter than the classifier's type
+		{SpdxId: "MIT", Type: "restricted"},
+		// unknown to the classifier
+		{SpdxId: "LicenseRef-Acme", Type: "notice"},
+	}
+
+	warnRelaxedOverrides(cfg)
+	klog.Flush()
+
+	out := buf.String()
+	assert.Contains(t, out, "License type override relaxes AGPL-3.0 from Forbidden to Notice")
+	assert.Contains(t, out, "License type override relaxes GPL-2.0 from Restricted to Permissive")
+	assert.NotContains(t, out, "MIT")
+	assert.NotContains(t, out, "LicenseRef-Acme")
+}
+
+// runSaveCmd runs the save command with args in a sub process, because it
+// exits the process on failures.
+func runSaveCmd(t *testing.T, args ...string) (string, error) {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestSaveCmdHelper$")
+	cmd.Env = append(os.Environ(), "GO_LICENSES_SAVE_ARGS="+strings.Join(append([]string{"save"}, args...), "\n"))
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+// TestSaveCmdHelper is not a real test, it runs the save command for
+// runSaveCmd.
+func TestSaveCmdHelper(t *testing.T) {
+	args := os.Getenv("GO_LICENSES_SAVE_ARGS")
+	if args == "" {
+		t.Skip("only run by runSaveCmd")
+	}
+	rootCmd.SetArgs(strings.Split(args, "\n"))
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func TestSaveCmd_CheckOnly(t *testing.T) {
+	dir := t.TempDir()
+	savePath := filepath.Join(dir, "notices")
+	csvPath := filepath.Join(dir, "licenses.csv")
+	configPath := filepath.Join(dir, "go-licenses.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte("{}\n"), 0644))
+	// The url is never downloaded.
+	require.NoError(t, ioutil.WriteFile(csvPath, []byte("example.com/mit, https://example.invalid/LICENSE, MIT\n"), 0644))
+
+	out, err := runSaveCmd(t, csvPath, "--config", configPath, "--save_path", savePath, "--check_only")
+	require.NoError(t, err, out)
+	assert.Contains(t, out, "Done: check compliance, nothing is saved")
+	_, err = os.Stat(savePath)
+	assert.True(t, os.IsNotExist(err), "%s is created", savePath)
+
+	require.NoError(t, ioutil.WriteFile(csvPath, []byte("example.com/acme, https://example.invalid/LICENSE, LicenseRef-Acme\n"), 0644))
+	out, err = runSaveCmd(t, csvPath, "--config", configPath, "--save_path", savePath, "--check_only")
+	assert.Error(t, err)
+	assert.Contains(t, out, "1 modules has rejected licenses")
+	_, err = os.Stat(savePath)
+	assert.True(t, os.IsNotExist(err), "%s is created", savePath)
+
+	out, err = runSaveCmd(t, csvPath, "--config", configPath, "--save_path", savePath, "--check_only", "--estimate")
+	assert.Error(t, err)
+	assert.Contains(t, out, "--check_only and --estimate cannot be used at the same time")
+}
+
+func TestLicensePathInModule(t *testing.T) {
+	moduleDict := map[string]gocli.Module{
+		"example.com/a": {Path: "example.com/a", Dir: filepath.FromSlash("/go/pkg/mod/example.com/a@v1.0.0")},
+	}
+	tests := []struct {
+		name      string
+		module    string
+		localPath string
+		want      string
+	}{
+		{name: "in module", module: "example.com/a", localPath: "/go/pkg/mod/example.com/a@v1.0.0/sub/LICENSE", want: "sub/LICENSE"},
+		{name: "sub module", module: "example.com/a/sub", localPath: "/go/pkg/mod/example.com/a@v1.0.0/sub/LICENSE", want: "sub/LICENSE"},
+		{name: "outside module", module: "example.com/a", localPath: "/src/a/LICENSE", want: "/src/a/LICENSE"},
+		{name: "unknown module", module: "example.com/b", localPath: "/src/b/LICENSE", want: "/src/b/LICENSE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := &dict.LicenseRecord{Module: tt.module}
+			assert.Equal(t, tt.want, licensePathInModule(record, filepath.FromSlash(tt.localPath), moduleDict))
+		})
+	}
+}
+
+func TestHasUnknownLicenseType(t *testing.T) {
+	tests := map[string]bool{
+		"LicenseRef-Acme":            true,
+		"MIT / LicenseRef-Acme":      true,
+		"MIT OR LicenseRef-Acme":     true,
+		"MIT":                        false,
+		"AGPL-3.0 / LicenseRef-Acme": false,
+		"not an expression (":        false,
+	}
+	for license, want := range tests {
+		assert.Equal(t, want, hasUnknownLicenseType(license, config.LicensesConfig{}), license)
+	}
+}
+
+func TestPlanCompliance_UnknownAction(t *testing.T) {
+	defer func(action string) { unknownAction = action }(unknownAction)
+	mit := &dict.LicenseRecord{Module: "github.com/a/mit", Type: "MIT"}
+	info := []*dict.LicenseRecord{
+		mit,
+		{Module: "github.com/b/acme", Type: "LicenseRef-Acme"},
+	}
+	for _, action := range []string{unknownActionWarn, unknownActionSkip} {
+		t.Run(action, func(t *testing.T) {
+			unknownAction = action
+			plan, err := planCompliance(info, config.GoModLicensesConfig{})
+			require.NoError(t, err)
+			require.Len(t, plan, 1)
+			assert.Equal(t, mit, plan[0].record)
+		})
+	}
+
+	unknownAction = unknownActionFail
+	_, err := planCompliance(info, config.GoModLicensesConfig{})
+	assert.EqualError(t, err, "1 modules has rejected licenses")
+
+	// Forbidden licenses always fail.
+	unknownAction = unknownActionSkip
+	_, err = planCompliance(append(info, &dict.LicenseRecord{Module: "github.com/c/agpl", Type: "AGPL-3.0"}), config.GoModLicensesConfig{})
+	assert.EqualError(t, err, "1 modules has rejected licenses")
+}
+
+func TestVerifyLicense(t *testing.T) {
+	const dbPath = "../third_party/google/licenseclassifier/licenses"
+	verifier, err := licenses.NewContentClassifier(dbPath, 0)
+	require.NoError(t, err)
+	gpl, err := ioutil.ReadFile(filepath.Join(dbPath, "GPL-2.0.txt"))
+	require.NoError(t, err)
+	mit, err := ioutil.ReadFile(filepath.Join(dbPath, "MIT.txt"))
+	require.NoError(t, err)
+	tests := []struct {
+		name        string
+		licenseType string
+		content     []byte
+		wantErr     bool
+	}{
+		{name: "deprecated ID", licenseType: "GPL-2.0", content: gpl},
+		// The classifier finds the deprecated GPL-2.0.
+		{name: "canonical ID", licenseType: "GPL-2.0-only", content: gpl},
+		{name: "expression", licenseType: "GPL-2.0-only WITH Classpath-exception-2.0", content: gpl},
+		{name: "LicenseRef", licenseType: "LicenseRef-Acme", content: mit},
+		{name: "mismatch", licenseType: "GPL-2.0-only", content: mit, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := &dict.LicenseRecord{Module: "example.com/a", Type: tt.licenseType, DownaloadUrl: "https://example.com/a/LICENSE"}
+			err := verifyLicense(record, string(tt.content), verifier)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "does not match GPL-2.0-only, classified licenses: MIT")
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,228 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/go-licenses/v2/licenses"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// https://cyclonedx.org/docs/1.4/json/
+	sbomFormatCycloneDx = "cyclonedx"
+	// https://spdx.github.io/spdx-spec/v2.3/
+	sbomFormatSpdxJson = "spdx-json"
+	sbomFormatSpdxTag  = "spdx-tag"
+)
+
+// flag variables
+var sbomFormat string // output format of the SBOM
+
+// sbomCmd represents the sbom command
+var sbomCmd = &cobra.Command{
+	Use:   "sbom {<package>, --binary <binary_path>} --format {cyclonedx,spdx-json,spdx-tag}",
+	Short: "Generate a software bill of materials (SBOM) of dependencies with their licenses",
+	Long: `"go-licenses sbom" scans licenses of dependencies the same way as "go-licenses csv",
+and writes a software bill of materials, listing every module as a component with
+its version, package url and licenses, for supply chain tools to ingest. CycloneDX
+JSON and SPDX documents in JSON or tag-value format are supported.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runE(func(cmd *cobra.Command, args []string) error {
+		return sbomImp(context.Background(), args[0])
+	}),
+}
+
+func init() {
+	rootCmd.AddCommand(sbomCmd)
+	addScanFlags(sbomCmd)
+	sbomCmd.Flags().StringVar(&sbomFormat, "format", sbomFormatCycloneDx, fmt.Sprintf("SBOM format, one of %s, %s, %s", sbomFormatCycloneDx, sbomFormatSpdxJson, sbomFormatSpdxTag))
+}
+
+type cycloneDxBom struct {
+	BomFormat    string               `json:"bomFormat"`
+	SpecVersion  string               `json:"specVersion"`
+	SerialNumber string               `json:"serialNumber"`
+	Version      int                  `json:"version"`
+	Metadata     cycloneDxMetadata    `json:"metadata"`
+	Components   []cycloneDxComponent `json:"components"`
+}
+
+type cycloneDxMetadata struct {
+	Timestamp time.Time       `json:"timestamp"`
+	Tools     []cycloneDxTool `json:"tools"`
+}
+
+type cycloneDxTool struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type cycloneDxComponent struct {
+	Type     string             `json:"type"`
+	BomRef   string             `json:"bom-ref"`
+	Name     string             `json:"name"`
+	Version  string             `json:"version,omitempty"`
+	Purl     string             `json:"purl"`
+	Licenses []cycloneDxLicense `json:"licenses,omitempty"`
+}
+
+// cycloneDxLicense is either a license or an SPDX license expression.
+type cycloneDxLicense struct {
+	License    *cycloneDxLicenseId `json:"license,omitempty"`
+	Expression string              `json:"expression,omitempty"`
+}
+
+type cycloneDxLicenseId struct {
+	// SPDX ID of the license
+	Id string `json:"id,omitempty"`
+	// Name of a license without SPDX ID, e.g. LicenseRef-Acme-Commercial
+	Name string `json:"name,omitempty"`
+}
+
+func sbomImp(ctx context.Context, binaryOrImportPath string) error {
+	switch sbomFormat {
+	case sbomFormatCycloneDx, sbomFormatSpdxJson, sbomFormatSpdxTag:
+	default:
+		return usageError(fmt.Errorf("invalid --format %q: must be one of %s, %s, %s", sbomFormat, sbomFormatCycloneDx, sbomFormatSpdxJson, sbomFormatSpdxTag))
+	}
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	rows, scanErr := scanLicenses(ctx, []string{binaryOrImportPath}, config)
+	if rows == nil {
+		return scanErr
+	}
+	modules := reportModules(rows, config.Licenses)
+	switch sbomFormat {
+	case sbomFormatCycloneDx:
+		var bom *cycloneDxBom
+		bom, err = cycloneDx(modules)
+		if err != nil {
+			return err
+		}
+		err = writeJSON(os.Stdout, bom)
+	default:
+		mainModule := ""
+		for _, row := range rows {
+			if row.Main {
+				mainModule = row.Module
+			}
+		}
+		var doc *spdxDocument
+		doc, err = spdx(modules, mainModule, config.Licenses)
+		if err != nil {
+			return err
+		}
+		if sbomFormat == sbomFormatSpdxJson {
+			err = writeJSON(os.Stdout, doc)
+		} else {
+			err = writeSpdxTagValue(os.Stdout, doc)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	// Modules that failed scanning are reported after writing the SBOM.
+	return scanErr
+}
+
+// cycloneDx generates a CycloneDX BOM with a component for each module.
+func cycloneDx(modules []reportModule) (*cycloneDxBom, error) {
+	serialNumber, err := uuid()
+	if err != nil {
+		return nil, err
+	}
+	bom := &cycloneDxBom{
+		BomFormat:    "CycloneDX",
+		SpecVersion:  "1.4",
+		SerialNumber: "urn:uuid:" + serialNumber,
+		Version:      1,
+		Metadata: cycloneDxMetadata{
+			Timestamp: time.Now().UTC(),
+			Tools:     []cycloneDxTool{{Name: "go-licenses", Version: goLicensesVersion()}},
+		},
+		Components: make([]cycloneDxComponent, 0, len(modules)),
+	}
+	for _, module := range modules {
+		component := cycloneDxComponent{
+			Type:    "library",
+			Name:    module.Module,
+			Version: module.Version,
+			Purl:    purl(module.Module, module.Version),
+		}
+		component.BomRef = component.Purl
+		// A license may be found in several files of a module.
+		seen := make(map[string]bool)
+		for _, license := range module.Licenses {
+			// Strict SPDX validators reject deprecated IDs.
+			spdxId := licenses.NormalizeSpdxIds(license.SpdxId)
+			if seen[spdxId] {
+				continue
+			}
+			seen[spdxId] = true
+			component.Licenses = append(component.Licenses, cycloneDxLicenseOf(spdxId))
+		}
+		bom.Components = append(bom.Components, component)
+	}
+	return bom, nil
+}
+
+// goLicensesVersion returns version of this binary, without module path.
+func goLicensesVersion() string {
+	tool := toolVersion()
+	return tool[strings.LastIndex(tool, "@")+1:]
+}
+
+// cycloneDxLicenseOf converts an SPDX ID or expression to a CycloneDX license.
+func cycloneDxLicenseOf(spdxId string) cycloneDxLicense {
+	switch {
+	case strings.Contains(spdxId, " "):
+		// e.g. "Apache-2.0 OR MIT"
+		return cycloneDxLicense{Expression: spdxId}
+	case strings.HasPrefix(spdxId, "LicenseRef-"):
+		// Only ids in the SPDX license list are valid CycloneDX license ids.
+		return cycloneDxLicense{License: &cycloneDxLicenseId{Name: spdxId}}
+	default:
+		return cycloneDxLicense{License: &cycloneDxLicenseId{Id: spdxId}}
+	}
+}
+
+// purl returns the package url of a go module.
+// Reference: https://github.com/package-url/purl-spec/blob/master/PURL-TYPES.rst#golang
+func purl(module string, version string) string {
+	if version == "" {
+		return "pkg:golang/" + module
+	}
+	return "pkg:golang/" + module + "@" + version
+}
+
+// uuid returns a random (version 4) UUID.
+func uuid() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("Failed to generate uuid: %w", err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
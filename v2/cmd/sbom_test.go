@@ -0,0 +1,74 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCycloneDx(t *testing.T) {
+	modules := []reportModule{
+		{
+			Module:  "example.com/a",
+			Version: "v1.0.0",
+			Licenses: []reportLicense{
+				{SpdxId: "MIT", Path: "LICENSE"},
+				{SpdxId: "MIT", Path: "sub/LICENSE"},
+				{SpdxId: "LicenseRef-Acme"},
+			},
+		},
+		{
+			Module:   "example.com/main",
+			Licenses: []reportLicense{{SpdxId: "Apache-2.0 OR MIT"}},
+		},
+	}
+	bom, err := cycloneDx(modules)
+	require.NoError(t, err)
+	assert.Equal(t, "CycloneDX", bom.BomFormat)
+	assert.Equal(t, "1.4", bom.SpecVersion)
+	assert.Regexp(t, regexp.MustCompile(`^urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), bom.SerialNumber)
+	assert.Equal(t, []cycloneDxComponent{
+		{
+			Type:    "library",
+			BomRef:  "pkg:golang/example.com/a@v1.0.0",
+			Name:    "example.com/a",
+			Version: "v1.0.0",
+			Purl:    "pkg:golang/example.com/a@v1.0.0",
+			Licenses: []cycloneDxLicense{
+				{License: &cycloneDxLicenseId{Id: "MIT"}},
+				{License: &cycloneDxLicenseId{Name: "LicenseRef-Acme"}},
+			},
+		},
+		{
+			Type:     "library",
+			BomRef:   "pkg:golang/example.com/main",
+			Name:     "example.com/main",
+			Purl:     "pkg:golang/example.com/main",
+			Licenses: []cycloneDxLicense{{Expression: "Apache-2.0 OR MIT"}},
+		},
+	}, bom.Components)
+}
+
+func TestSbomImp_InvalidFormat(t *testing.T) {
+	defer func(format string) { sbomFormat = format }(sbomFormat)
+	sbomFormat = "spdx"
+	err := sbomImp(context.Background(), "example.com/main")
+	assert.EqualError(t, err, `invalid --format "spdx": must be one of cyclonedx, spdx-json, spdx-tag`)
+}
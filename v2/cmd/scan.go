@@ -0,0 +1,915 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	configmodule "github.com/google/go-licenses/v2/config"
+	"github.com/google/go-licenses/v2/ghutils"
+	"github.com/google/go-licenses/v2/gocli"
+	"github.com/google/go-licenses/v2/goutils"
+	"github.com/google/go-licenses/v2/licenses"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+)
+
+// licenseRow is a license found for a module, it's a row in licenses csv.
+type licenseRow struct {
+	Module  string // module path, with sub module path appended if any
+	Version string // module version
+	Url     string // license url, or license path when url is not available
+	SpdxId  string // SPDX ID of the license, multiple licenses are joined by " / "
+	Local   bool   // whether the module is replaced by a local directory, Url is a local path then
+	// go version declared by the module's go.mod go directive, may be empty
+	GoVersion string
+	// language of the license text when it's identified using a translation
+	Language string
+	// license file path relative to the module root, empty when only a url
+	// is configured for the license
+	Path string
+	// lines of the licenses in the license file, the first line is 1. They
+	// are 0 when unknown or the licenses are the whole file.
+	LineStart int
+	LineEnd   int
+	// lowest classifier confidence of licenses in the file, 0 when licenses
+	// are not classified, e.g. configured or declared
+	Confidence float64
+	// whether the license is declared by the module instead of classified
+	// from a license text, it's less reliable
+	Declared bool
+	// whether the module is the main module
+	Main bool
+}
+
+// flags shared by commands that scan licenses
+var flagBinary *bool
+var flagExclude *[]string
+var flagAllowEmptyVersion *[]string
+var flagBaseUrl *[]string
+var flagLicenseFilename *string
+var flagReportMissing *string
+var flagEvidenceDir *string
+var flagSkipLargeModules *int64
+var flagConcurrency *int
+var flagOffline *bool
+var flagSourceTimeout *time.Duration
+var flagSourceRetries *int
+var flagDefaultBranch *string
+var flagFollowSymlinks *bool
+var flagScanReadme *bool
+var flagScanIgnoreFile *string
+var flagBuildTags *[]string
+var flagGoos *string
+var flagGoarch *string
+var flagIncludeTestDeps *bool
+
+// addScanFlags adds flags shared by commands that scan licenses to cmd.
+func addScanFlags(cmd *cobra.Command) {
+	if flagBinary == nil {
+		flagBinary = new(bool)
+		flagExclude = new([]string)
+		flagAllowEmptyVersion = new([]string)
+		flagBaseUrl = new([]string)
+		flagLicenseFilename = new(string)
+		flagReportMissing = new(string)
+		flagEvidenceDir = new(string)
+		flagSkipLargeModules = new(int64)
+		flagConcurrency = new(int)
+		flagOffline = new(bool)
+		flagSourceTimeout = new(time.Duration)
+		flagSourceRetries = new(int)
+		flagDefaultBranch = new(string)
+		flagFollowSymlinks = new(bool)
+		flagScanReadme = new(bool)
+		flagScanIgnoreFile = new(string)
+		flagBuildTags = new([]string)
+		flagGoos = new(string)
+		flagGoarch = new(string)
+		flagIncludeTestDeps = new(bool)
+	}
+	cmd.Flags().BoolVarP(flagBinary, "binary", "b", false, "scan a go binary instead of a package, the binary must be built using current go working dir in go modules mode")
+	cmd.Flags().StringArrayVar(flagExclude, "exclude", nil, "exclude a specific module version from scanning, in the form of <module>@<version>, can be repeated")
+	cmd.Flags().StringSliceVar(flagBuildTags, "build_tags", nil, "comma separated build tags used when listing dependencies of packages, so that dependencies only imported under the tags are found, e.g. linux,integration, ignored with --binary")
+	cmd.Flags().StringVar(flagGoos, "goos", "", "GOOS used when listing dependencies of packages, e.g. windows for a cross-compiled binary, defaults to the go environment, ignored with --binary")
+	cmd.Flags().StringVar(flagGoarch, "goarch", "", "GOARCH used when listing dependencies of packages, e.g. arm64, defaults to the go environment, ignored with --binary")
+	cmd.Flags().BoolVar(flagIncludeTestDeps, "include_test_deps", false, "also scan dependencies only imported by tests of the packages, they are excluded by default because they are not shipped, ignored with --binary")
+	cmd.Flags().StringSliceVar(flagAllowEmptyVersion, "allow_empty_version", nil, "comma separated module path prefixes that are expected to have no version, so no warning is logged for them, prefixes match whole path elements, e.g. github.com/acme matches github.com/acme/tools")
+	cmd.Flags().StringArrayVar(flagBaseUrl, "base_url", nil, "rewrite license urls starting with a prefix to use another base url, in the form of <prefix>=<replacement>, e.g. https://github.com/=https://mirror.example.com/github/, can be repeated")
+	cmd.Flags().StringVar(flagLicenseFilename, "license_filename", "", "only classify files with this exact name, e.g. LICENSE, ignoring other license-like files")
+	cmd.Flags().StringVar(flagReportMissing, "report_missing", "", "write a json list of modules whose licenses are not found to this file, even when scanning fails")
+	cmd.Flags().StringVar(flagEvidenceDir, "evidence_dir", "", "copy classified license files of each module to this folder, with json files recording the classification details, as evidence for audit")
+	cmd.Flags().BoolVar(flagOffline, "offline", false, "do not resolve module source repos over the network, license urls are left empty, e.g. when scanning vendored dependencies in air-gapped environments")
+	cmd.Flags().DurationVar(flagSourceTimeout, "source_timeout", goutils.DefaultGoGetTimeout, "timeout of each request resolving a module's source repo")
+	cmd.Flags().IntVar(flagSourceRetries, "source_retries", 2, "number of retries with exponential backoff when resolving a module's source repo fails, license urls are left empty when it still fails")
+	cmd.Flags().StringVar(flagDefaultBranch, "default_branch", "", "branch license urls link to when a module version is empty, defaults to the default branch of each repo")
+	cmd.Flags().BoolVar(flagFollowSymlinks, "follow_symlinks", false, "follow symbolic links when scanning module folders, e.g. a LICENSE symlinked to a license shared by a monorepo, links forming loops are skipped")
+	cmd.Flags().BoolVar(flagScanReadme, "scan_readme", false, "classify README files with a higher confidence threshold when no license files are found, for modules that only state their license in README.md, it matters with --license_filename because README files are classified like other files otherwise")
+	cmd.Flags().StringVar(flagScanIgnoreFile, "scan_ignore_file", "", "a .gitignore-style file of glob patterns, one per line, of files and folders not scanned in each module, e.g. vendored test fixtures or generated code, patterns with a slash match paths relative to the module root")
+	cmd.Flags().IntVar(flagConcurrency, "concurrency", 0, "number of modules to scan concurrently, defaults to GOMAXPROCS")
+	cmd.Flags().Int64Var(flagSkipLargeModules, "skip_large_modules", 0, "skip scanning modules whose source folder is larger than this many MiB, for quick checks, 0 means no limit")
+}
+
+// missingLicense is a module whose licenses are not found.
+type missingLicense struct {
+	Module  string `json:"module"`
+	Version string `json:"version"`
+	Reason  string `json:"reason"`
+	// module dir that was scanned, it's not reported because it depends on
+	// the machine
+	Dir string `json:"-"`
+}
+
+// scanLicenses lists dependencies of a go package or a built go binary and
+// scans their licenses. Licenses are returned sorted by module path, then by
+// license path, so that results are stable across runs.
+// Modules that fail to be scanned are reported in logs, and an error is
+// returned after scanning all the other modules, so that callers can still
+// use licenses that are successfully found.
+func scanLicenses(ctx context.Context, binaryOrImportPaths []string, config *configmodule.GoModLicensesConfig) (rows []licenseRow, err error) {
+	rows, _, err = scanLicensesWithMissing(ctx, binaryOrImportPaths, config)
+	return rows, err
+}
+
+// scanLicensesEach is scanLicenses, but also passes licenses of each module
+// to onScan as soon as the module is scanned, see moduleScanner.onScan.
+func scanLicensesEach(ctx context.Context, binaryOrImportPaths []string, config *configmodule.GoModLicensesConfig, onScan func(rows []licenseRow) error) (rows []licenseRow, err error) {
+	rows, _, err = scanModulesWithMissing(ctx, config, func() ([]gocli.Module, error) {
+		return listModules(binaryOrImportPaths, config)
+	}, onScan)
+	return rows, err
+}
+
+// scanLicensesWithMissing is scanLicenses, but also returns modules whose
+// licenses are not found.
+func scanLicensesWithMissing(ctx context.Context, binaryOrImportPaths []string, config *configmodule.GoModLicensesConfig) (rows []licenseRow, missing []missingLicense, err error) {
+	return scanModulesWithMissing(ctx, config, func() ([]gocli.Module, error) {
+		return listModules(binaryOrImportPaths, config)
+	}, nil)
+}
+
+// scanModulesWithMissing scans licenses of modules returned by listMods,
+// which is called after flags are validated. onScan is optional, see
+// moduleScanner.onScan.
+func scanModulesWithMissing(ctx context.Context, config *configmodule.GoModLicensesConfig, listMods func() ([]gocli.Module, error), onScan func(rows []licenseRow) error) (rows []licenseRow, missing []missingLicense, err error) {
+	if err := useDefaultLicenseDB(config); err != nil {
+		return nil, nil, err
+	}
+	excluded, err := parseExcludes(flagExclude)
+	if err != nil {
+		return nil, nil, usageError(err)
+	}
+	rewrites, err := parseBaseUrls(flagBaseUrl)
+	if err != nil {
+		return nil, nil, usageError(err)
+	}
+	vanityImports := make(map[string]string)
+	for _, vanityImport := range config.Module.VanityImports {
+		vanityImports[vanityImport.Prefix] = vanityImport.Repo
+	}
+	var ignorePatterns []string
+	if *flagScanIgnoreFile != "" {
+		ignorePatterns, err = licenses.ReadIgnoreFile(*flagScanIgnoreFile)
+		if err != nil {
+			return nil, nil, usageError(err)
+		}
+	}
+	mods, err := listMods()
+	if err != nil {
+		return nil, nil, err
+	}
+	scanner := &moduleScanner{
+		config:         config,
+		excluded:       excluded,
+		rewrites:       rewrites,
+		ignorePatterns: ignorePatterns,
+		onScan:         onScan,
+		repoOptions: goutils.RepoOptions{
+			VanityImports: vanityImports,
+			Timeout:       *flagSourceTimeout,
+			Retries:       *flagSourceRetries,
+		},
+	}
+	results, err := scanner.scanAll(ctx, mods, *flagConcurrency)
+	if err != nil {
+		return nil, nil, err
+	}
+	rows = make([]licenseRow, 0)
+	missing = make([]missingLicense, 0)
+	errorCount := 0
+	for _, result := range results {
+		rows = append(rows, result.rows...)
+		missing = append(missing, result.missing...)
+		errorCount = errorCount + result.errorCount
+	}
+	sortRows(rows)
+	if *flagReportMissing != "" {
+		if err := writeMissingLicenses(*flagReportMissing, missing); err != nil {
+			return nil, nil, err
+		}
+	}
+	if errorCount > 0 {
+		return rows, missing, fmt.Errorf("Failed to scan licenses for %v module(s)", errorCount)
+	}
+	klog.InfoS("Done: scan licenses of dependencies", "licenseCount", len(rows), "moduleCount", len(mods))
+	return rows, missing, nil
+}
+
+// moduleScanner scans licenses of a module, it's safe for concurrent use.
+type moduleScanner struct {
+	config      *configmodule.GoModLicensesConfig
+	excluded    map[string]bool
+	rewrites    []urlRewrite
+	repoOptions goutils.RepoOptions
+	// patterns of --scan_ignore_file
+	ignorePatterns []string
+	// onScan is called with licenses of each module as soon as it's scanned,
+	// one call at a time, in the order modules finish scanning, unless it's
+	// nil. An error stops scanning.
+	onScan func(rows []licenseRow) error
+}
+
+// moduleScan is the result of scanning a module.
+type moduleScan struct {
+	rows    []licenseRow
+	missing []missingLicense
+	// number of errors that are reported in logs, they don't stop scanning
+	// other modules
+	errorCount int
+}
+
+// scanAll scans modules using a bounded number of concurrent workers.
+// Results are in the same order as mods. Errors that should stop scanning
+// cancel remaining work, and the first one is returned.
+func (s *moduleScanner) scanAll(ctx context.Context, mods []gocli.Module, concurrency int) ([]moduleScan, error) {
+	results := make([]moduleScan, len(mods))
+	var onScanMu sync.Mutex
+	err := forEachConcurrently(ctx, len(mods), concurrency, func(ctx context.Context, i int) error {
+		result, err := s.scan(ctx, mods[i])
+		if err != nil {
+			return err
+		}
+		results[i] = result
+		if s.onScan != nil {
+			onScanMu.Lock()
+			defer onScanMu.Unlock()
+			return s.onScan(result.rows)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// scan scans licenses of a module.
+func (s *moduleScanner) scan(ctx context.Context, goModule gocli.Module) (result moduleScan, err error) {
+	report := func(err error, args ...interface{}) {
+		result.errorCount = result.errorCount + 1
+		errorArgs := []interface{}{"module", goModule.Path}
+		errorArgs = append(errorArgs, args...)
+		klog.ErrorS(err, "Failed", errorArgs...)
+	}
+	if s.excluded[goModule.Path+"@"+goModule.Version] {
+		klog.InfoS("Excluded", "module", goModule.Path, "version", goModule.Version)
+		return result, nil
+	}
+	var override configmodule.ModuleOverride
+	for _, o := range s.config.Module.Overrides {
+		if o.Name == goModule.Path {
+			override = o
+		}
+	}
+	// When override.Version == "", the override apply to any version.
+	if override.Version != "" && override.Version != goModule.Version {
+		report(fmt.Errorf("override version mismatch: found %s, but override is for %s", goModule.Version, override.Version))
+		return result, nil
+	}
+	if override.Skip {
+		klog.InfoS("Skipped", "module", goModule.Path)
+		return result, nil
+	}
+	if goModule.Version == "" && !goModule.Main && goModule.LocalPath == "" && !allowEmptyVersion(goModule.Path) {
+		branch := *flagDefaultBranch
+		if branch == "" {
+			branch = "default"
+		}
+		klog.Warningf("%s: module version is empty, license urls link to the %s branch", goModule.Path, branch)
+	}
+	var repo *ghutils.GitHubRepo
+	var errGetGithubRepo error
+	// whether the source repo cannot be resolved because of network issues,
+	// license urls are left empty then
+	repoUnavailable := false
+	if goModule.LocalPath == "" && !*flagOffline {
+		repo, errGetGithubRepo = goutils.GetGithubRepoWithOptions(goModule.Path, s.repoOptions)
+		if errors.Is(errGetGithubRepo, goutils.ErrGoGetRequest) {
+			klog.Warningf("%s: failed to resolve source repo, license urls are left empty: %v", goModule.Path, errGetGithubRepo)
+			repoUnavailable = true
+		}
+	}
+	// this is not immediately an error, because we might specify override.License.Url below
+	type licenseInfo struct {
+		spdxId        string  // required
+		licensePath   string  // optional, required when url is not supplied
+		url           string  // optional
+		subModulePath string  // optional
+		lineStart     int     // optional
+		lineEnd       int     // optional
+		declared      bool    // optional
+		language      string  // optional
+		confidence    float64 // optional
+		synthetic     bool    // optional, the license is configured without a license file
+		// optional, lines of classified licenses in the file, unlike
+		// lineStart and lineEnd they are not part of the url
+		matchStart int
+		matchEnd   int
+	}
+	hasReportedGetGithubRepoErr := false
+	writeLicenseInfo := func(info licenseInfo) error {
+		if info.spdxId == "" {
+			return fmt.Errorf("failed writeLicenseInfo: info.spdxId required")
+		}
+		url := info.url
+		if url == "" && !info.synthetic && (goModule.LocalPath != "" || !*flagOffline && !repoUnavailable) {
+			if info.licensePath == "" {
+				return fmt.Errorf("failed writeLicenseInfo: info.licensePath required when info.url is empty")
+			}
+			licensePath := info.licensePath
+			if info.subModulePath != "" && info.subModulePath != "." {
+				licensePath = info.subModulePath + "/" + info.licensePath
+			}
+			if goModule.LocalPath != "" {
+				// The module is replaced by a local directory, so there
+				// isn't a remote url. Report the license path relative to
+				// the main module instead.
+				url = path.Join(filepath.ToSlash(goModule.LocalPath), licensePath)
+			} else {
+				if repo == nil && !hasReportedGetGithubRepoErr {
+					// now we need to use repo, so this becomes a fatal error
+					report(errGetGithubRepo)
+					hasReportedGetGithubRepoErr = true // only report once
+					// when repo == nil, repo.RemoteUrl has fallback behavior to use local path,
+					// so keep running to show more information to debug.
+				}
+				url, err = repo.RemoteUrl(ghutils.RemoteUrlArgs{
+					Path:      licensePath,
+					Version:   goModule.Version,
+					LineStart: info.lineStart,
+					LineEnd:   info.lineEnd,
+					// only used when version is empty
+					DefaultBranch: *flagDefaultBranch,
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+		if goModule.LocalPath == "" {
+			url = rewriteUrl(url, s.rewrites)
+		}
+		moduleString := goModule.Path
+		rowPath := info.licensePath
+		lineStart, lineEnd := info.lineStart, info.lineEnd
+		if lineStart == 0 {
+			lineStart, lineEnd = info.matchStart, info.matchEnd
+		}
+		if info.subModulePath != "" {
+			moduleString = moduleString + "/" + info.subModulePath
+			if rowPath != "" {
+				rowPath = info.subModulePath + "/" + rowPath
+			}
+		}
+		result.rows = append(result.rows, licenseRow{
+			Module:     moduleString,
+			Version:    goModule.Version,
+			Url:        url,
+			SpdxId:     info.spdxId,
+			Local:      goModule.LocalPath != "",
+			Declared:   info.declared,
+			GoVersion:  goModule.GoVersion,
+			Language:   info.language,
+			Path:       rowPath,
+			LineStart:  lineStart,
+			LineEnd:    lineEnd,
+			Confidence: info.confidence,
+			Main:       goModule.Main && info.subModulePath == "",
+		})
+		return nil
+	}
+
+	if override.License.SpdxId != "" {
+		license := override.License
+		if license.Path == "" && license.Url == "" {
+			report(fmt.Errorf("At least one of override.license.Path and override.license.Url is required"))
+			return result, nil
+		}
+		klog.V(4).InfoS("License overridden", "module", goModule.Path, "version", goModule.Version, "Dir", goModule.Dir)
+		klog.V(5).InfoS("Override config", "override", fmt.Sprintf("%+v", override))
+		err := writeLicenseInfo(licenseInfo{
+			url:         license.Url,
+			licensePath: license.Path,
+			spdxId:      license.SpdxId,
+			lineStart:   license.LineStart,
+			lineEnd:     license.LineEnd,
+		})
+		if err != nil {
+			return result, err
+		}
+		for _, subModule := range override.SubModules {
+			license := subModule.License
+			if len(subModule.Path) == 0 || len(license.Path) == 0 || len(license.SpdxId) == 0 {
+				report(fmt.Errorf("override.subModule: path, license.path and license.spdxId are required: subModule=%+v", subModule))
+				continue
+			}
+			err := writeLicenseInfo(licenseInfo{
+				url:           license.Url,
+				licensePath:   license.Path,
+				spdxId:        license.SpdxId,
+				lineStart:     license.LineStart,
+				lineEnd:       license.LineEnd,
+				subModulePath: subModule.Path,
+			})
+			if err != nil {
+				return result, err
+			}
+		}
+		return result, nil
+	}
+
+	if *flagSkipLargeModules > 0 {
+		size, err := dirSize(goModule.Dir)
+		if err != nil {
+			report(err)
+			return result, nil
+		}
+		if size > *flagSkipLargeModules*1024*1024 {
+			klog.Warningf("%s: skipped scanning, module folder size %v MiB exceeds --skip_large_modules", goModule.Path, size/1024/1024)
+			return result, nil
+		}
+	}
+	moduleLicense := findModuleLicense(goModule.Path, s.config.Module.Licenses)
+	// useModuleLicense uses the configured license when licenses are not
+	// found by scanning.
+	useModuleLicense := func(cause error) {
+		klog.Warningf("%s: %v, using configured license %s", goModule.Path, cause, moduleLicense.License)
+		err := writeLicenseInfo(licenseInfo{
+			spdxId:    moduleLicense.License,
+			url:       moduleLicense.Url,
+			synthetic: true,
+		})
+		if err != nil {
+			report(err)
+		}
+	}
+	klog.V(4).InfoS("Scanning", "module", goModule.Path, "version", goModule.Version, "Dir", goModule.Dir)
+	scanOptions := licenses.ScanDirOptions{
+		ExcludePaths:        override.ExcludePaths,
+		DbPath:              s.config.Module.LicenseDB.Path,
+		ConfidenceThreshold: override.ConfidenceThreshold,
+		LicenseFilename:     *flagLicenseFilename,
+		TranslationsDbPath:  s.config.Module.LicenseDB.TranslationsPath,
+		IgnoreDirs:          s.config.Module.IgnoreDirs,
+		IgnorePatterns:      s.ignorePatterns,
+		FollowSymlinks:      *flagFollowSymlinks,
+		ScanReadme:          *flagScanReadme,
+	}
+	fileLicenses, err := licenses.ScanDirContext(ctx, goModule.Dir, scanOptions)
+	if err == nil && !hasOwnLicense(fileLicenses) && (goModule.Main || goModule.LocalPath != "") {
+		// Modules in sub folders of a repo often rely on the license at
+		// the repo root. Modules downloaded to the module cache do not
+		// need this, because go adds the repo root LICENSE to them.
+		var parentLicenses []licenses.File
+		parentLicenses, err = scanParentDirs(ctx, goModule.Dir, scanOptions)
+		if len(parentLicenses) > 0 {
+			klog.Warningf("%s: license files not found in the module, using licenses found in %s", goModule.Path, parentLicenses[0].Path)
+			fileLicenses = append(fileLicenses, parentLicenses...)
+		}
+	}
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return result, ctxErr
+		}
+		if moduleLicense != nil {
+			useModuleLicense(err)
+			return result, nil
+		}
+		report(err)
+		return result, nil
+	}
+	if readme := readmeOnlyLicense(fileLicenses); readme != "" {
+		klog.Warningf("%s: license files not found, using licenses found in %s, please verify them manually", goModule.Path, readme)
+	}
+	if len(fileLicenses) == 0 {
+		// As a last resort, use licenses declared by the module.
+		fileLicenses, err = licenses.ScanDeclared(goModule.Dir)
+		if err != nil {
+			report(err)
+			return result, nil
+		}
+		if len(fileLicenses) > 0 {
+			klog.Warningf("%s: license files not found, using licenses declared in %s, please verify them manually", goModule.Path, fileLicenses[0].Path)
+		}
+	}
+	reportMissing := func(err error) {
+		report(err)
+		result.missing = append(result.missing, missingLicense{Module: goModule.Path, Version: goModule.Version, Reason: err.Error(), Dir: goModule.Dir})
+	}
+	ownLicenseFound := hasOwnLicense(fileLicenses)
+	if !ownLicenseFound && moduleLicense != nil {
+		useModuleLicense(errors.Errorf("licenses not found"))
+		// Licenses of vendored dependencies or C libraries are still
+		// reported below.
+	}
+	if len(fileLicenses) == 0 {
+		if moduleLicense == nil {
+			reportMissing(errors.Errorf("licenses not found"))
+		}
+		return result, nil
+	}
+	if !ownLicenseFound && moduleLicense == nil {
+		reportMissing(errors.Errorf("licenses not found, only found licenses of vendored dependencies or C libraries"))
+		return result, nil
+	}
+
+	// sub module path and content hash of license files already reported
+	reportedFiles := make(map[string]bool)
+	for _, file := range fileLicenses {
+		spdxIds := make([]string, 0)
+		for _, license := range file.Licenses {
+			// We need the joinedSpdxId to be deterministic,
+			// because we want to verify found licenses are
+			// the same as what people have verified manually
+			// last time.
+			// If we use map[string]bool, we cannot guarantee
+			// order.
+			// Although slightly inefficient, looping
+			// through the array to find whether a license
+			// is a new found does guarantee we are appending
+			// licenses into the array in a deterministic
+			// order.
+			found := false
+			for _, spdxId := range spdxIds {
+				if license.SpdxId == spdxId {
+					found = true
+				}
+			}
+			if !found {
+				spdxIds = append(spdxIds, license.SpdxId)
+			}
+		}
+		var joinedSpdxId = ""
+		for _, spdxId := range spdxIds {
+			if joinedSpdxId == "" {
+				joinedSpdxId = spdxId
+			} else {
+				joinedSpdxId = joinedSpdxId + " / " + spdxId
+			}
+		}
+		if moduleLicense != nil && !moduleLicense.OnlyWhenNotFound && !file.Vendored && !file.CLibrary {
+			klog.V(2).InfoS("License replaced by config", "module", goModule.Path, "found", joinedSpdxId, "configured", moduleLicense.License, "path", file.Path)
+			joinedSpdxId = moduleLicense.License
+		}
+		klog.V(3).InfoS("License", "module", goModule.Path, "SpdxId", joinedSpdxId, "path", filepath.Join(goModule.Dir, file.Path))
+		info := licenseInfo{
+			spdxId:      joinedSpdxId,
+			licensePath: file.Path,
+			declared:    file.Declared,
+		}
+		for i, license := range file.Licenses {
+			if i == 0 || license.Confidence < info.confidence {
+				info.confidence = license.Confidence
+			}
+			if license.StartLine > 0 && (info.matchStart == 0 || license.StartLine < info.matchStart) {
+				info.matchStart = license.StartLine
+			}
+			if license.EndLine > info.matchEnd {
+				info.matchEnd = license.EndLine
+			}
+		}
+		if len(file.Licenses) > 0 && file.Licenses[0].Language != "" {
+			info.language = file.Licenses[0].Language
+			klog.InfoS("License identified using a translation", "module", goModule.Path, "SpdxId", joinedSpdxId, "language", info.language, "path", file.Path)
+		}
+		if file.Vendored || file.CLibrary {
+			// Attribute licenses of vendored dependencies or C libraries
+			// to a sub module, so they are not confused with the module's
+			// own licenses.
+			info.subModulePath = filepath.ToSlash(filepath.Dir(file.Path))
+			info.licensePath = filepath.Base(file.Path)
+		}
+		// The same license file is often copied, e.g. LICENSE and
+		// LICENSE.txt, only report it once. Other files are reported even
+		// when their licenses are the same, because their copyright notices
+		// may differ.
+		fileKey := info.subModulePath + "\x00" + licenseFileKey(filepath.Join(goModule.Dir, file.Path))
+		if !reportedFiles[fileKey] {
+			reportedFiles[fileKey] = true
+			err := writeLicenseInfo(info)
+			if err != nil {
+				return result, err
+			}
+		} else {
+			klog.V(3).InfoS("Duplicate license file skipped", "module", goModule.Path, "SpdxId", joinedSpdxId, "path", file.Path)
+		}
+		if *flagEvidenceDir != "" {
+			err := writeEvidence(*flagEvidenceDir, goModule, file, s.config.Module.LicenseDB.Path)
+			if err != nil {
+				report(err)
+			}
+		}
+	}
+	return result, nil
+}
+
+// licenseFileKey identifies a license file by the hash of its content, so
+// that copies of a file are identified as the same. Falls back to its path
+// when it cannot be read.
+func licenseFileKey(path string) string {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// writeMissingLicenses writes modules whose licenses are not found to path as
+// a json list.
+func writeMissingLicenses(path string, missing []missingLicense) error {
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, missing); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, buf.Bytes(), permFileCurrentUser); err != nil {
+		return errors.Wrapf(err, "Failed to write %s", path)
+	}
+	klog.InfoS("Reported modules with missing licenses", "path", path, "count", len(missing))
+	return nil
+}
+
+// hasOwnLicense reports whether any of files is a license of the module
+// itself, instead of its vendored dependencies or C libraries.
+func hasOwnLicense(files []licenses.File) bool {
+	for _, file := range files {
+		if !file.Vendored && !file.CLibrary {
+			return true
+		}
+	}
+	return false
+}
+
+// readmeOnlyLicense returns the path of the README that licenses of the
+// module are found in, when they are not found in any other file, otherwise
+// it returns "".
+func readmeOnlyLicense(files []licenses.File) string {
+	readme := ""
+	for _, file := range files {
+		if file.Vendored || file.CLibrary {
+			continue
+		}
+		if !file.Readme {
+			return ""
+		}
+		if readme == "" {
+			readme = file.Path
+		}
+	}
+	return readme
+}
+
+// scanParentDirs scans files directly in parent folders of dir, up to the
+// root of the git repo containing dir, until licenses are found. Paths of
+// returned files are relative to dir, e.g. ../LICENSE.
+func scanParentDirs(ctx context.Context, dir string, options licenses.ScanDirOptions) ([]licenses.File, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	// exclude paths are relative to dir, they do not apply to parent folders
+	options.ExcludePaths = nil
+	options.Shallow = true
+	for parent := dir; !isRepoRoot(parent); {
+		next := filepath.Dir(parent)
+		if next == parent {
+			// reached the filesystem root
+			return nil, nil
+		}
+		parent = next
+		found, err := licenses.ScanDirContext(ctx, parent, options)
+		if err != nil {
+			return nil, err
+		}
+		files := make([]licenses.File, 0, len(found))
+		for _, file := range found {
+			relPath, err := filepath.Rel(dir, filepath.Join(parent, file.Path))
+			if err != nil {
+				return nil, err
+			}
+			file.Path = filepath.ToSlash(relPath)
+			files = append(files, file)
+		}
+		if len(files) > 0 {
+			return files, nil
+		}
+	}
+	return nil, nil
+}
+
+// isRepoRoot reports whether dir is the root of a git repo.
+func isRepoRoot(dir string) bool {
+	// .git is a file in git worktrees and submodules
+	_, err := os.Stat(filepath.Join(dir, ".git"))
+	return err == nil
+}
+
+// dirSize returns total size of files in dir in bytes.
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size = size + info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, errors.Wrapf(err, "Failed to get size of %s", dir)
+	}
+	return size, nil
+}
+
+// useDefaultLicenseDB sets license DB path in config to the default one, when
+// it's not configured.
+func useDefaultLicenseDB(config *configmodule.GoModLicensesConfig) error {
+	if config.Module.LicenseDB.Path == "" {
+		var err error
+		config.Module.LicenseDB.Path, err = defaultLicenseDB()
+		if err != nil {
+			return fmt.Errorf("licenseDB.path is empty, also failed to get defaulut licenseDB path: %w", err)
+		}
+		klog.V(2).InfoS("Config: use default license DB")
+	}
+	klog.V(2).InfoS("Config: license DB path", "path", config.Module.LicenseDB.Path)
+	return nil
+}
+
+// findModuleLicense returns the configured license of a module with the
+// longest matching module path prefix, or nil if there isn't one.
+func findModuleLicense(modulePath string, moduleLicenses []configmodule.ModuleLicense) *configmodule.ModuleLicense {
+	var found *configmodule.ModuleLicense
+	for i := range moduleLicenses {
+		prefix := moduleLicenses[i].Module
+		if modulePath != prefix && !strings.HasPrefix(modulePath, prefix+"/") {
+			continue
+		}
+		if found == nil || len(prefix) > len(found.Module) {
+			found = &moduleLicenses[i]
+		}
+	}
+	return found
+}
+
+// sortRows sorts licenses by module path, then by license path.
+func sortRows(rows []licenseRow) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Module != rows[j].Module {
+			return rows[i].Module < rows[j].Module
+		}
+		return rows[i].Path < rows[j].Path
+	})
+}
+
+// listModules lists dependencies of go packages, or built go binaries when
+// --binary is set.
+func listModules(binaryOrImportPaths []string, config *configmodule.GoModLicensesConfig) (mods []gocli.Module, err error) {
+	if flagBinary != nil && *flagBinary {
+		mods, err = modsFromBinary(binaryOrImportPaths, config)
+	} else {
+		mods, err = gocli.ListDepsWithOptions(listOptions(), binaryOrImportPaths...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	klog.InfoS("Done: found dependencies", "count", len(mods))
+	if klog.V(3).Enabled() {
+		for _, goModule := range mods {
+			klog.InfoS("dependency", "module", goModule.Path, "version", goModule.Version, "Dir", goModule.Dir)
+		}
+	}
+	return mods, nil
+}
+
+// listOptions returns build constraints of dependencies and the module dir
+// from flags.
+func listOptions() gocli.ListOptions {
+	if flagBuildTags == nil {
+		return gocli.ListOptions{Dir: flagDir}
+	}
+	return gocli.ListOptions{
+		BuildTags: *flagBuildTags,
+		GOOS:      *flagGoos,
+		GOARCH:    *flagGoarch,
+		Tests:     *flagIncludeTestDeps,
+		Dir:       flagDir,
+	}
+}
+
+// allowEmptyVersion reports whether a module is expected to have no version,
+// because it matches --allow_empty_version. Prefixes match whole path
+// elements, e.g. github.com/acme matches github.com/acme/tools, but not
+// github.com/acmefoo.
+func allowEmptyVersion(modulePath string) bool {
+	if flagAllowEmptyVersion == nil {
+		return false
+	}
+	for _, prefix := range *flagAllowEmptyVersion {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if modulePath == prefix || strings.HasPrefix(modulePath, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseExcludes parses --exclude flag values in the form of <module>@<version>
+// to a set keyed by the same form.
+func parseExcludes(excludes *[]string) (map[string]bool, error) {
+	set := make(map[string]bool)
+	if excludes == nil {
+		return set, nil
+	}
+	for _, exclude := range *excludes {
+		i := strings.LastIndex(exclude, "@")
+		if i <= 0 || i == len(exclude)-1 {
+			return nil, fmt.Errorf("invalid --exclude %q: expected format <module>@<version>", exclude)
+		}
+		set[exclude] = true
+	}
+	return set, nil
+}
+
+// urlRewrite replaces prefix of a license url with replacement.
+type urlRewrite struct {
+	prefix      string
+	replacement string
+}
+
+// parseBaseUrls parses --base_url flag values in the form of
+// <prefix>=<replacement>.
+func parseBaseUrls(baseUrls *[]string) ([]urlRewrite, error) {
+	rewrites := make([]urlRewrite, 0)
+	if baseUrls == nil {
+		return rewrites, nil
+	}
+	for _, baseUrl := range *baseUrls {
+		parts := strings.SplitN(baseUrl, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid --base_url %q: expected format <prefix>=<replacement>", baseUrl)
+		}
+		rewrites = append(rewrites, urlRewrite{prefix: parts[0], replacement: parts[1]})
+	}
+	return rewrites, nil
+}
+
+// rewriteUrl applies the rewrite with the longest matching prefix to url.
+func rewriteUrl(url string, rewrites []urlRewrite) string {
+	var matched *urlRewrite
+	for i := range rewrites {
+		if strings.HasPrefix(url, rewrites[i].prefix) && (matched == nil || len(rewrites[i].prefix) > len(matched.prefix)) {
+			matched = &rewrites[i]
+		}
+	}
+	if matched == nil {
+		return url
+	}
+	return matched.replacement + strings.TrimPrefix(url, matched.prefix)
+}
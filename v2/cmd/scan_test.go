@@ -0,0 +1,299 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	configmodule "github.com/google/go-licenses/v2/config"
+	"github.com/google/go-licenses/v2/gocli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllowEmptyVersion(t *testing.T) {
+	defer func(allowed *[]string) { flagAllowEmptyVersion = allowed }(flagAllowEmptyVersion)
+	flagAllowEmptyVersion = &[]string{"github.com/acme", "example.com/local/"}
+	tests := []struct {
+		modulePath string
+		want       bool
+	}{
+		{modulePath: "github.com/acme", want: true},
+		{modulePath: "github.com/acme/tools", want: true},
+		{modulePath: "github.com/acmefoo", want: false},
+		{modulePath: "github.com/acmefoo/tools", want: false},
+		{modulePath: "github.com/other/tools", want: false},
+		{modulePath: "example.com/local", want: true},
+		{modulePath: "example.com/local/lib", want: true},
+		{modulePath: "example.com/localfoo", want: false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, allowEmptyVersion(tt.modulePath), "allowEmptyVersion(%q)", tt.modulePath)
+	}
+}
+
+func TestParseBaseUrls(t *testing.T) {
+	tests := []struct {
+		name     string
+		baseUrls *[]string
+		want     []urlRewrite
+		wantErr  bool
+	}{
+		{name: "nil", baseUrls: nil, want: []urlRewrite{}},
+		{
+			name:     "prefixes",
+			baseUrls: &[]string{"https://github.com/=https://mirror.example.com/github/", "https://a.com/=https://b.com/?q=1"},
+			want: []urlRewrite{
+				{prefix: "https://github.com/", replacement: "https://mirror.example.com/github/"},
+				{prefix: "https://a.com/", replacement: "https://b.com/?q=1"},
+			},
+		},
+		{name: "no replacement", baseUrls: &[]string{"https://github.com/"}, wantErr: true},
+		{name: "empty prefix", baseUrls: &[]string{"=https://mirror.example.com/"}, wantErr: true},
+		{name: "empty replacement", baseUrls: &[]string{"https://github.com/="}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseBaseUrls(tt.baseUrls)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRewriteUrl(t *testing.T) {
+	rewrites := []urlRewrite{
+		{prefix: "https://github.com/", replacement: "https://mirror.example.com/github/"},
+		{prefix: "https://github.com/acme/", replacement: "https://git.acme.com/"},
+	}
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{
+			name: "matching prefix",
+			url:  "https://github.com/a/b/blob/v1.0.0/LICENSE",
+			want: "https://mirror.example.com/github/a/b/blob/v1.0.0/LICENSE",
+		},
+		{
+			name: "longest matching prefix",
+			url:  "https://github.com/acme/b/blob/v1.0.0/LICENSE",
+			want: "https://git.acme.com/b/blob/v1.0.0/LICENSE",
+		},
+		{
+			name: "no matching prefix",
+			url:  "https://gitlab.com/a/b/-/blob/v1.0.0/LICENSE",
+			want: "https://gitlab.com/a/b/-/blob/v1.0.0/LICENSE",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, rewriteUrl(tt.url, rewrites))
+		})
+	}
+}
+
+func TestWriteMissingLicenses(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scan_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	tests := []struct {
+		name    string
+		missing []missingLicense
+		want    string
+	}{
+		{name: "none", missing: []missingLicense{}, want: `[]`},
+		{
+			name:    "missing",
+			missing: []missingLicense{{Module: "example.com/a", Version: "v1.0.0", Reason: "licenses not found"}},
+			want:    `[{"module": "example.com/a", "version": "v1.0.0", "reason": "licenses not found"}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".json")
+			require.NoError(t, writeMissingLicenses(path, tt.missing))
+			content, err := ioutil.ReadFile(path)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(content))
+		})
+	}
+}
+
+func TestDirSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scan_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	// writeFiles writes each path as the file's content.
+	writeFiles(t, dir, "a.go", "sub/b.go", "sub/deep/c.go")
+
+	size, err := dirSize(dir)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len("a.go")+len("sub/b.go")+len("sub/deep/c.go")), size)
+
+	_, err = dirSize(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
+
+func TestSortRows(t *testing.T) {
+	rows := []licenseRow{
+		{Module: "example.com/b", Path: "LICENSE"},
+		{Module: "example.com/a", Path: "third_party/LICENSE"},
+		{Module: "example.com/a", Path: "LICENSE"},
+		{Module: "example.com/a/sub", Path: "LICENSE"},
+		{Module: "example.com/c", Url: "https://example.com/c/LICENSE"},
+		{Module: "example.com/c", Url: "https://example.com/c/NOTICE"},
+	}
+	sortRows(rows)
+	assert.Equal(t, []licenseRow{
+		{Module: "example.com/a", Path: "LICENSE"},
+		{Module: "example.com/a", Path: "third_party/LICENSE"},
+		{Module: "example.com/a/sub", Path: "LICENSE"},
+		{Module: "example.com/b", Path: "LICENSE"},
+		// Licenses without paths keep their order.
+		{Module: "example.com/c", Url: "https://example.com/c/LICENSE"},
+		{Module: "example.com/c", Url: "https://example.com/c/NOTICE"},
+	}, rows)
+}
+
+func TestFindModuleLicense(t *testing.T) {
+	moduleLicenses := []configmodule.ModuleLicense{
+		{Module: "example.com/foo", License: "BSD-3-Clause"},
+		{Module: "example.com/foo/bar", License: "MIT"},
+		{Module: "example.com/baz", License: "Apache-2.0"},
+	}
+	tests := []struct {
+		modulePath string
+		want       string
+	}{
+		{modulePath: "example.com/foo", want: "BSD-3-Clause"},
+		{modulePath: "example.com/foo/v2", want: "BSD-3-Clause"},
+		{modulePath: "example.com/foo/bar", want: "MIT"},
+		{modulePath: "example.com/foo/bar/v3", want: "MIT"},
+		{modulePath: "example.com/foobar", want: ""},
+		{modulePath: "example.com/other", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.modulePath, func(t *testing.T) {
+			found := findModuleLicense(tt.modulePath, moduleLicenses)
+			if tt.want == "" {
+				assert.Nil(t, found)
+				return
+			}
+			require.NotNil(t, found)
+			assert.Equal(t, tt.want, found.License)
+		})
+	}
+}
+
+func TestModuleScanner_ScanAll(t *testing.T) {
+	config := &configmodule.GoModLicensesConfig{}
+	mods := make([]gocli.Module, 0)
+	for i := 0; i < 20; i++ {
+		module := fmt.Sprintf("github.com/acme/mod%v", i)
+		mods = append(mods, gocli.Module{Path: module, Version: "v1.0.0"})
+		config.Module.Overrides = append(config.Module.Overrides, configmodule.ModuleOverride{
+			Name:    module,
+			License: configmodule.LicenseOverride{SpdxId: "MIT", Url: "https://" + module + "/LICENSE"},
+		})
+	}
+	scanner := &moduleScanner{config: config}
+
+	results, err := scanner.scanAll(context.Background(), mods, 3)
+	require.NoError(t, err)
+	require.Len(t, results, len(mods))
+	for i, result := range results {
+		// Results are in the same order as modules.
+		assert.Equal(t, []licenseRow{{Module: mods[i].Path, Version: "v1.0.0", Url: "https://" + mods[i].Path + "/LICENSE", SpdxId: "MIT"}}, result.rows)
+		assert.Equal(t, 0, result.errorCount)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = scanner.scanAll(ctx, mods, 3)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestModuleScanner_ScanAll_OnScan(t *testing.T) {
+	config := &configmodule.GoModLicensesConfig{}
+	mods := make([]gocli.Module, 0)
+	for i := 0; i < 3; i++ {
+		module := fmt.Sprintf("github.com/acme/mod%v", i)
+		mods = append(mods, gocli.Module{Path: module, Version: "v1.0.0"})
+		config.Module.Overrides = append(config.Module.Overrides, configmodule.ModuleOverride{
+			Name:    module,
+			License: configmodule.LicenseOverride{SpdxId: "MIT", Url: "https://" + module + "/LICENSE"},
+		})
+	}
+	scanned := make(chan []licenseRow)
+	scanner := &moduleScanner{config: config, onScan: func(rows []licenseRow) error {
+		scanned <- rows
+		return nil
+	}}
+
+	done := make(chan error)
+	go func() {
+		_, err := scanner.scanAll(context.Background(), mods, 1)
+		done <- err
+	}()
+	for i := range mods {
+		// Each module is reported while the scan is still running.
+		select {
+		case rows := <-scanned:
+			assert.Equal(t, []licenseRow{{Module: mods[i].Path, Version: "v1.0.0", Url: "https://" + mods[i].Path + "/LICENSE", SpdxId: "MIT"}}, rows)
+		case err := <-done:
+			t.Fatalf("scanAll() = %v before module %v is reported", err, mods[i].Path)
+		}
+	}
+	require.NoError(t, <-done)
+
+	errFailed := errors.New("failed")
+	scanner.onScan = func(rows []licenseRow) error { return errFailed }
+	_, err := scanner.scanAll(context.Background(), mods, 1)
+	assert.Equal(t, errFailed, err)
+}
+
+func TestLicenseFileKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scan_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"LICENSE":     "MIT License\n\nCopyright (c) 2021 Alice\n",
+		"LICENSE.txt": "MIT License\n\nCopyright (c) 2021 Alice\n",
+		"COPYING":     "MIT License\n\nCopyright (c) 2021 Bob\n",
+	}
+	for name, content := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	key := licenseFileKey(filepath.Join(dir, "LICENSE"))
+	// copies of a license file are the same
+	assert.Equal(t, key, licenseFileKey(filepath.Join(dir, "LICENSE.txt")))
+	// the same license with another copyright notice is not
+	assert.NotEqual(t, key, licenseFileKey(filepath.Join(dir, "COPYING")))
+	// files that cannot be read are identified by path
+	assert.Equal(t, filepath.Join(dir, "missing"), licenseFileKey(filepath.Join(dir, "sub", "..", "missing")))
+}
@@ -0,0 +1,242 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	configmodule "github.com/google/go-licenses/v2/config"
+	"github.com/google/go-licenses/v2/licenses"
+)
+
+// SPDX 2.3 document.
+// Reference: https://spdx.github.io/spdx-spec/v2.3/
+type spdxDocument struct {
+	SpdxVersion       string                 `json:"spdxVersion"`
+	DataLicense       string                 `json:"dataLicense"`
+	SpdxId            string                 `json:"SPDXID"`
+	Name              string                 `json:"name"`
+	DocumentNamespace string                 `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo       `json:"creationInfo"`
+	Packages          []spdxPackage          `json:"packages"`
+	Relationships     []spdxRelationship     `json:"relationships"`
+	ExtractedLicenses []spdxExtractedLicense `json:"hasExtractedLicensingInfos,omitempty"`
+}
+
+type spdxCreationInfo struct {
+	Created  time.Time `json:"created"`
+	Creators []string  `json:"creators"`
+}
+
+type spdxPackage struct {
+	SpdxId           string            `json:"SPDXID"`
+	Name             string            `json:"name"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	LicenseConcluded string            `json:"licenseConcluded"`
+	LicenseDeclared  string            `json:"licenseDeclared"`
+	CopyrightText    string            `json:"copyrightText"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SpdxElementId      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSpdxElement string `json:"relatedSpdxElement"`
+}
+
+// spdxExtractedLicense is a license not in the SPDX license list, e.g.
+// LicenseRef-Acme-Commercial.
+type spdxExtractedLicense struct {
+	LicenseId     string `json:"licenseId"`
+	ExtractedText string `json:"extractedText"`
+}
+
+const (
+	spdxDocumentId  = "SPDXRef-DOCUMENT"
+	spdxNoAssertion = "NOASSERTION"
+)
+
+// spdxIdInvalidChars matches characters not allowed in SPDX element IDs.
+var spdxIdInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9.-]`)
+
+// spdx generates an SPDX document with a package for each module. The
+// document describes the main module, which depends on all other modules.
+// When the main module is unknown, the document describes all modules.
+func spdx(modules []reportModule, mainModule string, cfg configmodule.LicensesConfig) (*spdxDocument, error) {
+	serialNumber, err := uuid()
+	if err != nil {
+		return nil, err
+	}
+	name := mainModule
+	if name == "" {
+		name = "go-licenses"
+	}
+	doc := &spdxDocument{
+		SpdxVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SpdxId:            spdxDocumentId,
+		Name:              name,
+		DocumentNamespace: "https://spdx.org/spdxdocs/" + spdxIdInvalidChars.ReplaceAllString(name, "-") + "-" + serialNumber,
+		CreationInfo: spdxCreationInfo{
+			Created:  time.Now().UTC().Truncate(time.Second),
+			Creators: []string{"Tool: go-licenses-" + goLicensesVersion()},
+		},
+		Packages:      make([]spdxPackage, 0, len(modules)),
+		Relationships: make([]spdxRelationship, 0),
+	}
+	usedIds := make(map[string]bool)
+	extracted := make(map[string]bool)
+	mainId := ""
+	for _, module := range modules {
+		id := "SPDXRef-Package-" + spdxIdInvalidChars.ReplaceAllString(module.Module, "-")
+		for i := 2; usedIds[id]; i++ {
+			id = fmt.Sprintf("SPDXRef-Package-%s-%d", spdxIdInvalidChars.ReplaceAllString(module.Module, "-"), i)
+		}
+		usedIds[id] = true
+		license := spdxLicenseExpression(module, cfg)
+		doc.Packages = append(doc.Packages, spdxPackage{
+			SpdxId:           id,
+			Name:             module.Module,
+			VersionInfo:      module.Version,
+			DownloadLocation: spdxNoAssertion,
+			LicenseConcluded: license,
+			LicenseDeclared:  license,
+			CopyrightText:    spdxNoAssertion,
+			ExternalRefs: []spdxExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  purl(module.Module, module.Version),
+			}},
+		})
+		if license != spdxNoAssertion {
+			for _, l := range module.Licenses {
+				if strings.HasPrefix(l.SpdxId, "LicenseRef-") && !extracted[l.SpdxId] {
+					extracted[l.SpdxId] = true
+					doc.ExtractedLicenses = append(doc.ExtractedLicenses, spdxExtractedLicense{
+						LicenseId:     l.SpdxId,
+						ExtractedText: "The license text is not included, see " + l.Url,
+					})
+				}
+			}
+		}
+		if module.Module == mainModule {
+			mainId = id
+		}
+	}
+	if mainId != "" {
+		doc.Relationships = append(doc.Relationships, spdxRelationship{spdxDocumentId, "DESCRIBES", mainId})
+	}
+	for _, pkg := range doc.Packages {
+		switch {
+		case mainId == "":
+			doc.Relationships = append(doc.Relationships, spdxRelationship{spdxDocumentId, "DESCRIBES", pkg.SpdxId})
+		case pkg.SpdxId != mainId:
+			doc.Relationships = append(doc.Relationships, spdxRelationship{mainId, "DEPENDS_ON", pkg.SpdxId})
+		}
+	}
+	return doc, nil
+}
+
+// spdxLicenseExpression returns licenses of a module as an SPDX license
+// expression, licenses found in different files all apply, so they are
+// joined by AND. Licenses that are expressions themselves, e.g.
+// "Apache-2.0 OR MIT", are enclosed in parentheses. Returns NOASSERTION when
+// the type of any license in them is unknown, or a license is not a valid
+// expression.
+func spdxLicenseExpression(module reportModule, cfg configmodule.LicensesConfig) string {
+	expressions := make([]*licenses.Expression, 0, len(module.Licenses))
+	seen := make(map[string]bool)
+	for _, license := range module.Licenses {
+		// Strict SPDX validators reject deprecated IDs.
+		expression, err := licenses.ParseExpression(licenses.NormalizeSpdxIds(license.SpdxId))
+		if err != nil {
+			return spdxNoAssertion
+		}
+		for _, spdxId := range expression.SpdxIds() {
+			if licenseType(spdxId, cfg) == "" {
+				return spdxNoAssertion
+			}
+		}
+		if seen[expression.String()] {
+			continue
+		}
+		seen[expression.String()] = true
+		expressions = append(expressions, expression)
+	}
+	switch len(expressions) {
+	case 0:
+		return spdxNoAssertion
+	case 1:
+		return expressions[0].String()
+	default:
+		return (&licenses.Expression{Operator: licenses.OperatorAnd, Operands: expressions}).String()
+	}
+}
+
+// writeSpdxTagValue writes an SPDX document in the tag-value format.
+func writeSpdxTagValue(w io.Writer, doc *spdxDocument) error {
+	bw := bufio.NewWriter(w)
+	line := func(tag string, value string) {
+		fmt.Fprintf(bw, "%s: %s\n", tag, value)
+	}
+	line("SPDXVersion", doc.SpdxVersion)
+	line("DataLicense", doc.DataLicense)
+	line("SPDXID", doc.SpdxId)
+	line("DocumentName", doc.Name)
+	line("DocumentNamespace", doc.DocumentNamespace)
+	for _, creator := range doc.CreationInfo.Creators {
+		line("Creator", creator)
+	}
+	line("Created", doc.CreationInfo.Created.Format(time.RFC3339))
+	for _, pkg := range doc.Packages {
+		fmt.Fprintln(bw)
+		line("PackageName", pkg.Name)
+		line("SPDXID", pkg.SpdxId)
+		if pkg.VersionInfo != "" {
+			line("PackageVersion", pkg.VersionInfo)
+		}
+		line("PackageDownloadLocation", pkg.DownloadLocation)
+		line("FilesAnalyzed", fmt.Sprint(pkg.FilesAnalyzed))
+		line("PackageLicenseConcluded", pkg.LicenseConcluded)
+		line("PackageLicenseDeclared", pkg.LicenseDeclared)
+		line("PackageCopyrightText", pkg.CopyrightText)
+		for _, ref := range pkg.ExternalRefs {
+			line("ExternalRef", strings.Join([]string{ref.ReferenceCategory, ref.ReferenceType, ref.ReferenceLocator}, " "))
+		}
+	}
+	fmt.Fprintln(bw)
+	for _, relationship := range doc.Relationships {
+		line("Relationship", strings.Join([]string{relationship.SpdxElementId, relationship.RelationshipType, relationship.RelatedSpdxElement}, " "))
+	}
+	for _, license := range doc.ExtractedLicenses {
+		fmt.Fprintln(bw)
+		line("LicenseID", license.LicenseId)
+		line("ExtractedText", "<text>"+license.ExtractedText+"</text>")
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,77 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"testing"
+
+	configmodule "github.com/google/go-licenses/v2/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpdxLicenseExpression(t *testing.T) {
+	var cfg configmodule.LicensesConfig
+	cfg.Types.Overrides = []configmodule.LicenseTypeOverride{{SpdxId: "LicenseRef-Acme", Type: "notice"}}
+	tests := []struct {
+		name     string
+		licenses []string
+		want     string
+	}{
+		{name: "single license", licenses: []string{"MIT"}, want: "MIT"},
+		{name: "deprecated ID", licenses: []string{"GPL-2.0"}, want: "GPL-2.0-only"},
+		{name: "deprecated ID with exception", licenses: []string{"GPL-2.0-with-classpath-exception", "MIT"}, want: "GPL-2.0-only WITH Classpath-exception-2.0 AND MIT"},
+		{name: "licenses in several files", licenses: []string{"MIT", "Apache-2.0"}, want: "MIT AND Apache-2.0"},
+		{name: "duplicate licenses", licenses: []string{"MIT", "MIT"}, want: "MIT"},
+		{name: "expression", licenses: []string{"Apache-2.0 OR MIT"}, want: "Apache-2.0 OR MIT"},
+		{name: "expression and license", licenses: []string{"Apache-2.0 OR MIT", "BSD-3-Clause"}, want: "(Apache-2.0 OR MIT) AND BSD-3-Clause"},
+		{name: "license ref of a configured type", licenses: []string{"LicenseRef-Acme"}, want: "LicenseRef-Acme"},
+		{name: "unknown license", licenses: []string{"MIT", "Unknown"}, want: spdxNoAssertion},
+		{name: "unknown license in expression", licenses: []string{"MIT OR LicenseRef-Other"}, want: spdxNoAssertion},
+		{name: "invalid expression", licenses: []string{"MIT OR"}, want: spdxNoAssertion},
+		{name: "no licenses", want: spdxNoAssertion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := reportModule{Module: "example.com/m"}
+			for _, spdxId := range tt.licenses {
+				module.Licenses = append(module.Licenses, reportLicense{SpdxId: spdxId})
+			}
+			assert.Equal(t, tt.want, spdxLicenseExpression(module, cfg))
+		})
+	}
+}
+
+func TestSpdx(t *testing.T) {
+	modules := []reportModule{
+		{Module: "example.com/main", Version: "", Licenses: []reportLicense{{SpdxId: "Apache-2.0"}}},
+		{Module: "example.com/dep", Version: "v1.0.0", Licenses: []reportLicense{{SpdxId: "Apache-2.0 OR MIT"}, {SpdxId: "BSD-3-Clause"}}},
+	}
+	doc, err := spdx(modules, "example.com/main", configmodule.LicensesConfig{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	licenses := make(map[string]string)
+	for _, pkg := range doc.Packages {
+		licenses[pkg.Name] = pkg.LicenseConcluded
+	}
+	assert.Equal(t, map[string]string{
+		"example.com/main": "Apache-2.0",
+		"example.com/dep":  "(Apache-2.0 OR MIT) AND BSD-3-Clause",
+	}, licenses)
+	assert.Equal(t, []spdxRelationship{
+		{spdxDocumentId, "DESCRIBES", "SPDXRef-Package-example.com-main"},
+		{"SPDXRef-Package-example.com-main", "DEPENDS_ON", "SPDXRef-Package-example.com-dep"},
+	}, doc.Relationships)
+}
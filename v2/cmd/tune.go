@@ -0,0 +1,163 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	configmodule "github.com/google/go-licenses/v2/config"
+	"github.com/google/go-licenses/v2/licenses"
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+)
+
+// flag variables
+var tuneThresholds []float64 // confidence thresholds to compare
+
+// tuneCmd represents the tune command
+var tuneCmd = &cobra.Command{
+	Use:   "tune {<package>, --binary <binary_path>}",
+	Short: "Compare license classification results across confidence thresholds",
+	Long: `"go-licenses tune" scans licenses of dependencies once, and reports how identified
+and unidentified licenses change across a range of confidence thresholds. A lower
+threshold may identify licenses by mistake, while a higher threshold may fail to
+identify licenses with unusual formatting. Use the report to choose confidenceThreshold
+of module overrides in go-licenses.yaml.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runE(func(cmd *cobra.Command, args []string) error {
+		return tuneImp(args[0], tuneThresholds)
+	}),
+}
+
+func init() {
+	rootCmd.AddCommand(tuneCmd)
+	addScanFlags(tuneCmd)
+	tuneCmd.Flags().Float64SliceVar(&tuneThresholds, "thresholds", []float64{0.8, 0.85, 0.9, 0.95, 0.99}, "comma separated confidence thresholds to compare")
+}
+
+// moduleMatches are licenses found in a module at the lowest threshold.
+type moduleMatches struct {
+	Module string
+	Found  []licenses.Found
+}
+
+func tuneImp(binaryOrImportPath string, thresholds []float64) error {
+	if len(thresholds) == 0 {
+		return usageError(fmt.Errorf("--thresholds must not be empty"))
+	}
+	for _, threshold := range thresholds {
+		if threshold <= 0 || threshold > 1 {
+			return usageError(fmt.Errorf("invalid --thresholds %v: thresholds must be between 0 and 1", threshold))
+		}
+	}
+	thresholds = append([]float64{}, thresholds...)
+	sort.Float64s(thresholds)
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if err := useDefaultLicenseDB(config); err != nil {
+		return err
+	}
+	mods, err := listModules([]string{binaryOrImportPath}, config)
+	if err != nil {
+		return err
+	}
+	// Scan once at the lowest threshold, results at higher thresholds are the
+	// matches with enough confidence.
+	scanned := make([]moduleMatches, 0, len(mods))
+	for _, goModule := range mods {
+		var override configmodule.ModuleOverride
+		for _, o := range config.Module.Overrides {
+			if o.Name == goModule.Path {
+				override = o
+			}
+		}
+		if override.Skip || override.License.SpdxId != "" {
+			// Licenses of these modules do not depend on thresholds.
+			continue
+		}
+		files, err := licenses.ScanDir(goModule.Dir, licenses.ScanDirOptions{
+			ExcludePaths:        override.ExcludePaths,
+			DbPath:              config.Module.LicenseDB.Path,
+			ConfidenceThreshold: thresholds[0],
+			LicenseFilename:     *flagLicenseFilename,
+			TranslationsDbPath:  config.Module.LicenseDB.TranslationsPath,
+		})
+		if err != nil {
+			klog.ErrorS(err, "Failed", "module", goModule.Path)
+			continue
+		}
+		matches := moduleMatches{Module: goModule.Path}
+		for _, file := range files {
+			matches.Found = append(matches.Found, file.Licenses...)
+		}
+		scanned = append(scanned, matches)
+	}
+	fmt.Fprintf(os.Stdout, "threshold, identified modules, unidentified modules, licenses\n")
+	for _, threshold := range thresholds {
+		identified, unidentified, count := classifyAt(scanned, threshold)
+		fmt.Fprintf(os.Stdout, "%v, %v, %v, %v\n", threshold, len(identified), len(unidentified), count)
+	}
+	// Report modules that become unidentified as the threshold increases, so
+	// users know which modules to look at.
+	_, previous, _ := classifyAt(scanned, thresholds[0])
+	for _, threshold := range thresholds[1:] {
+		_, unidentified, _ := classifyAt(scanned, threshold)
+		lost := make([]string, 0)
+		for _, module := range unidentified {
+			if !contains(previous, module) {
+				lost = append(lost, module)
+			}
+		}
+		if len(lost) > 0 {
+			fmt.Fprintf(os.Stdout, "# unidentified at %v: %s\n", threshold, strings.Join(lost, ", "))
+		}
+		previous = unidentified
+	}
+	return nil
+}
+
+// classifyAt returns modules with and without licenses identified at
+// threshold, and the number of licenses identified.
+func classifyAt(scanned []moduleMatches, threshold float64) (identified []string, unidentified []string, count int) {
+	for _, matches := range scanned {
+		found := 0
+		for _, license := range matches.Found {
+			if license.Confidence >= threshold {
+				found = found + 1
+			}
+		}
+		if found > 0 {
+			identified = append(identified, matches.Module)
+		} else {
+			unidentified = append(unidentified, matches.Module)
+		}
+		count = count + found
+	}
+	return identified, unidentified, count
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
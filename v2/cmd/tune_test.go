@@ -0,0 +1,65 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/google/go-licenses/v2/licenses"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyAt(t *testing.T) {
+	scanned := []moduleMatches{
+		{Module: "example.com/a", Found: []licenses.Found{{SpdxId: "MIT", Confidence: 0.99}, {SpdxId: "Apache-2.0", Confidence: 0.85}}},
+		{Module: "example.com/b", Found: []licenses.Found{{SpdxId: "BSD-3-Clause", Confidence: 0.9}}},
+		{Module: "example.com/c"},
+	}
+	tests := []struct {
+		threshold    float64
+		identified   []string
+		unidentified []string
+		count        int
+	}{
+		{threshold: 0.8, identified: []string{"example.com/a", "example.com/b"}, unidentified: []string{"example.com/c"}, count: 3},
+		{threshold: 0.9, identified: []string{"example.com/a", "example.com/b"}, unidentified: []string{"example.com/c"}, count: 2},
+		{threshold: 0.95, identified: []string{"example.com/a"}, unidentified: []string{"example.com/b", "example.com/c"}, count: 1},
+		{threshold: 1, identified: nil, unidentified: []string{"example.com/a", "example.com/b", "example.com/c"}, count: 0},
+	}
+	for _, tt := range tests {
+		identified, unidentified, count := classifyAt(scanned, tt.threshold)
+		assert.Equal(t, tt.identified, identified, "identified at %v", tt.threshold)
+		assert.Equal(t, tt.unidentified, unidentified, "unidentified at %v", tt.threshold)
+		assert.Equal(t, tt.count, count, "count at %v", tt.threshold)
+	}
+}
+
+func TestTuneImp_InvalidThresholds(t *testing.T) {
+	tests := []struct {
+		name       string
+		thresholds []float64
+		want       string
+	}{
+		{name: "empty", thresholds: nil, want: "--thresholds must not be empty"},
+		{name: "zero", thresholds: []float64{0.9, 0}, want: "invalid --thresholds 0: thresholds must be between 0 and 1"},
+		{name: "above one", thresholds: []float64{1.5}, want: "invalid --thresholds 1.5: thresholds must be between 0 and 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tuneImp("example.com/main", tt.thresholds)
+			assert.EqualError(t, err, tt.want)
+		})
+	}
+}
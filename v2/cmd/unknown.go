@@ -0,0 +1,103 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	configmodule "github.com/google/go-licenses/v2/config"
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+)
+
+// unknownCmd represents the unknown command
+var unknownCmd = &cobra.Command{
+	Use:   "unknown {<package>, --binary <binary_path>}",
+	Short: "List dependency modules whose licenses are not found or of unknown types",
+	Long: `"go-licenses unknown" scans licenses the same way as "go-licenses csv", and only
+lists modules whose licenses are not found, or whose licenses are of unknown types,
+with the file or folder that was examined. Use it as a worklist of modules to
+configure in go-licenses.yaml.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runE(func(cmd *cobra.Command, args []string) error {
+		return unknownImp(context.Background(), args[0])
+	}),
+}
+
+func init() {
+	rootCmd.AddCommand(unknownCmd)
+	addScanFlags(unknownCmd)
+}
+
+// unknownLicense is a module whose license is not found or of an unknown type.
+type unknownLicense struct {
+	Module string
+	// license file or url of licenses of unknown types, or the module dir
+	// when licenses are not found
+	Path   string
+	Reason string
+}
+
+func unknownImp(ctx context.Context, binaryOrImportPath string) error {
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	rows, missing, scanErr := scanLicensesWithMissing(ctx, []string{binaryOrImportPath}, config)
+	if rows == nil {
+		return scanErr
+	}
+	unknown := unknownLicenses(rows, missing, config.Licenses)
+	for _, license := range unknown {
+		fmt.Printf("%s, %s, %s\n", license.Module, license.Path, license.Reason)
+	}
+	klog.InfoS("Done: list unknown licenses", "moduleCount", len(unknown))
+	// Modules whose licenses are not found are also scan errors.
+	return scanErr
+}
+
+// unknownLicenses returns modules in missing, and licenses in rows of unknown
+// types, sorted by module.
+func unknownLicenses(rows []licenseRow, missing []missingLicense, cfg configmodule.LicensesConfig) []unknownLicense {
+	unknown := make([]unknownLicense, 0)
+	for _, module := range missing {
+		unknown = append(unknown, unknownLicense{Module: module.Module, Path: module.Dir, Reason: module.Reason})
+	}
+	for _, row := range rows {
+		spdxIds := make([]string, 0)
+		for _, part := range strings.Split(row.SpdxId, "/") {
+			if spdxId := strings.TrimSpace(part); licenseType(spdxId, cfg) == "" {
+				spdxIds = append(spdxIds, spdxId)
+			}
+		}
+		if len(spdxIds) == 0 {
+			continue
+		}
+		path := row.Path
+		if path == "" {
+			path = row.Url
+		}
+		unknown = append(unknown, unknownLicense{
+			Module: row.Module,
+			Path:   path,
+			Reason: fmt.Sprintf("unknown license type of %s", strings.Join(spdxIds, " / ")),
+		})
+	}
+	sort.SliceStable(unknown, func(i, j int) bool { return unknown[i].Module < unknown[j].Module })
+	return unknown
+}
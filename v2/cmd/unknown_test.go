@@ -0,0 +1,50 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"testing"
+
+	configmodule "github.com/google/go-licenses/v2/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnknownLicenses(t *testing.T) {
+	rows := []licenseRow{
+		{Module: "example.com/mit", SpdxId: "MIT", Path: "/mod/mit/LICENSE"},
+		{Module: "example.com/custom", SpdxId: "MIT / LicenseRef-Custom", Path: "/mod/custom/LICENSE"},
+		{Module: "example.com/override", SpdxId: "LicenseRef-Override", Url: "https://example.com/override/LICENSE"},
+	}
+	missing := []missingLicense{
+		{Module: "example.com/missing", Reason: "license not found", Dir: "/mod/missing"},
+	}
+
+	unknown := unknownLicenses(rows, missing, configmodule.LicensesConfig{})
+
+	assert.Equal(t, []unknownLicense{
+		{Module: "example.com/custom", Path: "/mod/custom/LICENSE", Reason: "unknown license type of LicenseRef-Custom"},
+		{Module: "example.com/missing", Path: "/mod/missing", Reason: "license not found"},
+		// url is reported when the license file is unknown
+		{Module: "example.com/override", Path: "https://example.com/override/LICENSE", Reason: "unknown license type of LicenseRef-Override"},
+	}, unknown)
+}
+
+func TestUnknownLicenses_TypeOverride(t *testing.T) {
+	cfg := configmodule.LicensesConfig{}
+	cfg.Types.Overrides = []configmodule.LicenseTypeOverride{{SpdxId: "LicenseRef-Custom", Type: "notice"}}
+	rows := []licenseRow{{Module: "example.com/custom", SpdxId: "LicenseRef-Custom"}}
+
+	assert.Empty(t, unknownLicenses(rows, nil, cfg))
+}
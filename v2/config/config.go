@@ -17,6 +17,7 @@ package config
 import (
 	"fmt"
 	"io/ioutil"
+	"path/filepath"
 
 	"github.com/google/licenseclassifier"
 	"github.com/pkg/errors"
@@ -28,12 +29,31 @@ type GoModLicensesConfig struct {
 		LicenseDB LicenseDB        `yaml:"licenseDB"`
 		Go        GoModuleConfig   `yaml:"go"`
 		Overrides []ModuleOverride `yaml:"overrides"`
+		// optional, github repos of vanity import paths that cannot be
+		// resolved correctly, e.g. gopkg.in/yaml.v2
+		VanityImports []VanityImport `yaml:"vanityImports"`
+		// optional, licenses of modules matched by path prefix, used when
+		// licenses are misdetected or not found
+		Licenses []ModuleLicense `yaml:"licenses"`
+		// optional, names of folders not scanned in modules, e.g. examples.
+		// They replace the defaults .git and node_modules when specified.
+		IgnoreDirs []string `yaml:"ignoreDirs"`
+		// optional, host patterns the save command may download license
+		// texts from, e.g. github.com or *.googlesource.com. When specified,
+		// license urls of other hosts fail the command before they are
+		// downloaded, so that a compromised csv cannot make it reach out to
+		// unexpected domains. All hosts are allowed by default.
+		AllowedLicenseHosts []string `yaml:"allowedLicenseHosts"`
 	} `yaml:"module"`
 	Licenses LicensesConfig `yaml:"licenses"`
 }
 
 type LicenseDB struct {
 	Path string `yaml:"path"`
+	// optional, a folder of translated license texts, organized as
+	// <language>/<SPDX ID>.txt, e.g. de/MIT.txt. They are matched against
+	// license files that cannot be classified using English license texts.
+	TranslationsPath string `yaml:"translationsPath"`
 }
 
 type GoModuleConfig struct {
@@ -41,6 +61,28 @@ type GoModuleConfig struct {
 	Version string `yaml:"version"` // main module version, e.g. master (defaults to main)
 }
 
+type VanityImport struct {
+	Prefix string `yaml:"prefix"` // required, module path prefix, e.g. gopkg.in/yaml.v2
+	Repo   string `yaml:"repo"`   // required, github repo of modules with the prefix, e.g. github.com/go-yaml/yaml
+}
+
+type ModuleLicense struct {
+	// required, module path prefix, matched at path segment boundaries, so
+	// that a whole tree of modules can be covered, e.g. example.com/foo
+	// matches example.com/foo and example.com/foo/bar. The longest prefix wins.
+	Module  string `yaml:"module"`
+	License string `yaml:"license"` // required, SPDX ID of the license
+	// optional, type of the license, it overrides the type of the SPDX ID
+	// like licenses.types.overrides
+	Type string `yaml:"type"`
+	// optional, license url, required by the save command when no license
+	// file is found
+	Url string `yaml:"url"`
+	// optional, only use the license when scanning does not find licenses,
+	// otherwise it replaces licenses found in the module's own license files
+	OnlyWhenNotFound bool `yaml:"onlyWhenNotFound"`
+}
+
 type ModuleOverride struct {
 	Name string `yaml:"name"`
 	// optional, if specified, the override is pinned to a version. After an
@@ -50,6 +92,19 @@ type ModuleOverride struct {
 	License      LicenseOverride `yaml:"license"`    // required, license of root module
 	SubModules   []SubModule     `yaml:"subModules"` // optional, specify if sub modules have a different license
 	ExcludePaths []string        `yaml:"excludePaths"`
+	// optional, minimum confidence to identify a license in this module,
+	// overrides the default when license files have unusual formatting.
+	ConfidenceThreshold float64 `yaml:"confidenceThreshold"`
+	// required when confidenceThreshold is specified, explain why it's needed.
+	Justification string `yaml:"justification"`
+	// optional, url where source code of the module is redistributed through
+	// another channel, e.g. a public mirror maintained by you. When specified,
+	// the save command does not copy source code of the module even if its
+	// license requires source redistribution, the url is recorded instead.
+	ExternalSource string `yaml:"externalSource"`
+	// optional, special obligations of the module's license, e.g. a reference
+	// to the agreement of a commercial license. The save command records them.
+	Obligations string `yaml:"obligations"`
 }
 
 type LicenseOverride struct {
@@ -72,13 +127,53 @@ type LicenseTypeOverride struct {
 }
 
 type LicensesConfig struct {
-	Types LicenseTypes `yaml:"types"`
+	Types      LicenseTypes       `yaml:"types"`
+	Commercial CommercialLicenses `yaml:"commercial"`
+	Policy     Policy             `yaml:"policy"`
+}
+
+// Policy decides which licenses fail the check command, in addition to its
+// flags. License types are names like Forbidden, Restricted or Unknown.
+type Policy struct {
+	// optional, when specified, licenses of other types fail the check
+	AllowedTypes []string `yaml:"allowedTypes"`
+	// optional, licenses of these types fail the check
+	DisallowedTypes []string `yaml:"disallowedTypes"`
+	// optional, SPDX IDs of licenses that never fail the check
+	AllowedLicenses []string `yaml:"allowedLicenses"`
+	// optional, SPDX IDs of licenses that always fail the check, they take
+	// precedence over allowedLicenses
+	DisallowedLicenses []string `yaml:"disallowedLicenses"`
+}
+
+// LicenseTypeCommercial is the type of proprietary or commercial licenses. The
+// classifier does not detect them, configure it as a license type override.
+const LicenseTypeCommercial = "commercial"
+
+type CommercialLicenses struct {
+	// optional, when true, commercial licenses are tracked instead of failing
+	// the check and save commands, e.g. when your organization has licensed them
+	Allowed bool `yaml:"allowed"`
 }
 
 type LicenseTypes struct {
 	Overrides []LicenseTypeOverride `yaml:"overrides"`
+	// optional, compliance requirements of license types keyed by type name,
+	// e.g. {internal: DistributeNotice}. They take precedence over
+	// requirements of built-in types, and types in overrides may be any type
+	// configured here.
+	Requirements map[string]string `yaml:"requirements"`
 }
 
+// Compliance requirements of license types configured in
+// LicenseTypes.Requirements.
+const (
+	// redistribute the entire source code of modules
+	RequirementDistributeSource = "DistributeSource"
+	// redistribute full license texts and copyright notices
+	RequirementDistributeNotice = "DistributeNotice"
+)
+
 const (
 	DefaultConfigPath = "go-licenses.yaml"
 )
@@ -107,12 +202,58 @@ func Load(path string) (config *GoModLicensesConfig, err error) {
 	if config.Module.Go.Version == "" {
 		config.Module.Go.Version = "main"
 	}
+	for i, override := range config.Module.Overrides {
+		if override.ConfidenceThreshold == 0 {
+			continue
+		}
+		if override.ConfidenceThreshold < 0 || override.ConfidenceThreshold > 1 {
+			return nil, fmt.Errorf("config.module.overrides[%v]: confidenceThreshold of %s must be between 0 and 1, got %v", i, override.Name, override.ConfidenceThreshold)
+		}
+		if override.Justification == "" {
+			return nil, fmt.Errorf("config.module.overrides[%v]: justification of %s is required when confidenceThreshold is specified", i, override.Name)
+		}
+	}
+	for i, vanityImport := range config.Module.VanityImports {
+		if vanityImport.Prefix == "" || vanityImport.Repo == "" {
+			return nil, fmt.Errorf("config.module.vanityImports[%v]: prefix and repo are required", i)
+		}
+	}
+	for i, pattern := range config.Module.AllowedLicenseHosts {
+		if pattern == "" {
+			return nil, fmt.Errorf("config.module.allowedLicenseHosts[%v]: host pattern must be non empty", i)
+		}
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("config.module.allowedLicenseHosts[%v]: invalid host pattern %q: %w", i, pattern, err)
+		}
+	}
+	for i, moduleLicense := range config.Module.Licenses {
+		if moduleLicense.Module == "" || moduleLicense.License == "" {
+			return nil, fmt.Errorf("config.module.licenses[%v]: module and license are required", i)
+		}
+		if moduleLicense.Type != "" {
+			config.Licenses.Types.Overrides = append(config.Licenses.Types.Overrides, LicenseTypeOverride{
+				SpdxId: moduleLicense.License,
+				Type:   moduleLicense.Type,
+			})
+		}
+	}
+	for licenseType, requirement := range config.Licenses.Types.Requirements {
+		if licenseType == "" {
+			return nil, fmt.Errorf("config.licenses.types.requirements: license type must be non empty")
+		}
+		if requirement != RequirementDistributeSource && requirement != RequirementDistributeNotice {
+			return nil, fmt.Errorf("config.licenses.types.requirements: requirement %q of type %q is invalid: must be %s or %s", requirement, licenseType, RequirementDistributeSource, RequirementDistributeNotice)
+		}
+	}
 	for i, licenseOverride := range config.Licenses.Types.Overrides {
 		if licenseOverride.SpdxId == "" {
 			return nil, fmt.Errorf("config.licenses.types.overrides[%v]: license override's spdxId must be non empty", i)
 		}
-		if !licenseclassifier.LicenseTypes.Contains(licenseOverride.Type) {
-			return nil, fmt.Errorf("license override spdxId=%q type=%q is invalid: type must be one of %v", licenseOverride.SpdxId, licenseOverride.Type, licenseclassifier.LicenseTypes.String())
+		if _, configured := config.Licenses.Types.Requirements[licenseOverride.Type]; configured {
+			continue
+		}
+		if !licenseclassifier.LicenseTypes.Contains(licenseOverride.Type) && licenseOverride.Type != LicenseTypeCommercial {
+			return nil, fmt.Errorf("license override spdxId=%q type=%q is invalid: type must be %s, a type in licenses.types.requirements or one of %v", licenseOverride.SpdxId, licenseOverride.Type, LicenseTypeCommercial, licenseclassifier.LicenseTypes.String())
 		}
 	}
 	return config, nil
@@ -88,6 +88,81 @@ func TestLoadConfig_SpecifiedPath(t *testing.T) {
 	}, loaded.Licenses)
 }
 
+func TestLoadConfig_ConfidenceThreshold(t *testing.T) {
+	loaded, err := config.Load("testdata/confidence.yaml")
+	require.Nil(t, err)
+	expected := []config.ModuleOverride{{
+		Name:                "github.com/example/unusual",
+		ConfidenceThreshold: 0.7,
+		Justification:       "LICENSE has an extra preamble before the MIT license text.",
+	}}
+	assert.Equal(t, expected, loaded.Module.Overrides)
+}
+
+func TestLoadConfig_ConfidenceThresholdWithoutJustification(t *testing.T) {
+	_, err := config.Load("testdata/confidence-no-justification.yaml")
+	require.NotNil(t, err, "should report error when confidenceThreshold has no justification")
+	assert.Contains(t, err.Error(), "justification")
+}
+
+func TestLoadConfig_Commercial(t *testing.T) {
+	loaded, err := config.Load("testdata/commercial.yaml")
+	require.Nil(t, err)
+	assert.Equal(t, []config.LicenseTypeOverride{{SpdxId: "LicenseRef-Acme-Commercial", Type: config.LicenseTypeCommercial}}, loaded.Licenses.Types.Overrides)
+	assert.True(t, loaded.Licenses.Commercial.Allowed)
+	assert.Equal(t, "Acme SDK license agreement 2021-042, renew yearly.", loaded.Module.Overrides[0].Obligations)
+}
+
+func TestLoadConfig_Policy(t *testing.T) {
+	loaded, err := config.Load("testdata/policy.yaml")
+	require.Nil(t, err)
+	expected := config.Policy{
+		AllowedTypes:       []string{"Notice", "Permissive"},
+		DisallowedLicenses: []string{"CC-BY-NC-4.0"},
+	}
+	assert.Equal(t, expected, loaded.Licenses.Policy)
+}
+
+func TestLoadConfig_Requirements(t *testing.T) {
+	loaded, err := config.Load("testdata/requirements.yaml")
+	require.Nil(t, err)
+	expected := map[string]string{
+		"internal":   config.RequirementDistributeNotice,
+		"reciprocal": config.RequirementDistributeNotice,
+	}
+	assert.Equal(t, expected, loaded.Licenses.Types.Requirements)
+	assert.Equal(t, []config.LicenseTypeOverride{{SpdxId: "LicenseRef-Acme-Internal", Type: "internal"}}, loaded.Licenses.Types.Overrides)
+}
+
+func TestLoadConfig_InvalidRequirement(t *testing.T) {
+	_, err := config.Load("testdata/requirements-invalid.yaml")
+	require.NotNil(t, err, "should report error when a requirement is invalid")
+	assert.Contains(t, err.Error(), "Allowed")
+}
+
+func TestLoadConfig_ModuleLicenses(t *testing.T) {
+	loaded, err := config.Load("testdata/module-licenses.yaml")
+	require.Nil(t, err)
+	expected := []config.ModuleLicense{
+		{Module: "example.com/foo", License: "BSD-3-Clause", Url: "https://example.com/foo/LICENSE"},
+		{Module: "example.com/internal", License: "LicenseRef-Example-Internal", Type: "notice", OnlyWhenNotFound: true},
+	}
+	assert.Equal(t, expected, loaded.Module.Licenses)
+	assert.Contains(t, loaded.Licenses.Types.Overrides, config.LicenseTypeOverride{SpdxId: "LicenseRef-Example-Internal", Type: "notice"})
+}
+
+func TestLoadConfig_AllowedLicenseHosts(t *testing.T) {
+	loaded, err := config.Load("testdata/allowed-hosts.yaml")
+	require.Nil(t, err)
+	assert.Equal(t, []string{"github.com", "*.googlesource.com"}, loaded.Module.AllowedLicenseHosts)
+}
+
+func TestLoadConfig_InvalidAllowedLicenseHost(t *testing.T) {
+	_, err := config.Load("testdata/allowed-hosts-invalid.yaml")
+	require.NotNil(t, err, "should report error when a host pattern is invalid")
+	assert.Contains(t, err.Error(), "allowedLicenseHosts[1]")
+}
+
 func TestLoadConfig_PathNotExist(t *testing.T) {
 	_, err := config.Load("file-not-exist")
 	require.NotNil(t, err)
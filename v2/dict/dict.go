@@ -33,7 +33,18 @@ type LicenseRecord struct {
 
 const defaultDictLocation = "license_dict.csv"
 
+// LoadOptions customizes how license records are validated.
+type LoadOptions struct {
+	// Allow records without download urls, e.g. when records are only
+	// compared with each other instead of downloaded.
+	AllowEmptyUrl bool
+}
+
 func LoadLicenseRecords(r io.Reader) ([]*LicenseRecord, error) {
+	return LoadLicenseRecordsWithOptions(r, LoadOptions{})
+}
+
+func LoadLicenseRecordsWithOptions(r io.Reader, options LoadOptions) ([]*LicenseRecord, error) {
 	reader := csv.NewReader(r)
 	reader.Comment = '#'
 	reader.FieldsPerRecord = 3
@@ -43,7 +54,7 @@ func LoadLicenseRecords(r io.Reader) ([]*LicenseRecord, error) {
 	}
 	records := make([]*LicenseRecord, 0)
 	for index, raw := range rawRecords {
-		record, err := parseRawRecord(raw)
+		record, err := parseRawRecord(raw, options)
 		if err != nil {
 			return nil, errors.Wrapf(err, "Record #%v with content '%s' is invalid ", index+1, strings.Join(raw, ","))
 		}
@@ -64,7 +75,7 @@ func LoadLicenseDict(r io.Reader) (LicenseDict, error) {
 	return dict, nil
 }
 
-func parseRawRecord(raw []string) (*LicenseRecord, error) {
+func parseRawRecord(raw []string, options LoadOptions) (*LicenseRecord, error) {
 	if len(raw) != 3 {
 		return nil, errors.Errorf("Invalid license record: 3 segments expected")
 	}
@@ -78,7 +89,7 @@ func parseRawRecord(raw []string) (*LicenseRecord, error) {
 	if record.Type == "Ignore" {
 		record.ShouldIgnore = true
 	}
-	if !record.ShouldIgnore {
+	if !record.ShouldIgnore && !options.AllowEmptyUrl {
 		if record.DownaloadUrl == "" {
 			return nil, errors.Errorf("Empty download url")
 		}
@@ -0,0 +1,227 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ghutils
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// DefaultDownloadBackoff is the default wait time before the first retry of a
+// failed download, it doubles for each retry.
+const DefaultDownloadBackoff = time.Second
+
+// githubHosts are hosts a github token is sent to.
+var githubHosts = map[string]bool{
+	"github.com":                true,
+	"raw.githubusercontent.com": true,
+}
+
+// Downloader downloads content like SmartDownload, retrying transient
+// failures and caching content by url, so that modules sharing a license url
+// download it once. It's safe for concurrent use.
+type Downloader struct {
+	// Number of retries of a download that failed sending, or responded with
+	// a server error or 429 Too Many Requests.
+	Retries int
+	// Wait time before the first retry, defaults to DefaultDownloadBackoff.
+	// The server's Retry-After is used instead when it responds with one.
+	Backoff time.Duration
+	// Optional, github token sent to github hosts to raise their rate limits.
+	Token string
+	// Optional, http client to download with.
+	Client *http.Client
+	// Optional, called with the url each redirect goes to. The redirect is
+	// refused when it returns an error, and the download fails without
+	// retries.
+	CheckRedirect func(url string) error
+
+	mu sync.Mutex
+	// download url -> content
+	cache map[string]string
+}
+
+// SmartDownload is like the SmartDownload function, using the options of d.
+func (d *Downloader) SmartDownload(url string) (string, error) {
+	wrap := func(err error) error {
+		return fmt.Errorf("SmartDownload(%q): %w", url, err)
+	}
+	downloadUrl, lineStart, lineEnd, err := GithubDownloadUrl(url)
+	if err != nil {
+		return "", wrap(err)
+	}
+	if downloadUrl == "" {
+		// if not detected, use original url to download
+		downloadUrl = url
+	}
+	content, err := d.cachedDownload(downloadUrl)
+	if err != nil {
+		return "", wrap(err)
+	}
+	if content == "" {
+		return "", wrap(fmt.Errorf("downloaded content is empty"))
+	}
+	content, err = SelectLines(content, lineStart, lineEnd)
+	if err != nil {
+		return "", wrap(err)
+	}
+	return content, nil
+}
+
+func (d *Downloader) cachedDownload(url string) (string, error) {
+	d.mu.Lock()
+	content, cached := d.cache[url]
+	d.mu.Unlock()
+	if cached {
+		klog.V(2).InfoS("Using cached download", "url", url)
+		return content, nil
+	}
+	content, err := d.download(url)
+	if err != nil {
+		return "", err
+	}
+	d.mu.Lock()
+	if d.cache == nil {
+		d.cache = make(map[string]string)
+	}
+	d.cache[url] = content
+	d.mu.Unlock()
+	return content, nil
+}
+
+// download gets content of url, retrying with exponential backoff when it
+// fails sending or the server responds with a transient error.
+func (d *Downloader) download(url string) (string, error) {
+	client := http.DefaultClient
+	if d.Client != nil {
+		client = d.Client
+	}
+	if d.Token != "" {
+		// The token is added to each https request to github hosts,
+		// including redirects, e.g. from github.com to
+		// raw.githubusercontent.com, but never to other hosts or http.
+		withToken := *client
+		withToken.Transport = &tokenTransport{token: d.Token, base: client.Transport}
+		client = &withToken
+	}
+	if d.CheckRedirect != nil {
+		withCheck := *client
+		withCheck.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			// the same limit as the default policy of http.Client
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after 10 redirects")
+			}
+			if err := d.CheckRedirect(req.URL.String()); err != nil {
+				return &refusedRedirectError{err: err}
+			}
+			return nil
+		}
+		client = &withCheck
+	}
+	backoff := d.Backoff
+	if backoff == 0 {
+		backoff = DefaultDownloadBackoff
+	}
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		content, retryAfter, err := get(client, url)
+		if err == nil {
+			return content, nil
+		}
+		lastErr = err
+		if retryAfter < 0 || attempt >= d.Retries {
+			break
+		}
+		wait := backoff
+		if retryAfter > 0 {
+			wait = retryAfter
+		}
+		klog.V(2).InfoS("Retrying download", "url", url, "err", err, "backoff", wait)
+		time.Sleep(wait)
+		backoff = backoff * 2
+	}
+	return "", fmt.Errorf("download(%q) failed after %v attempt(s): %w", url, d.Retries+1, lastErr)
+}
+
+// get sends a request to url. When it fails, retryAfter is negative if the
+// failure is not transient, or the wait time the server asked for if any.
+func get(client *http.Client, url string) (content string, retryAfter time.Duration, err error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		var refused *refusedRedirectError
+		if errors.As(err, &refused) {
+			return "", -1, err
+		}
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		err := fmt.Errorf("response status code %v not OK", resp.StatusCode)
+		if resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+			return "", -1, err
+		}
+		if seconds, parseErr := strconv.Atoi(resp.Header.Get("Retry-After")); parseErr == nil && seconds > 0 {
+			retryAfter = time.Duration(seconds) * time.Second
+		}
+		return "", retryAfter, err
+	}
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read from response body: %w", err)
+	}
+	return string(bodyBytes), 0, nil
+}
+
+// refusedRedirectError is an error of Downloader.CheckRedirect.
+type refusedRedirectError struct {
+	err error
+}
+
+func (e *refusedRedirectError) Error() string {
+	return "refused redirect: " + e.err.Error()
+}
+
+func (e *refusedRedirectError) Unwrap() error {
+	return e.err
+}
+
+// tokenTransport authorizes https requests to github hosts using a token, so
+// that the token is never sent in cleartext or to other hosts, e.g. after a
+// redirect.
+type tokenTransport struct {
+	token string
+	base  http.RoundTripper
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if req.URL.Scheme != "https" || !githubHosts[req.URL.Hostname()] {
+		return base.RoundTrip(req)
+	}
+	// RoundTrippers must not modify the request.
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "token "+t.token)
+	return base.RoundTrip(req)
+}
@@ -0,0 +1,126 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ghutils_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-licenses/v2/ghutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloader_Retries(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts = attempts + 1
+		switch {
+		case r.URL.Path == "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case attempts < 3:
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			fmt.Fprint(w, "license text")
+		}
+	}))
+	defer server.Close()
+
+	downloader := &ghutils.Downloader{Retries: 1, Backoff: time.Millisecond}
+	_, err := downloader.SmartDownload(server.URL + "/LICENSE")
+	require.NotNil(t, err, "should fail when retries are exhausted")
+	assert.Equal(t, 2, attempts)
+
+	attempts = 0
+	downloader.Retries = 2
+	content, err := downloader.SmartDownload(server.URL + "/LICENSE")
+	require.Nil(t, err)
+	assert.Equal(t, "license text", content)
+	assert.Equal(t, 3, attempts)
+
+	// content is cached by url
+	content, err = downloader.SmartDownload(server.URL + "/LICENSE")
+	require.Nil(t, err)
+	assert.Equal(t, "license text", content)
+	assert.Equal(t, 3, attempts)
+
+	// client errors are not retried
+	attempts = 0
+	_, err = downloader.SmartDownload(server.URL + "/missing")
+	require.NotNil(t, err)
+	assert.Equal(t, 1, attempts)
+}
+
+// roundTripperFunc responds to requests using a function.
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestDownloader_Token(t *testing.T) {
+	authorization := make(map[string]string)
+	client := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		authorization[req.URL.Host] = req.Header.Get("Authorization")
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader("license text")),
+			Request:    req,
+		}, nil
+	})}
+	downloader := &ghutils.Downloader{Token: "secret", Client: client}
+	_, err := downloader.SmartDownload("https://github.com/example/repo/blob/v1.0.0/LICENSE")
+	require.Nil(t, err)
+	_, err = downloader.SmartDownload("https://example.com/LICENSE")
+	require.Nil(t, err)
+	assert.Equal(t, map[string]string{"github.com": "token secret", "example.com": ""}, authorization)
+}
+
+func TestDownloader_TokenNotSentOverHttp(t *testing.T) {
+	authorization := make(map[string]string)
+	client := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		authorization[req.URL.String()] = req.Header.Get("Authorization")
+		if req.URL.Path == "/redirect" {
+			// redirected from https to http
+			return &http.Response{
+				StatusCode: http.StatusFound,
+				Header:     http.Header{"Location": []string{"http://raw.githubusercontent.com/LICENSE"}},
+				Body:       ioutil.NopCloser(strings.NewReader("")),
+				Request:    req,
+			}, nil
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader("license text")),
+			Request:    req,
+		}, nil
+	})}
+	downloader := &ghutils.Downloader{Token: "secret", Client: client}
+	_, err := downloader.SmartDownload("http://raw.githubusercontent.com/example/repo/v1.0.0/LICENSE")
+	require.Nil(t, err)
+	_, err = downloader.SmartDownload("https://raw.githubusercontent.com/redirect")
+	require.Nil(t, err)
+	expected := map[string]string{
+		"http://raw.githubusercontent.com/example/repo/v1.0.0/LICENSE": "",
+		"https://raw.githubusercontent.com/redirect":                   "token secret",
+		"http://raw.githubusercontent.com/LICENSE":                     "",
+	}
+	assert.Equal(t, expected, authorization)
+}
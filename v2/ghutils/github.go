@@ -16,8 +16,7 @@ package ghutils
 
 import (
 	"fmt"
-	"io/ioutil"
-	"net/http"
+	"path"
 	"regexp"
 	"strconv"
 	"strings"
@@ -28,6 +27,10 @@ import (
 type GitHubRepo struct {
 	Owner string
 	Name  string
+	// Directory of the go module relative to the repo root, e.g. "gopls" for
+	// golang.org/x/tools/gopls. Empty when the module is at the repo root.
+	// Modules in sub directories are tagged like <ModuleDir>/<version>.
+	ModuleDir string
 }
 
 const (
@@ -55,11 +58,17 @@ func ParseGitHubUrl(githubUrl string) (*GitHubRepo, error) {
 }
 
 type RemoteUrlArgs struct {
+	// Path of the file relative to the module, e.g. LICENSE, or ../LICENSE
+	// for a license in a parent folder of the module.
 	Path      string
 	Version   string
 	Raw       bool
 	LineStart int
 	LineEnd   int
+	// Branch linked to when Version is empty, e.g. for modules replaced by
+	// local folders. Defaults to "HEAD", which github resolves to the default
+	// branch of the repo.
+	DefaultBranch string
 }
 
 func (repo *GitHubRepo) RemoteUrl(args RemoteUrlArgs) (string, error) {
@@ -81,8 +90,8 @@ func (repo *GitHubRepo) RemoteUrl(args RemoteUrlArgs) (string, error) {
 		template,
 		repo.Owner,
 		repo.Name,
-		parseGoModulePseudoVersion(args.Version),
-		args.Path)
+		versionTag(repo.ModuleDir, args.Version, args.DefaultBranch),
+		repoPath(repo.ModuleDir, args.Path))
 	if args.LineStart > 0 {
 		if args.Raw {
 			return "", fmt.Errorf("GitHubRepo.RemoteUrl(%+v): LineStart and LineEnd not supported for url to raw content.", args)
@@ -98,9 +107,14 @@ func (repo *GitHubRepo) RemoteUrl(args RemoteUrlArgs) (string, error) {
 // vX.Y.(Z+1)-0.yyyymmddhhmmss-abcdefabcdef is used when the base version is a release version like vX.Y.Z. For example, if the base version is v1.2.3, a pseudo-version might be v1.2.4-0.20191109021931-daa7c04131f5.
 var psuedoVersionPattern = regexp.MustCompile(`^v[0-9]+\.[0-9]+\.[0-9]+-.*[0-9]{14}-(?P<commit>[a-f0-9]{11,12})$`)
 
-func parseGoModulePseudoVersion(version string) string {
+func parseGoModulePseudoVersion(version string, defaultBranch string) string {
 	if version == "" {
-		return "main" // default to content in main branch
+		if defaultBranch == "" {
+			// github resolves HEAD to the default branch, whether it's main,
+			// master or something else
+			return "HEAD"
+		}
+		return defaultBranch
 	}
 	// Parse version like v0.0.0-20210108172934-df6aa8a2788b to commit hash:
 	// df6aa8a2788b.
@@ -112,6 +126,36 @@ func parseGoModulePseudoVersion(version string) string {
 	return version
 }
 
+var majorVersionSuffixPattern = regexp.MustCompile(`(^|/)v[0-9]+$`)
+
+// versionTag returns the git revision of a module version, for a module in
+// moduleDir of the repo.
+// Reference: https://golang.org/ref/mod#vcs-version
+// If a module is defined in a subdirectory within the repository, the
+// subdirectory prefix must be added to the tag, e.g. module
+// golang.org/x/tools/gopls version v0.7.0 is tagged gopls/v0.7.0. The major
+// version suffix is not part of the prefix.
+func versionTag(moduleDir string, version string, defaultBranch string) string {
+	revision := parseGoModulePseudoVersion(version, defaultBranch)
+	if revision != version {
+		// a commit hash or the default branch, they don't have prefixes
+		return revision
+	}
+	prefix := majorVersionSuffixPattern.ReplaceAllString(moduleDir, "")
+	if prefix == "" {
+		return version
+	}
+	return prefix + "/" + version
+}
+
+// repoPath returns the path relative to the repo root of a file in a module
+// in moduleDir of the repo. Like tags, the major version suffix is not part of
+// the module's directory, because modules like k8s.io/klog/v2 are usually
+// developed on a major branch at the repo root.
+func repoPath(moduleDir string, filePath string) string {
+	return path.Join(majorVersionSuffixPattern.ReplaceAllString(moduleDir, ""), filePath)
+}
+
 var githubUrlPattern = regexp.MustCompile(`^(https://)?(www\.)?github.com/(?P<repo>[^/]+/[^/]+)/blob/(?P<path>[^#]*)(?P<hash>#.*)?$`)
 var githubLinePattern = regexp.MustCompile(`^#L(?P<linestart>[0-9]+)-L(?P<lineend>[0-9]+)$`)
 
@@ -142,53 +186,26 @@ func GithubDownloadUrl(url string) (downloadUrl string, lineStart int, lineEnd i
 	return "", 0, 0, nil
 }
 
-// TODO: this downloads url content in memory.
-// We might need optimization in the future.
+// SmartDownload downloads content of url, or lines of a file in a github repo
+// when url is a github url with line numbers, e.g. #L3-L8. Downloads are not
+// retried or cached, use a Downloader for that.
 func SmartDownload(url string) (string, error) {
-	wrap := func(err error) error {
-		return fmt.Errorf("SmartDownload(%q): %w", url, err)
-	}
-	downloadUrl, lineStart, lineEnd, err := GithubDownloadUrl(url)
-	if err != nil {
-		return "", wrap(err)
-	}
-	if downloadUrl == "" {
-		// if not detected, use original url to download
-		downloadUrl = url
-	}
-	content, err := download(downloadUrl)
-	if err != nil {
-		return "", wrap(err)
-	}
-	if content == "" {
-		return "", wrap(fmt.Errorf("downloaded content is empty"))
-	}
+	return (&Downloader{}).SmartDownload(url)
+}
+
+// SelectLines returns lines from lineStart to lineEnd of content, both
+// included and starting from 1. content is returned as is when lineStart is 0.
+func SelectLines(content string, lineStart int, lineEnd int) (string, error) {
 	if lineStart == 0 {
 		return content, nil
 	}
 	if lineEnd == 0 {
-		return "", wrap(fmt.Errorf("lineEnd must be non zero when lineStart isn't"))
+		return "", fmt.Errorf("lineEnd must be non zero when lineStart isn't")
 	}
 	lines := strings.Split(content, "\n")
 	if lineEnd >= len(lines) {
-		return "", wrap(fmt.Errorf("total %v lines, but lineEnd=%v", len(lines), lineEnd))
+		return "", fmt.Errorf("total %v lines, but lineEnd=%v", len(lines), lineEnd)
 	}
 	// lineStart start from 1, so we convert to start from 0.
 	return strings.Join(lines[lineStart-1:lineEnd], "\n"), nil
 }
-
-func download(url string) (string, error) {
-	resp, err := http.Get(url)
-	if err != nil {
-		return "", fmt.Errorf("download(%q): %w", url, err)
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode >= 400 {
-		return "", fmt.Errorf("download(%q) response status code %v not OK", url, resp.StatusCode)
-	}
-	bodyBytes, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("download(%q) failed to read from response body: %w", url, err)
-	}
-	return string(bodyBytes), nil
-}
@@ -60,11 +60,34 @@ func TestGithubRepoRemoteUrl(t *testing.T) {
 			},
 			expected: "https://github.com/googleapis/google-cloud-go/blob/6480d4af844/LICENSE",
 		},
+		{
+			// empty version links to the default branch of the repo
+			args: ghutils.RemoteUrlArgs{
+				Path: "LICENSE",
+			},
+			expected: "https://github.com/googleapis/google-cloud-go/blob/HEAD/LICENSE",
+		},
+		{
+			args: ghutils.RemoteUrlArgs{
+				Path:          "LICENSE",
+				DefaultBranch: "master",
+			},
+			expected: "https://github.com/googleapis/google-cloud-go/blob/master/LICENSE",
+		},
+		{
+			// DefaultBranch is ignored when version is known
+			args: ghutils.RemoteUrlArgs{
+				Path:          "LICENSE",
+				Version:       "v0.72.0",
+				DefaultBranch: "master",
+			},
+			expected: "https://github.com/googleapis/google-cloud-go/blob/v0.72.0/LICENSE",
+		},
 	}
 	for _, tt := range cases {
 		got, err := repo.RemoteUrl(tt.args)
 		if err != nil {
-			t.Errorf("repo.RemoteUrl(%+v) failed: %w", tt.args, err)
+			t.Errorf("repo.RemoteUrl(%+v) failed: %v", tt.args, err)
 		}
 		if got != tt.expected {
 			t.Errorf("repo.RemoteUrl(%+v) got %q, expected %q", tt.args, got, tt.expected)
@@ -72,6 +95,61 @@ func TestGithubRepoRemoteUrl(t *testing.T) {
 	}
 }
 
+func TestGithubRepoRemoteUrl_ModuleDir(t *testing.T) {
+	cases := []struct {
+		moduleDir string
+		path      string
+		version   string
+		expected  string
+	}{
+		{
+			// the module's own license in its sub directory
+			moduleDir: "gopls",
+			path:      "LICENSE",
+			version:   "v0.7.0",
+			expected:  "https://github.com/golang/tools/blob/gopls/v0.7.0/gopls/LICENSE",
+		},
+		{
+			// a license in a parent folder of the module
+			moduleDir: "gopls",
+			path:      "../LICENSE",
+			version:   "v0.7.0",
+			expected:  "https://github.com/golang/tools/blob/gopls/v0.7.0/LICENSE",
+		},
+		{
+			moduleDir: "gopls/v2",
+			path:      "LICENSE",
+			version:   "v2.1.0",
+			expected:  "https://github.com/golang/tools/blob/gopls/v2.1.0/gopls/LICENSE",
+		},
+		{
+			// major version sub directory at the repo root
+			moduleDir: "v2",
+			path:      "LICENSE",
+			version:   "v2.1.0",
+			expected:  "https://github.com/golang/tools/blob/v2.1.0/LICENSE",
+		},
+		{
+			// pseudo versions are converted to commit hashes without prefix
+			moduleDir: "gopls",
+			path:      "LICENSE",
+			version:   "v0.0.0-20210108172934-dcfadaf1a8b1",
+			expected:  "https://github.com/golang/tools/blob/dcfadaf1a8b1/gopls/LICENSE",
+		},
+	}
+	for _, tt := range cases {
+		repo := ghutils.GitHubRepo{Owner: "golang", Name: "tools", ModuleDir: tt.moduleDir}
+		args := ghutils.RemoteUrlArgs{Path: tt.path, Version: tt.version}
+		got, err := repo.RemoteUrl(args)
+		if err != nil {
+			t.Errorf("repo.RemoteUrl(%+v) failed: %v", args, err)
+		}
+		if got != tt.expected {
+			t.Errorf("repo(ModuleDir=%q).RemoteUrl(%+v) got %q, expected %q", tt.moduleDir, args, got, tt.expected)
+		}
+	}
+}
+
 func TestGithubDownloadUrl(t *testing.T) {
 	cases := []struct {
 		url         string
@@ -93,7 +171,7 @@ func TestGithubDownloadUrl(t *testing.T) {
 	for _, tt := range cases {
 		downloadUrl, lineStart, lineEnd, err := ghutils.GithubDownloadUrl(tt.url)
 		if err != nil {
-			t.Errorf("GithubDownloadUrl(%q) failed: %w", tt.url, err)
+			t.Errorf("GithubDownloadUrl(%q) failed: %v", tt.url, err)
 		}
 		if downloadUrl != tt.downloadUrl || lineStart != tt.lineStart || lineEnd != tt.lineEnd {
 			t.Errorf("GithubDownloadUrl(%q) got downloadUrl=%q lineStart=%v lineEnd=%v, expected %+v", tt.url, downloadUrl, lineStart, lineEnd, tt)
@@ -4,6 +4,7 @@ go 1.15
 
 require (
 	github.com/PuerkitoBio/goquery v1.7.0
+	github.com/go-logr/logr v0.4.0
 	github.com/google/addlicense v0.0.0-20210428195630-6d92264d7170
 	github.com/google/licenseclassifier v0.0.0-20210325184830-bb04aff29e72
 	github.com/google/licenseclassifier/v2 v2.0.0-alpha.1.0.20210325184830-bb04aff29e72
@@ -70,6 +70,7 @@ func TestListDeps(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	defer os.Chdir(originalWorkDir)
 	for _, tc := range tests {
 		os.Chdir(filepath.Join(originalWorkDir, tc.workdir))
 		sort.Strings(tc.modules)
@@ -121,3 +122,174 @@ func TestListDeps(t *testing.T) {
 		})
 	}
 }
+
+func TestListDeps_Vendored(t *testing.T) {
+	originalWorkDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(originalWorkDir)
+	workdir := filepath.Join(originalWorkDir, "../tests/modules/vendored03")
+	os.Chdir(workdir)
+	originalGoFlags, hasGoFlags := os.LookupEnv("GOFLAGS")
+	os.Setenv("GOFLAGS", "-mod=vendor")
+	defer func() {
+		if hasGoFlags {
+			os.Setenv("GOFLAGS", originalGoFlags)
+		} else {
+			os.Unsetenv("GOFLAGS")
+		}
+	}()
+
+	mods, err := gocli.ListDeps("github.com/google/go-licenses/v2/tests/modules/vendored03")
+	if err != nil {
+		t.Fatalf("gocli.ListDeps: %v", err)
+	}
+	dirs := make(map[string]string)
+	for _, mod := range mods {
+		dirs[mod.Path] = mod.Dir
+	}
+	assert.Equal(t, filepath.Join(workdir, "vendor/example.com/greeting"), dirs["example.com/greeting"])
+}
+
+func TestListDepsWithOptions(t *testing.T) {
+	originalWorkDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(originalWorkDir)
+	os.Chdir(filepath.Join(originalWorkDir, "../tests/modules/buildtags04"))
+
+	tests := []struct {
+		name    string
+		options gocli.ListOptions
+		want    []string
+	}{
+		{
+			name: "default",
+			want: []string{},
+		},
+		{
+			name:    "build tags",
+			options: gocli.ListOptions{BuildTags: []string{"tagged"}},
+			want:    []string{"example.com/tagged"},
+		},
+		{
+			name:    "goos",
+			options: gocli.ListOptions{GOOS: "windows", GOARCH: "amd64"},
+			want:    []string{"example.com/windows"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mods, err := gocli.ListDepsWithOptions(tc.options, "github.com/google/go-licenses/v2/tests/modules/buildtags04")
+			if err != nil {
+				t.Fatalf("gocli.ListDepsWithOptions: %v", err)
+			}
+			deps := make([]string, 0)
+			for _, mod := range mods {
+				if !mod.Main {
+					deps = append(deps, mod.Path)
+				}
+			}
+			assert.Equal(t, tc.want, deps)
+		})
+	}
+}
+
+func TestListDepsWithOptions_Tests(t *testing.T) {
+	originalWorkDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(originalWorkDir)
+	os.Chdir(filepath.Join(originalWorkDir, "../tests/modules/testdeps05"))
+
+	for _, tests := range []bool{false, true} {
+		mods, err := gocli.ListDepsWithOptions(gocli.ListOptions{Tests: tests}, "github.com/google/go-licenses/v2/tests/modules/testdeps05")
+		if err != nil {
+			t.Fatalf("gocli.ListDepsWithOptions(Tests=%v): %v", tests, err)
+		}
+		found := false
+		for _, mod := range mods {
+			if mod.Path == "example.com/testonly" {
+				found = true
+			}
+		}
+		assert.Equal(t, tests, found, "example.com/testonly listed when Tests=%v", tests)
+	}
+}
+
+func TestListDeps_Replaced(t *testing.T) {
+	originalWorkDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(originalWorkDir)
+	workdir := filepath.Join(originalWorkDir, "../tests/modules/replace06")
+	os.Chdir(workdir)
+
+	// example.com/flags is replaced by github.com/spf13/pflag, and
+	// example.com/localdep by a local directory.
+	want := map[string]gocli.Module{
+		"github.com/spf13/pflag": {Path: "github.com/spf13/pflag", Version: "v1.0.5"},
+		"example.com/localdep":   {Path: "example.com/localdep", LocalPath: "./localdep", Dir: filepath.Join(workdir, "localdep")},
+	}
+	check := func(t *testing.T, mods []gocli.Module) {
+		got := make(map[string]gocli.Module)
+		for _, mod := range mods {
+			if !mod.Main {
+				got[mod.Path] = gocli.Module{Path: mod.Path, Version: mod.Version, LocalPath: mod.LocalPath, Dir: mod.Dir}
+			}
+		}
+		// Dirs in the module cache depend on the machine.
+		pflag := got["github.com/spf13/pflag"]
+		assert.NotEmpty(t, pflag.Dir)
+		pflag.Dir = ""
+		got["github.com/spf13/pflag"] = pflag
+		assert.Equal(t, want, got)
+	}
+
+	t.Run("gocli.ExtractBinaryMetadata", func(t *testing.T) {
+		tempDir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(tempDir)
+		binaryName := path.Join(tempDir, "main")
+		if _, err := exec.Command("go", "build", "-buildvcs=false", "-o", binaryName).Output(); err != nil {
+			t.Fatalf("go build: %v", err)
+		}
+		metadata, err := gocli.ExtractBinaryMetadata(binaryName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, metadata.Deps)
+	})
+
+	t.Run("gocli.ListDeps", func(t *testing.T) {
+		mods, err := gocli.ListDeps("github.com/google/go-licenses/v2/tests/modules/replace06")
+		if err != nil {
+			t.Fatalf("gocli.ListDeps: %v", err)
+		}
+		check(t, mods)
+	})
+}
+
+func TestListDeps_Dir(t *testing.T) {
+	// Go commands run in Dir, without changing the working directory.
+	mods, err := gocli.ListDepsWithOptions(gocli.ListOptions{Dir: "../tests/modules/hello01"}, "./...")
+	if err != nil {
+		t.Fatalf("gocli.ListDepsWithOptions: %v", err)
+	}
+	if assert.Len(t, mods, 1) {
+		assert.Equal(t, "github.com/google/go-licenses/v2/tests/modules/hello01", mods[0].Path)
+		assert.True(t, mods[0].Main)
+	}
+
+	dict, err := gocli.ListModulesInDir("../tests/modules/hello01")
+	if err != nil {
+		t.Fatalf("gocli.ListModulesInDir: %v", err)
+	}
+	assert.Contains(t, dict, "github.com/google/go-licenses/v2/tests/modules/hello01")
+}
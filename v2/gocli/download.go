@@ -0,0 +1,69 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gocli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// downloadedModule is the json output of `go mod download -json`.
+type downloadedModule struct {
+	Path    string
+	Version string
+	Error   string
+	Dir     string
+	GoMod   string
+}
+
+// DownloadModule downloads a module version, e.g. golang.org/x/text@v0.3.5,
+// using go CLI mod download command, so that modules that are not
+// dependencies of workdir can be inspected. The version may also be a query
+// like latest. The module is downloaded to modCache, used as GOMODCACHE,
+// e.g. a temp dir. Files in modCache are writable, so that it can be removed
+// by os.RemoveAll.
+func DownloadModule(moduleVersion string, modCache string) (*Module, error) {
+	if !strings.Contains(moduleVersion, "@") {
+		return nil, fmt.Errorf("DownloadModule(%q): version is required, e.g. %s@latest", moduleVersion, moduleVersion)
+	}
+	cmd := exec.Command("go", "mod", "download", "-json", moduleVersion)
+	// Run outside of workdir's module, its requirements are irrelevant.
+	cmd.Dir = modCache
+	goflags := strings.TrimSpace(os.Getenv("GOFLAGS") + " -modcacherw")
+	cmd.Env = append(os.Environ(), "GOMODCACHE="+modCache, "GOFLAGS="+goflags, "GOWORK=off")
+	out, err := cmd.Output()
+	var downloaded downloadedModule
+	if jsonErr := json.Unmarshal(out, &downloaded); jsonErr != nil {
+		if err == nil {
+			err = jsonErr
+		}
+		return nil, fmt.Errorf("Failed to download go module %s: %w", moduleVersion, err)
+	}
+	if downloaded.Error != "" {
+		return nil, fmt.Errorf("Failed to download go module %s: %s", moduleVersion, downloaded.Error)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to download go module %s: %w", moduleVersion, err)
+	}
+	return &Module{
+		Path:    downloaded.Path,
+		Version: strings.TrimSuffix(downloaded.Version, "+incompatible"),
+		Dir:     downloaded.Dir,
+		GoMod:   downloaded.GoMod,
+	}, nil
+}
@@ -16,6 +16,7 @@ package gocli
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/google/go-licenses/v2/third_party/go/runtime/debug"
 )
@@ -51,12 +52,34 @@ type BinaryMetadata struct {
 // 2. https://github.com/mitchellh/golicense/blob/8c09a94a11ac73299a72a68a7b41e3a737119f91/module/module.go#L27
 // 3. https://github.com/golang/go/issues/39301
 // 4. https://golang.org/pkg/cmd/go/internal/version/
-func ExtractBinaryMetadata(path string) (*BinaryMetadata, error) {
-	buildInfo, err := listModulesInBinary(path)
+//
+// Multiple binaries built from the same main module can be passed, the union
+// of their dependencies is returned. It's an error when binaries depend on
+// different versions of a module.
+func ExtractBinaryMetadata(paths ...string) (*BinaryMetadata, error) {
+	return ExtractBinaryMetadataInDir("", paths...)
+}
+
+// ExtractBinaryMetadataInDir is like ExtractBinaryMetadata, but joins modules
+// in the binaries with go modules of the module in dir instead of workdir,
+// when dir is not empty. Binary paths are not relative to dir.
+func ExtractBinaryMetadataInDir(dir string, paths ...string) (*BinaryMetadata, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("ExtractBinaryMetadata: no binary path")
+	}
+	buildInfos := make([]*debug.BuildInfo, 0, len(paths))
+	for _, path := range paths {
+		buildInfo, err := listModulesInBinary(path)
+		if err != nil {
+			return nil, err
+		}
+		buildInfos = append(buildInfos, buildInfo)
+	}
+	mainRef, refs, err := mergeModuleRefs(paths, buildInfos)
 	if err != nil {
 		return nil, err
 	}
-	main, deps, err := joinModulesMetadata(&buildInfo.Main, buildInfo.Deps)
+	main, deps, err := joinModulesMetadata(dir, mainRef, refs)
 	if err != nil {
 		return nil, err
 	}
@@ -66,16 +89,61 @@ func ExtractBinaryMetadata(path string) (*BinaryMetadata, error) {
 	}, nil
 }
 
+// mergeModuleRefs returns the main module and the union of dependencies in
+// build info of binaries at paths, in the order they are first found. It's an
+// error when binaries are built from different main modules, or depend on
+// different versions of a module.
+func mergeModuleRefs(paths []string, buildInfos []*debug.BuildInfo) (mainRef *debug.Module, refs []*debug.Module, err error) {
+	refs = make([]*debug.Module, 0)
+	// module path -> binary that the module was first found in, used to
+	// dedupe modules and to report conflicting versions
+	foundIn := make(map[string]string)
+	refsByPath := make(map[string]*debug.Module)
+	for i, buildInfo := range buildInfos {
+		path := paths[i]
+		if mainRef == nil {
+			mainRef = &buildInfo.Main
+		} else if buildInfo.Main.Path != mainRef.Path {
+			return nil, nil, fmt.Errorf("binaries are built from different main modules: %s in %s, %s in %s", mainRef.Path, paths[0], buildInfo.Main.Path, path)
+		}
+		for _, ref := range buildInfo.Deps {
+			existing, ok := refsByPath[ref.Path]
+			if !ok {
+				refsByPath[ref.Path] = ref
+				foundIn[ref.Path] = path
+				refs = append(refs, ref)
+				continue
+			}
+			if refVersion(existing) != refVersion(ref) {
+				return nil, nil, fmt.Errorf("conflicting versions of module %s: %s in %s, %s in %s", ref.Path, refVersion(existing), foundIn[ref.Path], refVersion(ref), path)
+			}
+		}
+	}
+	return mainRef, refs, nil
+}
+
 func listModulesInBinary(path string) (buildinfo *debug.BuildInfo, err error) {
 	// TODO(Bobgy): replace with x/mod equivalent from https://github.com/golang/go/issues/39301
 	// when it is available.
 	buildinfo, err = version(path)
 	if err != nil {
-		err = fmt.Errorf("listModulesInGoBinary(path=%q): %w", path, err)
+		return nil, fmt.Errorf("listModulesInGoBinary(path=%q): %w", path, err)
+	}
+	if buildinfo.Main.Path == "" {
+		return nil, fmt.Errorf("listModulesInGoBinary(path=%q): the binary does not contain go modules information, build it in go modules mode, e.g. `GO111MODULE=on go build`", path)
 	}
 	return buildinfo, nil
 }
 
+// refVersion returns version of a module ref, including its replacement if
+// any, e.g. "v1.0.0 => example.com/fork@v1.0.1".
+func refVersion(ref *debug.Module) string {
+	if ref.Replace == nil {
+		return ref.Version
+	}
+	return fmt.Sprintf("%s => %s@%s", ref.Version, ref.Replace.Path, ref.Replace.Version)
+}
+
 // joinModulesMetadata inner joins local go modules metadata with module ref
 // extracted from the binary.
 // The local go modules metadata is taken from calling `go list -m -json all`.
@@ -83,13 +151,14 @@ func listModulesInBinary(path string) (buildinfo *debug.BuildInfo, err error) {
 // An error is reported when we cannot find go module metadata for some refs,
 // or when there's a version mismatch. These errors usually indicate your current
 // working directory does not match exactly where the go binary is built.
-func joinModulesMetadata(mainRef *debug.Module, refs []*debug.Module) (main Module, deps []Module, err error) {
+// Go modules are listed in dir, or the working directory when it's empty.
+func joinModulesMetadata(dir string, mainRef *debug.Module, refs []*debug.Module) (main Module, deps []Module, err error) {
 	// Note, there was an attempt to use golang.org/x/tools/go/packages for
 	// loading modules instead, but it fails for modules like golang.org/x/sys.
 	// These modules only contains sub-packages, but no source code, so it
 	// throws an error when using packages.Load.
 	// More context: https://github.com/google/go-licenses/pull/71#issuecomment-890342154
-	localModulesDict, err := ListModules()
+	localModulesDict, err := ListModulesInDir(dir)
 	if err != nil {
 		return main, nil, err
 	}
@@ -97,18 +166,30 @@ func joinModulesMetadata(mainRef *debug.Module, refs []*debug.Module) (main Modu
 		if ref == nil {
 			return nil, fmt.Errorf("ref is nil")
 		}
-		mod, ok := localModulesDict[ref.Path]
+		modPath, ver := ref.Path, ref.Version
+		if ref.Replace != nil {
+			// Replaced modules are listed as their replacements, except
+			// that modules replaced by local directories keep their
+			// paths, see newModule.
+			ver = ref.Replace.Version
+			if !isLocalPath(ref.Replace.Path) {
+				modPath = ref.Replace.Path
+			}
+		}
+		mod, ok := localModulesDict[modPath]
 		if !ok {
-			return nil, fmt.Errorf("Cannot find %v in current dir's go modules. Are you running this tool from the working dir to build the binary you are analyzing?", ref.Path)
+			return nil, fmt.Errorf("Cannot find %v in current dir's go modules. Are you running this tool from the working dir to build the binary you are analyzing?", modPath)
 		}
 		if mod.Dir == "" {
-			return nil, fmt.Errorf("Module %v's local directory is empty. Did you run `go mod download`?", ref.Path)
+			return nil, fmt.Errorf("Module %v's local directory is empty. Did you run `go mod download`?", modPath)
 		}
-		ver := ref.Version
 		if ver == "(devel)" {
-			// Main module's version will be (devel). We should expect an empty version when listing the module info.
+			// Main module's version will be (devel), so are modules replaced
+			// by local directories. We should expect an empty version when
+			// listing the module info.
 			ver = ""
 		}
+		ver = strings.TrimSuffix(ver, "+incompatible")
 		if ver != mod.Version {
 			return nil, fmt.Errorf("Found %v@%v in go binary, but %v is downloaded in go modules. Are you running this tool from the working dir to build the binary you are analyzing?", ref.Path, ref.Version, mod.Version)
 		}
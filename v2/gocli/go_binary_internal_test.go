@@ -0,0 +1,98 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gocli
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-licenses/v2/third_party/go/runtime/debug"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeModuleRefs(t *testing.T) {
+	const header = "bin: go1.16.5\n" +
+		"\tpath\texample.com/main/cmd\n" +
+		"\tmod\texample.com/main\t(devel)\t\n"
+	tests := []struct {
+		name     string
+		versions []string
+		want     []string
+		wantErr  string
+	}{
+		{
+			name: "union of dependencies",
+			versions: []string{
+				header +
+					"\tdep\texample.com/a\tv1.0.0\th1:a=\n" +
+					"\tdep\texample.com/b\tv1.1.0\th1:b=\n",
+				header +
+					"\tdep\texample.com/b\tv1.1.0\th1:b=\n" +
+					"\tdep\texample.com/c\tv0.1.0\th1:c=\n",
+			},
+			want: []string{"example.com/a@v1.0.0", "example.com/b@v1.1.0", "example.com/c@v0.1.0"},
+		},
+		{
+			name: "conflicting versions in one binary",
+			versions: []string{
+				header +
+					"\tdep\texample.com/a\tv1.0.0\th1:a=\n" +
+					"\tdep\texample.com/a\tv1.2.0\th1:a2=\n",
+			},
+			wantErr: "conflicting versions of module example.com/a: v1.0.0 in bin0, v1.2.0 in bin0",
+		},
+		{
+			name: "conflicting versions in binaries",
+			versions: []string{
+				header + "\tdep\texample.com/a\tv1.0.0\th1:a=\n",
+				header + "\tdep\texample.com/a\tv1.2.0\th1:a2=\n",
+			},
+			wantErr: "conflicting versions of module example.com/a: v1.0.0 in bin0, v1.2.0 in bin1",
+		},
+		{
+			name: "conflicting replacements",
+			versions: []string{
+				header + "\tdep\texample.com/a\tv1.0.0\t\n\t=>\texample.com/fork\tv1.0.1\th1:f=\n",
+				header + "\tdep\texample.com/a\tv1.0.0\th1:a=\n",
+			},
+			wantErr: "conflicting versions of module example.com/a: v1.0.0 => example.com/fork@v1.0.1 in bin0, v1.0.0 in bin1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths := make([]string, 0, len(tt.versions))
+			buildInfos := make([]*debug.BuildInfo, 0, len(tt.versions))
+			for i, version := range tt.versions {
+				buildInfo, ok := debug.ParseBuildInfo(version)
+				require.True(t, ok, "ParseBuildInfo(%q)", version)
+				paths = append(paths, fmt.Sprintf("bin%d", i))
+				buildInfos = append(buildInfos, buildInfo)
+			}
+			mainRef, refs, err := mergeModuleRefs(paths, buildInfos)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "example.com/main", mainRef.Path)
+			got := make([]string, 0, len(refs))
+			for _, ref := range refs {
+				got = append(got, ref.Path+"@"+ref.Version)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gocli_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-licenses/v2/gocli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractBinaryMetadata_WithoutModules(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	mainPath := filepath.Join(tempDir, "main.go")
+	require.NoError(t, ioutil.WriteFile(mainPath, []byte("package main\n\nfunc main() {}\n"), 0644))
+	binaryName := filepath.Join(tempDir, "main")
+	// Binaries built in GOPATH mode do not contain go modules information.
+	cmd := exec.Command("go", "build", "-o", binaryName, mainPath)
+	cmd.Dir = tempDir
+	cmd.Env = append(os.Environ(), "GO111MODULE=off")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go build: %v: %s", err, output)
+	}
+
+	_, err = gocli.ExtractBinaryMetadata(binaryName)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the binary does not contain go modules information")
+}
+
+func TestExtractBinaryMetadata_NotBinary(t *testing.T) {
+	_, err := gocli.ExtractBinaryMetadata("go_binary_test.go")
+	assert.Error(t, err)
+}
@@ -0,0 +1,124 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gocli
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// ModuleGraph is the module requirement graph of the main module in workdir.
+// Versions are dropped, because only the selected version of each module is
+// in the build list.
+type ModuleGraph struct {
+	Main     string              // main module path
+	Requires map[string][]string // module path -> paths of modules it requires, in go.mod order
+}
+
+// ModGraph loads the module requirement graph in workdir using go CLI mod graph command.
+func ModGraph() (*ModuleGraph, error) {
+	return ModGraphInDir("")
+}
+
+// ModGraphInDir is like ModGraph, but loads the graph of the main module in
+// dir instead of workdir, when dir is not empty.
+func ModGraphInDir(dir string) (*ModuleGraph, error) {
+	cmd := exec.Command("go", "mod", "graph")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get go module graph: %w", err)
+	}
+	return ParseModGraph(bytes.NewReader(out))
+}
+
+// ParseModGraph parses output of `go mod graph`. Each line is an edge like
+// "example.com/main golang.org/x/text@v0.3.0", the main module is the only one
+// without a version.
+func ParseModGraph(r io.Reader) (*ModuleGraph, error) {
+	graph := &ModuleGraph{Requires: make(map[string][]string)}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("Failed to parse go mod graph line %q: 2 fields expected", line)
+		}
+		from := modulePath(fields[0])
+		to := modulePath(fields[1])
+		if !strings.Contains(fields[0], "@") {
+			graph.Main = from
+		}
+		if !contains(graph.Requires[from], to) {
+			graph.Requires[from] = append(graph.Requires[from], to)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Failed to read go mod graph: %w", err)
+	}
+	return graph, nil
+}
+
+// ShortestPath returns the shortest chain of modules from the main module to
+// module, both ends included. Returns nil when module is not required.
+func (g *ModuleGraph) ShortestPath(module string) []string {
+	if module == g.Main {
+		return []string{module}
+	}
+	parent := map[string]string{g.Main: ""}
+	queue := []string{g.Main}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, next := range g.Requires[current] {
+			if _, visited := parent[next]; visited {
+				continue
+			}
+			parent[next] = current
+			if next == module {
+				path := []string{next}
+				for p := current; p != ""; p = parent[p] {
+					path = append([]string{p}, path...)
+				}
+				return path
+			}
+			queue = append(queue, next)
+		}
+	}
+	return nil
+}
+
+func modulePath(moduleVersion string) string {
+	if i := strings.Index(moduleVersion, "@"); i >= 0 {
+		return moduleVersion[:i]
+	}
+	return moduleVersion
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
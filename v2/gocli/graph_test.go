@@ -0,0 +1,38 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gocli_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-licenses/v2/gocli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModuleGraph_ShortestPath(t *testing.T) {
+	graph, err := gocli.ParseModGraph(strings.NewReader(`example.com/main example.com/a@v1.0.0
+example.com/main example.com/b@v1.0.0
+example.com/a@v1.0.0 example.com/c@v1.0.0
+example.com/c@v1.0.0 example.com/gpl@v1.0.0
+example.com/b@v1.0.0 example.com/gpl@v1.1.0
+`))
+	require.Nil(t, err)
+	assert.Equal(t, "example.com/main", graph.Main)
+	assert.Equal(t, []string{"example.com/main", "example.com/b", "example.com/gpl"}, graph.ShortestPath("example.com/gpl"))
+	assert.Equal(t, []string{"example.com/main", "example.com/a", "example.com/c"}, graph.ShortestPath("example.com/c"))
+	assert.Nil(t, graph.ShortestPath("example.com/unknown"))
+}
@@ -27,7 +27,15 @@ import (
 // List go modules with metadata in workdir using go CLI list command.
 // Modules with replace directive are returned as the replaced module instead.
 func ListModules() (map[string]Module, error) {
-	out, err := exec.Command("go", "list", "-m", "-json", "all").Output()
+	return ListModulesInDir("")
+}
+
+// ListModulesInDir is like ListModules, but lists go modules of the module in
+// dir instead of workdir, when dir is not empty.
+func ListModulesInDir(dir string) (map[string]Module, error) {
+	cmd := exec.Command("go", "list", "-m", "-json", "all")
+	cmd.Dir = dir
+	out, err := cmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("Failed to list go modules: %w", err)
 	}
@@ -15,6 +15,7 @@
 package gocli
 
 import (
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -35,6 +36,7 @@ type Module struct {
 	Dir       string     // directory holding files for this module, if any
 	GoMod     string     // path to go.mod file used when loading this module, if any
 	GoVersion string     // go version used in module
+	LocalPath string     // local directory the module is replaced with, as written in go.mod, if any
 }
 
 func newModule(mod *packages.Module) *Module {
@@ -60,8 +62,15 @@ func newModule(mod *packages.Module) *Module {
 	// Haven't confirmed, but we may also need to override the
 	// entire struct when using replace directive with local folders.
 	tmp := *mod
+	localPath := ""
 	if tmp.Replace != nil {
 		tmp = *tmp.Replace
+		if isLocalPath(tmp.Path) {
+			// When replaced by a local directory, the replacement path is not
+			// a module path, so we keep the original module path.
+			localPath = tmp.Path
+			tmp.Path = mod.Path
+		}
 	}
 	// The +incompatible suffix does not affect module version.
 	// ref: https://golang.org/ref/mod#incompatible-versions
@@ -75,5 +84,12 @@ func newModule(mod *packages.Module) *Module {
 		Dir:       tmp.Dir,
 		GoMod:     tmp.GoMod,
 		GoVersion: tmp.GoVersion,
+		LocalPath: localPath,
 	}
 }
+
+// isLocalPath reports whether a replace directive target is a local directory.
+// ref: https://golang.org/ref/mod#go-mod-file-replace
+func isLocalPath(path string) bool {
+	return strings.HasPrefix(path, "./") || strings.HasPrefix(path, "../") || filepath.IsAbs(path)
+}
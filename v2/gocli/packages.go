@@ -15,18 +15,61 @@
 package gocli
 
 import (
+	"os"
+	"path/filepath"
 	"sort"
+	"strings"
 
 	"golang.org/x/tools/go/packages"
 )
 
+// ListOptions are build constraints used when listing dependencies, so that
+// dependencies only imported by files of some build tags or platforms are
+// found.
+type ListOptions struct {
+	// Build tags, e.g. "linux" or "integration".
+	BuildTags []string
+	// Target operating system and architecture, defaults to the GOOS and
+	// GOARCH of the go environment when empty.
+	GOOS   string
+	GOARCH string
+	// Also list dependencies only imported by tests of the packages.
+	Tests bool
+	// Directory of the main module that packages are loaded in, relative
+	// import paths like ./... are relative to it. Defaults to the working
+	// directory when empty.
+	Dir string
+}
+
 // ListDeps lists direct and transitive module dependencies of the import path packages.
 // It leverages golang.org/x/tools/go/packages under the hood.
 func ListDeps(importPaths ...string) ([]Module, error) {
+	return ListDepsWithOptions(ListOptions{}, importPaths...)
+}
+
+// ListDepsWithOptions is like ListDeps, but lists dependencies under build
+// constraints in options.
+func ListDepsWithOptions(options ListOptions, importPaths ...string) ([]Module, error) {
+	config := &packages.Config{
+		Mode:  packages.NeedModule | packages.NeedImports | packages.NeedName,
+		Tests: options.Tests,
+		Dir:   options.Dir,
+	}
+	if len(options.BuildTags) > 0 {
+		config.BuildFlags = []string{"-tags=" + strings.Join(options.BuildTags, ",")}
+	}
+	if options.GOOS != "" || options.GOARCH != "" {
+		// Later values take precedence over the environment.
+		config.Env = os.Environ()
+		if options.GOOS != "" {
+			config.Env = append(config.Env, "GOOS="+options.GOOS)
+		}
+		if options.GOARCH != "" {
+			config.Env = append(config.Env, "GOARCH="+options.GOARCH)
+		}
+	}
 	// TODO(Bobgy): wrap error messages
-	rootPkgs, err := packages.Load(&packages.Config{
-		Mode: packages.NeedModule | packages.NeedImports | packages.NeedName,
-	}, importPaths...)
+	rootPkgs, err := packages.Load(config, importPaths...)
 	if err != nil {
 		return nil, err
 	}
@@ -45,5 +88,30 @@ func ListDeps(importPaths ...string) ([]Module, error) {
 	sort.Slice(res, func(i, j int) bool {
 		return res[i].Path < res[j].Path
 	})
+	useVendorDirs(res)
 	return res, nil
 }
+
+// useVendorDirs points dirs of modules to their copies in the main module's
+// vendor folder. When dependencies are loaded in vendor mode, go does not
+// report dirs of modules, because they may not exist in the module cache.
+func useVendorDirs(mods []Module) {
+	mainDir := ""
+	for _, mod := range mods {
+		if mod.Main {
+			mainDir = mod.Dir
+		}
+	}
+	if mainDir == "" {
+		return
+	}
+	for i := range mods {
+		if mods[i].Main || mods[i].Dir != "" {
+			continue
+		}
+		dir := filepath.Join(mainDir, "vendor", filepath.FromSlash(mods[i].Path))
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			mods[i].Dir = dir
+		}
+	}
+}
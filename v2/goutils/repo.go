@@ -19,6 +19,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/google/go-licenses/v2/ghutils"
@@ -28,35 +29,131 @@ import (
 
 const (
 	githubBase = "github.com/"
+	// DefaultGoGetTimeout is the default timeout of each go-get request.
+	DefaultGoGetTimeout = 20 * time.Second
+	// DefaultGoGetBackoff is the default wait time before the first retry of a
+	// failed go-get request, it doubles for each retry.
+	DefaultGoGetBackoff = time.Second
 )
 
+// ErrGoGetRequest is wrapped by errors of go-get requests that still fail
+// after all retries, e.g. because of network issues.
+var ErrGoGetRequest = errors.New("go-get request failed")
+
+// RepoOptions controls how github repos of import paths are resolved.
+type RepoOptions struct {
+	// Import path prefixes mapped to github repos directly, without go-get
+	// requests. The longest matching prefix wins.
+	VanityImports map[string]string
+	// Timeout of each go-get request, defaults to DefaultGoGetTimeout.
+	Timeout time.Duration
+	// Number of retries of a go-get request that failed sending or responded
+	// with a server error.
+	Retries int
+	// Wait time before the first retry, defaults to DefaultGoGetBackoff.
+	Backoff time.Duration
+	// Optional, http client to send go-get requests, it overrides Timeout.
+	Client *http.Client
+}
+
 func GetGithubRepo(importPath string) (*ghutils.GitHubRepo, error) {
+	return GetGithubRepoWithOptions(importPath, RepoOptions{})
+}
+
+func getGithubRepo(importPath string, options RepoOptions) (*ghutils.GitHubRepo, error) {
 	if strings.HasPrefix(importPath, githubBase) {
 		repo, err := ghutils.ParseGitHubUrl(importPath)
 		if err != nil {
 			return nil, errors.Wrapf(err, "Failed to parse repo: importPath=%q", importPath)
 		}
+		// github.com/<owner>/<name>/<module dir>
+		segments := strings.SplitN(importPath[len(githubBase):], "/", 3)
+		if len(segments) == 3 {
+			repo.ModuleDir = segments[2]
+		}
 		return repo, nil
 	}
 
-	repo, err := parseGoGet(importPath)
+	repo, err := parseGoGet(importPath, options)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Failed to parse using go-get: importPath=%q", importPath)
 	}
 	return repo, nil
 }
 
-func parseGoGet(module string) (*ghutils.GitHubRepo, error) {
+// GetGithubRepoWithVanityImports is the same as GetGithubRepo, except that
+// import paths starting with a prefix in vanityImports are resolved to the
+// mapped github repo directly. The longest matching prefix wins.
+func GetGithubRepoWithVanityImports(importPath string, vanityImports map[string]string) (*ghutils.GitHubRepo, error) {
+	return GetGithubRepoWithOptions(importPath, RepoOptions{VanityImports: vanityImports})
+}
+
+// GetGithubRepoWithOptions is the same as GetGithubRepo, but configurable
+// using options.
+func GetGithubRepoWithOptions(importPath string, options RepoOptions) (*ghutils.GitHubRepo, error) {
+	prefix := ""
+	for p := range options.VanityImports {
+		if (importPath == p || strings.HasPrefix(importPath, p+"/")) && len(p) > len(prefix) {
+			prefix = p
+		}
+	}
+	if prefix == "" {
+		return getGithubRepo(importPath, options)
+	}
+	repo, err := ghutils.ParseGitHubUrl(options.VanityImports[prefix])
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse repo of vanity import prefix %q", prefix)
+	}
+	repo.ModuleDir = strings.TrimPrefix(strings.TrimPrefix(importPath, prefix), "/")
+	return repo, nil
+}
+
+// goGet sends a go-get request, retrying with exponential backoff when it
+// fails sending or the server responds with an error.
+func goGet(request string, options RepoOptions) (*http.Response, error) {
+	client := options.Client
+	if client == nil {
+		timeout := options.Timeout
+		if timeout == 0 {
+			timeout = DefaultGoGetTimeout
+		}
+		client = &http.Client{Timeout: timeout}
+	}
+	backoff := options.Backoff
+	if backoff == 0 {
+		backoff = DefaultGoGetBackoff
+	}
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		resp, err := client.Get(request)
+		if err == nil {
+			// Some go modules like gonum.org/v1/gonum return a 404 as
+			// response, but it also has the meta tags, so only server errors
+			// are retried.
+			if resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+				return resp, nil
+			}
+			resp.Body.Close()
+			err = errors.Errorf("response status %s", resp.Status)
+		}
+		lastErr = err
+		if attempt >= options.Retries {
+			break
+		}
+		klog.V(2).InfoS("Retrying go-get request", "request", request, "err", err, "backoff", backoff)
+		time.Sleep(backoff)
+		backoff = backoff * 2
+	}
+	return nil, fmt.Errorf("%w: %s after %v attempt(s): %v", ErrGoGetRequest, request, options.Retries+1, lastErr)
+}
+
+func parseGoGet(module string, options RepoOptions) (*ghutils.GitHubRepo, error) {
 	request := fmt.Sprintf("https://%s?go-get=1", module)
-	resp, err := http.Get(request)
+	resp, err := goGet(request, options)
 	if err != nil {
-		return nil, errors.Wrapf(err, "Failed sending request %s", request)
+		return nil, err
 	}
 	defer resp.Body.Close()
-	// Some go modules like gonum.org/v1/gonum return a 404 as response, but it also has the meta tags.
-	// if resp.StatusCode >= 400 {
-	// 	return nil, errors.Errorf("Response status code %v not OK for request %s", resp.StatusCode, request)
-	// }
 	bodyBytes, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Failed reading response body for request %s", request)
@@ -67,7 +164,7 @@ func parseGoGet(module string) (*ghutils.GitHubRepo, error) {
 	if err != nil {
 		return nil, errors.Wrapf(err, "Failed creating document")
 	}
-	var vcs, repoRoot, sourceHome, sourceDir string
+	var vcs, repoRoot, repoPrefix, sourceHome, sourceDir string
 	doc.Find("meta").Each(func(i int, s *goquery.Selection) {
 		name, _ := s.Attr("name")
 		content, _ := s.Attr("content")
@@ -90,6 +187,7 @@ func parseGoGet(module string) (*ghutils.GitHubRepo, error) {
 			}
 			vcs = thisVcs
 			repoRoot = thisRepoRoot
+			repoPrefix = importPrefix
 		} else if name == "go-source" {
 			segments := strings.Fields(content)
 			// documentation: https://github.com/golang/gddo/wiki/Source-Code-Links
@@ -116,16 +214,22 @@ func parseGoGet(module string) (*ghutils.GitHubRepo, error) {
 	if vcs != "git" {
 		return nil, errors.Errorf("go-import vcs %s not supported", vcs)
 	}
+	// The go-import prefix is the repo root, so the rest of the module path
+	// is the module's directory in the repo.
+	moduleDir := strings.TrimPrefix(strings.TrimPrefix(module, repoPrefix), "/")
 	repo, err := ghutils.ParseGitHubUrl(repoRoot)
 	if err == nil {
+		repo.ModuleDir = moduleDir
 		return repo, nil
 	}
 	repo, err2 := ghutils.ParseGitHubUrl(sourceHome)
 	if err2 == nil {
+		repo.ModuleDir = moduleDir
 		return repo, nil
 	}
 	repo, err3 := ghutils.ParseGitHubUrl(sourceDir)
 	if err3 == nil {
+		repo.ModuleDir = moduleDir
 		return repo, nil
 	}
 	return nil, errors.Errorf("Failed to parse github url from repoRoot '%s', sourceHome '%s', sourceDir '%s'", repoRoot, sourceHome, sourceDir)
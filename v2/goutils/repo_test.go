@@ -0,0 +1,88 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package goutils_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-licenses/v2/ghutils"
+	"github.com/google/go-licenses/v2/goutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetGithubRepoWithVanityImports(t *testing.T) {
+	vanityImports := map[string]string{
+		"gopkg.in/yaml.v2": "github.com/go-yaml/yaml",
+		"k8s.io/klog":      "https://github.com/kubernetes/klog",
+	}
+	cases := []struct {
+		importPath string
+		expected   ghutils.GitHubRepo
+	}{
+		{
+			importPath: "gopkg.in/yaml.v2",
+			expected:   ghutils.GitHubRepo{Owner: "go-yaml", Name: "yaml"},
+		},
+		{
+			importPath: "k8s.io/klog/v2",
+			expected:   ghutils.GitHubRepo{Owner: "kubernetes", Name: "klog", ModuleDir: "v2"},
+		},
+		{
+			// not a vanity import, resolved without network
+			importPath: "github.com/google/go-licenses/v2",
+			expected:   ghutils.GitHubRepo{Owner: "google", Name: "go-licenses", ModuleDir: "v2"},
+		},
+	}
+	for _, tt := range cases {
+		repo, err := goutils.GetGithubRepoWithVanityImports(tt.importPath, vanityImports)
+		require.Nil(t, err, tt.importPath)
+		assert.Equal(t, tt.expected, *repo, tt.importPath)
+	}
+}
+
+func TestGetGithubRepoWithOptions_Retries(t *testing.T) {
+	attempts := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts = attempts + 1
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		module := r.Host + r.URL.Path
+		fmt.Fprintf(w, `<meta name="go-import" content="%s git https://github.com/example/repo">`, module)
+	}))
+	defer server.Close()
+	importPath := strings.TrimPrefix(server.URL, "https://") + "/mod"
+
+	options := goutils.RepoOptions{Retries: 1, Backoff: time.Millisecond, Client: server.Client()}
+	_, err := goutils.GetGithubRepoWithOptions(importPath, options)
+	require.NotNil(t, err, "should fail when retries are exhausted")
+	assert.True(t, errors.Is(err, goutils.ErrGoGetRequest), "unexpected error: %v", err)
+	assert.Equal(t, 2, attempts)
+
+	attempts = 0
+	options.Retries = 2
+	repo, err := goutils.GetGithubRepoWithOptions(importPath, options)
+	require.Nil(t, err)
+	assert.Equal(t, ghutils.GitHubRepo{Owner: "example", Name: "repo"}, *repo)
+	assert.Equal(t, 3, attempts)
+}
@@ -0,0 +1,57 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	licenseclassifier "github.com/google/licenseclassifier/v2"
+	"github.com/pkg/errors"
+)
+
+// ContentClassifier identifies licenses in license texts that are not files
+// of a scanned dir, e.g. license texts downloaded from urls.
+type ContentClassifier struct {
+	classifier *licenseclassifier.Classifier
+}
+
+// NewContentClassifier loads license texts in dbPath. threshold defaults to
+// DefaultConfidenceThreshold when it's zero.
+func NewContentClassifier(dbPath string, threshold float64) (*ContentClassifier, error) {
+	if threshold == 0 {
+		threshold = DefaultConfidenceThreshold
+	}
+	classifier := licenseclassifier.NewClassifier(threshold)
+	if err := classifier.LoadLicenses(dbPath); err != nil {
+		return nil, errors.Wrapf(err, "Failed to load licenses from %s", dbPath)
+	}
+	return &ContentClassifier{classifier: classifier}, nil
+}
+
+// Classify returns licenses found in content, license headers are ignored
+// like when scanning dirs.
+func (c *ContentClassifier) Classify(content []byte) []Found {
+	found := make([]Found, 0)
+	for _, match := range c.classifier.Match(content) {
+		if match.MatchType == string(matchTypeHeader) {
+			continue
+		}
+		found = append(found, Found{
+			SpdxId:     match.Name,
+			StartLine:  match.StartLine,
+			EndLine:    match.EndLine,
+			Confidence: match.Confidence,
+		})
+	}
+	return found
+}
@@ -0,0 +1,37 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses_test
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/google/go-licenses/v2/licenses"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentClassifier(t *testing.T) {
+	classifier, err := licenses.NewContentClassifier(DbPath, 0)
+	require.Nil(t, err)
+	content, err := ioutil.ReadFile("testdata/MIT.txt")
+	require.Nil(t, err)
+
+	found := classifier.Classify(content)
+	require.Len(t, found, 1)
+	assert.Equal(t, "MIT", found[0].SpdxId)
+
+	assert.Empty(t, classifier.Classify([]byte("not a license")))
+}
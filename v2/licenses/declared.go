@@ -0,0 +1,97 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// spdxTagRegexp matches a machine-readable license declaration.
+// Reference: https://spdx.dev/ids/
+var spdxTagRegexp = regexp.MustCompile(`SPDX-License-Identifier:\s*([^\s*/]+(\s+(AND|OR|WITH)\s+[^\s*/]+)*)`)
+
+// declarationFiles are non go files in the root folder that may declare a license.
+var declarationFiles = []string{"AUTHORS", "AUTHORS.md", "AUTHORS.txt"}
+
+// ScanDeclared finds licenses declared by a module, instead of full license
+// texts. It's a last resort when no license file is found by ScanDir.
+// Licenses are declared using SPDX-License-Identifier tags in comments before
+// the package clause of go files (e.g. doc.go) or in AUTHORS files in the
+// root folder of dir. Returned files are marked as Declared, because the
+// licenses are not classified from license texts.
+func ScanDeclared(dir string) ([]File, error) {
+	if dir == "" {
+		return nil, ErrorEmptyDir
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to scan declared licenses in %s", dir)
+	}
+	files := make([]File, 0)
+	add := func(name string, text string) {
+		file := File{Path: name, Declared: true}
+		for _, match := range spdxTagRegexp.FindAllStringSubmatch(text, -1) {
+			file.Licenses = append(file.Licenses, Found{SpdxId: strings.TrimSpace(match[1])})
+		}
+		if len(file.Licenses) > 0 {
+			files = append(files, file)
+		}
+	}
+	fset := token.NewFileSet()
+	for _, entry := range entries {
+		if entry.IsDir() || entry.Mode()&os.ModeSymlink != 0 {
+			continue
+		}
+		name := entry.Name()
+		path := filepath.Join(dir, name)
+		switch {
+		case strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go"):
+			f, err := parser.ParseFile(fset, path, nil, parser.PackageClauseOnly|parser.ParseComments)
+			if err != nil {
+				// Not a valid go file, it cannot declare a license either.
+				continue
+			}
+			var text strings.Builder
+			for _, group := range f.Comments {
+				text.WriteString(group.Text())
+			}
+			add(name, text.String())
+		case isDeclarationFile(name):
+			content, err := ioutil.ReadFile(path)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Failed to read %s", path)
+			}
+			add(name, string(content))
+		}
+	}
+	return files, nil
+}
+
+func isDeclarationFile(name string) bool {
+	for _, declarationFile := range declarationFiles {
+		if name == declarationFile {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,175 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SPDX license expression operators.
+// Reference: https://spdx.github.io/spdx-spec/SPDX-license-expressions/
+const (
+	OperatorAnd = "AND"
+	OperatorOr  = "OR"
+)
+
+// Expression is a parsed SPDX license expression, e.g.
+// "Apache-2.0 OR (MIT AND BSD-3-Clause)".
+type Expression struct {
+	// OperatorAnd or OperatorOr combining Operands, empty for a single
+	// license.
+	Operator string
+	Operands []*Expression
+	// SPDX ID of a single license.
+	SpdxId string
+	// Optional license exception of a single license, e.g.
+	// "Classpath-exception-2.0" in "GPL-2.0-only WITH Classpath-exception-2.0".
+	Exception string
+}
+
+// SpdxIds returns SPDX IDs of all licenses in the expression, in the order
+// they appear.
+func (e *Expression) SpdxIds() []string {
+	if e.Operator == "" {
+		return []string{e.SpdxId}
+	}
+	ids := make([]string, 0)
+	for _, operand := range e.Operands {
+		ids = append(ids, operand.SpdxIds()...)
+	}
+	return ids
+}
+
+// String formats the expression, operands combined by another operator are
+// enclosed in parentheses.
+func (e *Expression) String() string {
+	if e.Operator == "" {
+		if e.Exception != "" {
+			return e.SpdxId + " WITH " + e.Exception
+		}
+		return e.SpdxId
+	}
+	operands := make([]string, 0, len(e.Operands))
+	for _, operand := range e.Operands {
+		if operand.Operator != "" {
+			operands = append(operands, "("+operand.String()+")")
+		} else {
+			operands = append(operands, operand.String())
+		}
+	}
+	return strings.Join(operands, " "+e.Operator+" ")
+}
+
+// ParseExpression parses an SPDX license expression. AND takes precedence
+// over OR, and parentheses can be used for grouping. Operators are either all
+// uppercase or all lowercase, as the SPDX spec requires.
+func ParseExpression(expression string) (*Expression, error) {
+	expression = strings.ReplaceAll(expression, "(", " ( ")
+	expression = strings.ReplaceAll(expression, ")", " ) ")
+	p := &expressionParser{tokens: strings.Fields(expression)}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf("Empty license expression")
+	}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("Invalid license expression %q: unexpected %q", strings.Join(p.tokens, " "), p.tokens[p.pos])
+	}
+	return e, nil
+}
+
+type expressionParser struct {
+	tokens []string
+	pos    int
+}
+
+// next returns the next token, or "" at the end of the expression.
+func (p *expressionParser) next() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+// isOperator reports whether the next token is the operator.
+func (p *expressionParser) isOperator(operator string) bool {
+	token := p.next()
+	return token == operator || token == strings.ToLower(operator)
+}
+
+func (p *expressionParser) parseOr() (*Expression, error) {
+	return p.parseBinary(OperatorOr, p.parseAnd)
+}
+
+func (p *expressionParser) parseAnd() (*Expression, error) {
+	return p.parseBinary(OperatorAnd, p.parseOperand)
+}
+
+// parseBinary parses operands parsed by parseOperand and joined by operator.
+func (p *expressionParser) parseBinary(operator string, parseOperand func() (*Expression, error)) (*Expression, error) {
+	first, err := parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isOperator(operator) {
+		return first, nil
+	}
+	e := &Expression{Operator: operator, Operands: []*Expression{first}}
+	for p.isOperator(operator) {
+		p.pos++
+		operand, err := parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		e.Operands = append(e.Operands, operand)
+	}
+	return e, nil
+}
+
+func (p *expressionParser) parseOperand() (*Expression, error) {
+	token := p.next()
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("Invalid license expression %q: unexpected end", strings.Join(p.tokens, " "))
+	case token == "(":
+		p.pos++
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("Invalid license expression %q: missing )", strings.Join(p.tokens, " "))
+		}
+		p.pos++
+		return e, nil
+	case token == ")" || p.isOperator(OperatorAnd) || p.isOperator(OperatorOr) || p.isOperator("WITH"):
+		return nil, fmt.Errorf("Invalid license expression %q: unexpected %q", strings.Join(p.tokens, " "), token)
+	}
+	p.pos++
+	e := &Expression{SpdxId: token}
+	if p.isOperator("WITH") {
+		p.pos++
+		exception := p.next()
+		if exception == "" || exception == "(" || exception == ")" {
+			return nil, fmt.Errorf("Invalid license expression %q: missing exception after WITH", strings.Join(p.tokens, " "))
+		}
+		p.pos++
+		e.Exception = exception
+	}
+	return e, nil
+}
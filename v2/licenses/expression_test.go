@@ -0,0 +1,91 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses_test
+
+import (
+	"testing"
+
+	"github.com/google/go-licenses/v2/licenses"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExpression(t *testing.T) {
+	mit := &licenses.Expression{SpdxId: "MIT"}
+	apache := &licenses.Expression{SpdxId: "Apache-2.0"}
+	bsd := &licenses.Expression{SpdxId: "BSD-3-Clause"}
+	var tests = []struct {
+		expression string
+		expected   *licenses.Expression
+	}{
+		{"MIT", mit},
+		{"Apache-2.0 OR MIT", &licenses.Expression{Operator: licenses.OperatorOr, Operands: []*licenses.Expression{apache, mit}}},
+		{"Apache-2.0 or MIT", &licenses.Expression{Operator: licenses.OperatorOr, Operands: []*licenses.Expression{apache, mit}}},
+		{
+			// AND takes precedence over OR
+			"Apache-2.0 OR MIT AND BSD-3-Clause",
+			&licenses.Expression{Operator: licenses.OperatorOr, Operands: []*licenses.Expression{
+				apache,
+				{Operator: licenses.OperatorAnd, Operands: []*licenses.Expression{mit, bsd}},
+			}},
+		},
+		{
+			"(Apache-2.0 OR MIT) AND BSD-3-Clause",
+			&licenses.Expression{Operator: licenses.OperatorAnd, Operands: []*licenses.Expression{
+				{Operator: licenses.OperatorOr, Operands: []*licenses.Expression{apache, mit}},
+				bsd,
+			}},
+		},
+		{"GPL-2.0-only WITH Classpath-exception-2.0", &licenses.Expression{SpdxId: "GPL-2.0-only", Exception: "Classpath-exception-2.0"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.expression, func(t *testing.T) {
+			e, err := licenses.ParseExpression(tc.expression)
+			require.Nil(t, err)
+			assert.Equal(t, tc.expected, e)
+		})
+	}
+}
+
+func TestParseExpression_Invalid(t *testing.T) {
+	for _, expression := range []string{"", "MIT OR", "OR MIT", "(MIT", "MIT)", "MIT Apache-2.0", "GPL-2.0-only WITH"} {
+		t.Run(expression, func(t *testing.T) {
+			_, err := licenses.ParseExpression(expression)
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestExpression_SpdxIds(t *testing.T) {
+	e, err := licenses.ParseExpression("(Apache-2.0 OR MIT) AND BSD-3-Clause")
+	require.Nil(t, err)
+	assert.Equal(t, []string{"Apache-2.0", "MIT", "BSD-3-Clause"}, e.SpdxIds())
+}
+
+func TestExpression_String(t *testing.T) {
+	for _, expression := range []string{
+		"MIT",
+		"Apache-2.0 OR MIT",
+		"(Apache-2.0 OR MIT) AND BSD-3-Clause",
+		"Apache-2.0 OR (MIT AND BSD-3-Clause)",
+		"GPL-2.0-only WITH Classpath-exception-2.0 OR MIT",
+	} {
+		t.Run(expression, func(t *testing.T) {
+			e, err := licenses.ParseExpression(expression)
+			require.Nil(t, err)
+			assert.Equal(t, expression, e.String())
+		})
+	}
+}
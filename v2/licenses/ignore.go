@@ -0,0 +1,145 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ReadIgnoreFile reads ignore patterns from a .gitignore-style file, one
+// pattern per line. Empty lines and lines starting with # are skipped.
+// Refer to ScanDirOptions.IgnorePatterns for the syntax of patterns.
+func ReadIgnoreFile(filePath string) (patterns []string, err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Wrapf(err, "Failed to read ignore file %s", filePath)
+		}
+	}()
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, err := compileIgnorePattern(line); err != nil {
+			return nil, fmt.Errorf("line %v: %w", lineNumber, err)
+		}
+		patterns = append(patterns, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return patterns, nil
+}
+
+// ignorePattern is a compiled pattern of ScanDirOptions.IgnorePatterns.
+type ignorePattern struct {
+	glob string
+	// match the glob against paths relative to the scanned dir, instead of
+	// names of files and folders at any depth
+	anchored bool
+	// match the glob at any depth below the scanned dir, for a leading **/
+	anyDepth bool
+	dirOnly  bool
+}
+
+func compileIgnorePattern(pattern string) (ignorePattern, error) {
+	p := ignorePattern{glob: pattern}
+	if strings.HasSuffix(p.glob, "/") {
+		p.dirOnly = true
+		p.glob = strings.TrimSuffix(p.glob, "/")
+	}
+	if strings.HasPrefix(p.glob, "**/") {
+		p.anyDepth = true
+		p.glob = strings.TrimPrefix(p.glob, "**/")
+	}
+	if strings.HasPrefix(p.glob, "/") {
+		p.glob = strings.TrimPrefix(p.glob, "/")
+		p.anchored = true
+	}
+	if strings.Contains(p.glob, "/") {
+		p.anchored = true
+	}
+	if p.glob == "" {
+		return p, fmt.Errorf("invalid ignore pattern %q: it matches nothing", pattern)
+	}
+	if _, err := path.Match(p.glob, ""); err != nil {
+		return p, fmt.Errorf("invalid ignore pattern %q: %w", pattern, err)
+	}
+	return p, nil
+}
+
+// match reports whether relPath, a slash separated path relative to the
+// scanned dir, matches the pattern.
+func (p ignorePattern) match(relPath string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+	if !p.anchored {
+		matched, _ := path.Match(p.glob, path.Base(relPath))
+		return matched
+	}
+	if matched, _ := path.Match(p.glob, relPath); matched || !p.anyDepth {
+		return matched
+	}
+	for i := range relPath {
+		if relPath[i] != '/' {
+			continue
+		}
+		if matched, _ := path.Match(p.glob, relPath[i+1:]); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoreMatcher matches paths against ignore patterns.
+type ignoreMatcher []ignorePattern
+
+func newIgnoreMatcher(patterns []string) (ignoreMatcher, error) {
+	matcher := make(ignoreMatcher, 0, len(patterns))
+	for _, pattern := range patterns {
+		p, err := compileIgnorePattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		matcher = append(matcher, p)
+	}
+	return matcher, nil
+}
+
+// ignored reports whether relPath, a slash separated path relative to the
+// scanned dir, matches any of the patterns.
+func (m ignoreMatcher) ignored(relPath string, isDir bool) bool {
+	for _, p := range m {
+		if p.match(relPath, isDir) {
+			return true
+		}
+	}
+	return false
+}
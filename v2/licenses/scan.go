@@ -15,10 +15,13 @@
 package licenses
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 
 	licenseclassifier "github.com/google/licenseclassifier/v2"
 	"github.com/pkg/errors"
@@ -27,11 +30,31 @@ import (
 
 const DefaultConfidenceThreshold = 0.80
 
+// DefaultReadmeConfidenceThreshold is the default minimum confidence to
+// identify a license in a README, see ScanDirOptions.ScanReadme.
+const DefaultReadmeConfidenceThreshold = 0.95
+
 var ErrorEmptyDir = errors.New("Invalid Argument: dir is empty")
 
 type File struct {
 	Path     string  // file path
 	Licenses []Found // licenses found in the file
+	// Whether the file is inside a folder of vendored dependencies, e.g.
+	// vendor/ or third_party/. Licenses in these folders usually belong to
+	// the vendored dependencies instead of the scanned module.
+	Vendored bool
+	// Whether the file is in a sub folder with C source code, e.g. a C
+	// library wrapped by cgo. The C library may have a different license
+	// from the go module wrapping it.
+	CLibrary bool
+	// Whether licenses are declared by the module, e.g. using SPDX license
+	// identifiers, instead of classified from license texts. Declared
+	// licenses are less reliable than classified ones.
+	Declared bool
+	// Whether the file is a README in the root folder, e.g. README.md,
+	// instead of a dedicated license file. Licenses in READMEs should be
+	// verified manually.
+	Readme bool
 }
 
 type Found struct {
@@ -39,11 +62,53 @@ type Found struct {
 	StartLine  int
 	EndLine    int
 	Confidence float64
+	// Language of the license text when it's matched using a translation,
+	// e.g. "de". Empty for the original license text.
+	Language string
 }
 
 type ScanDirOptions struct {
 	ExcludePaths []string
 	DbPath       string
+	// Minimum confidence to identify a license, defaults to
+	// DefaultConfidenceThreshold when it's zero.
+	ConfidenceThreshold float64
+	// Only classify files with this exact name when it's not empty, e.g.
+	// "LICENSE".
+	LicenseFilename string
+	// Optional folder of translated license texts organized as
+	// <language>/<SPDX ID>.txt. Translations are only matched against license
+	// files that are not classified using DbPath.
+	TranslationsDbPath string
+	// Names of folders not scanned, anywhere in dir. They replace
+	// DefaultIgnoreDirs when specified, append to DefaultIgnoreDirs to keep
+	// the defaults.
+	IgnoreDirs []string
+	// .gitignore-style glob patterns of files and folders not scanned, e.g.
+	// read by ReadIgnoreFile. Patterns without a slash match names at any
+	// depth, e.g. *.pb.go, other patterns match slash separated paths
+	// relative to dir, e.g. /testdata/fixtures or internal/*/testdata. A
+	// leading **/ matches at any depth, and a trailing slash only matches
+	// folders. Matched folders are not descended into. Negation with ! is
+	// not supported.
+	IgnorePatterns []string
+	// Only scan files directly in dir, not in its sub folders.
+	Shallow bool
+	// Follow symbolic links to files and folders, so that e.g. a LICENSE
+	// symlinked to a license shared by a monorepo is classified. Links are
+	// reported at their paths in dir. Broken links, links forming loops and
+	// links nested deeper than MaxSymlinkDepth are skipped.
+	FollowSymlinks bool
+	// Classify README files in the root folder, e.g. README.md, when no
+	// license files of the scanned module are found otherwise, using
+	// ReadmeConfidenceThreshold. It matters when LicenseFilename is set,
+	// because README files are classified like any other file otherwise.
+	ScanReadme bool
+	// Minimum confidence to identify a license in a README when ScanReadme
+	// is true, defaults to DefaultReadmeConfidenceThreshold when it's zero.
+	// It's higher than ConfidenceThreshold by default, because READMEs
+	// often mention licenses without their full texts.
+	ReadmeConfidenceThreshold float64
 }
 
 type matchType string
@@ -53,15 +118,32 @@ const (
 	matchTypeLicense matchType = "License"
 )
 
-var ignoredDir map[string]bool = make(map[string]bool)
+// MaxSymlinkDepth is the maximum number of nested symbolic links to folders
+// followed when ScanDirOptions.FollowSymlinks is true.
+const MaxSymlinkDepth = 8
+
+// DefaultIgnoreDirs are names of folders not scanned by default.
+var DefaultIgnoreDirs = []string{".git", "node_modules"}
+
+var vendoredDir map[string]bool = make(map[string]bool)
+var cSourceExt map[string]bool = make(map[string]bool)
 
 func init() {
-	ignoredDir[".git"] = true
-	ignoredDir["node_modules"] = true
+	vendoredDir["vendor"] = true
+	vendoredDir["third_party"] = true
+	for _, ext := range []string{".c", ".h", ".cc", ".cpp", ".cxx", ".hh", ".hpp", ".hxx"} {
+		cSourceExt[ext] = true
+	}
 }
 
 // Scan a directory for licenses.
 func ScanDir(dir string, options ScanDirOptions) ([]File, error) {
+	return ScanDirContext(context.Background(), dir, options)
+}
+
+// ScanDirContext is like ScanDir, but stops scanning and returns ctx.Err()
+// when ctx is done.
+func ScanDirContext(ctx context.Context, dir string, options ScanDirOptions) ([]File, error) {
 	var wrap = func(cause error, extra string) error {
 		extraMessage := ""
 		if extra != "" {
@@ -88,20 +170,96 @@ func ScanDir(dir string, options ScanDirOptions) ([]File, error) {
 		}
 		excludeAbsPaths[absPath] = true
 	}
-	classifier := licenseclassifier.NewClassifier(DefaultConfidenceThreshold)
+	ignoreDirs := options.IgnoreDirs
+	if len(ignoreDirs) == 0 {
+		ignoreDirs = DefaultIgnoreDirs
+	}
+	ignoredDir := make(map[string]bool)
+	for _, name := range ignoreDirs {
+		ignoredDir[name] = true
+	}
+	ignoreMatcher, err := newIgnoreMatcher(options.IgnorePatterns)
+	if err != nil {
+		return nil, wrap(err, "")
+	}
+	threshold := options.ConfidenceThreshold
+	if threshold == 0 {
+		threshold = DefaultConfidenceThreshold
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	classifier := licenseclassifier.NewClassifier(threshold)
 	classifier.LoadLicenses(options.DbPath)
+	translations, err := loadTranslations(options.TranslationsDbPath, threshold)
+	if err != nil {
+		return nil, wrap(err, "loading license translations")
+	}
 	files := make([]File, 0)
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return wrap(err, "walk error")
-		}
-		if info.Mode()&os.ModeSymlink != 0 {
-			// skip symbolic links
-			return nil
-		}
+	// relative paths of folders that contain C source code
+	cSourceDirs := make(map[string]bool)
+	// visit scans a file or folder at path in dir, whose content is at
+	// realPath.
+	var visit func(path string, name string, realPath string, info os.FileInfo) error
+	// walk scans root, a folder in dir. When root is reached by following
+	// symbolic links, realRoot is the folder they resolve to, and parents
+	// are the real paths of the folders linking to it, for loop detection.
+	var walk func(root string, realRoot string, parents []string) error
+	walk = func(root string, realRoot string, parents []string) error {
+		return filepath.Walk(realRoot, func(realPath string, info os.FileInfo, err error) error {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			if err != nil {
+				return wrap(err, "walk error")
+			}
+			// path of the file in dir, it differs from realPath in
+			// folders reached by following symbolic links
+			path := root + realPath[len(realRoot):]
+			name := filepath.Base(path)
+			if info.Mode()&os.ModeSymlink != 0 {
+				if !options.FollowSymlinks {
+					// skip symbolic links
+					return nil
+				}
+				target, err := filepath.EvalSymlinks(realPath)
+				if err == nil {
+					info, err = os.Stat(target)
+				}
+				if err != nil {
+					klog.V(2).InfoS("Skipped broken symbolic link", "path", path, "err", err)
+					return nil
+				}
+				if !info.IsDir() {
+					realPath = target
+				} else {
+					linked := append(parents[:len(parents):len(parents)], realPath)
+					switch {
+					case len(linked) > MaxSymlinkDepth+1:
+						klog.Warningf("Skipped symbolic link %s: more than %v nested links to folders", path, MaxSymlinkDepth)
+						return nil
+					case isParentOrSelf(target, linked):
+						klog.V(2).InfoS("Skipped symbolic link to a parent folder", "path", path, "target", target)
+						return nil
+					}
+					return walk(path, target, linked)
+				}
+			}
+			return visit(path, name, realPath, info)
+		})
+	}
+	visit = func(path string, name string, realPath string, info os.FileInfo) error {
 		if info.IsDir() {
-			// TODO: move this to config
-			if ignoredDir[info.Name()] {
+			// dir itself is always scanned, even if its name is ignored,
+			// e.g. a module in a docs/ folder
+			if ignoredDir[name] && path != dir {
+				return filepath.SkipDir
+			}
+			if options.Shallow && path != dir {
+				return filepath.SkipDir
+			}
+			if path != dir && ignoreMatcher.ignored(filepath.ToSlash(path[len(dir)+1:]), true) {
+				klog.V(4).InfoS("Skipped ignored folder", "path", path)
 				return filepath.SkipDir
 			}
 			_, excluded := excludeAbsPaths[path]
@@ -115,13 +273,25 @@ func ScanDir(dir string, options ScanDirOptions) ([]File, error) {
 		if excluded {
 			return nil
 		}
-		fileBytes, err := ioutil.ReadFile(path)
+		if ignoreMatcher.ignored(filepath.ToSlash(path[len(dir)+1:]), false) {
+			klog.V(4).InfoS("Skipped ignored file", "path", path)
+			return nil
+		}
+		if cSourceExt[strings.ToLower(filepath.Ext(path))] {
+			cSourceDirs[filepath.ToSlash(filepath.Dir(path[len(dir)+1:]))] = true
+		}
+		if options.LicenseFilename != "" && name != options.LicenseFilename {
+			return nil
+		}
+		fileBytes, err := ioutil.ReadFile(realPath)
 		if err != nil {
 			return wrap(err, fmt.Sprintf("reading file %s", path))
 		}
 		matches := classifier.Match(fileBytes)
 		var file File
 		file.Path = path[len(dir)+1:] // relative path from module.Dir
+		file.Vendored = isVendored(file.Path)
+		file.Readme = file.Path == name && readmeFileRegexp.MatchString(name)
 		for _, match := range matches {
 			if match.MatchType == string(matchTypeHeader) {
 				// ignore headers
@@ -135,17 +305,191 @@ func ScanDir(dir string, options ScanDirOptions) ([]File, error) {
 				Confidence: match.Confidence,
 			})
 		}
+		if len(file.Licenses) == 0 && licenseFileRegexp.MatchString(name) {
+			file.Licenses = matchTranslations(translations, fileBytes)
+		}
 		if len(file.Licenses) > 0 {
 			files = append(files, file)
 		}
 		return nil
-	})
+	}
+	realDir := dir
+	if options.FollowSymlinks {
+		realDir, err = filepath.EvalSymlinks(dir)
+		if err != nil {
+			return nil, wrap(err, "")
+		}
+	}
+	err = walk(dir, realDir, []string{realDir})
 	if err != nil {
 		return nil, err
 	}
+	for i := range files {
+		files[i].CLibrary = isCLibrary(files[i].Path, cSourceDirs)
+	}
+	if options.ScanReadme && !hasModuleLicense(files) {
+		readmeThreshold := options.ReadmeConfidenceThreshold
+		if readmeThreshold == 0 {
+			readmeThreshold = DefaultReadmeConfidenceThreshold
+		}
+		readmes, err := scanReadmes(dir, options.DbPath, readmeThreshold)
+		if err != nil {
+			return nil, wrap(err, "scanning README files")
+		}
+		files = append(files, readmes...)
+	}
 	return files, nil
 }
 
+// readmeFileRegexp matches names of README files.
+var readmeFileRegexp = regexp.MustCompile(`(?i)^readme(\..+)?$`)
+
+// hasModuleLicense reports whether any of files is a license of the scanned
+// module, instead of vendored dependencies or C libraries.
+func hasModuleLicense(files []File) bool {
+	for _, file := range files {
+		if !file.Vendored && !file.CLibrary {
+			return true
+		}
+	}
+	return false
+}
+
+// scanReadmes classifies README files in the root folder of dir with
+// threshold, ignoring license headers.
+func scanReadmes(dir string, dbPath string, threshold float64) ([]File, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var classifier *licenseclassifier.Classifier
+	files := make([]File, 0)
+	for _, entry := range entries {
+		if !entry.Mode().IsRegular() || !readmeFileRegexp.MatchString(entry.Name()) {
+			continue
+		}
+		if classifier == nil {
+			classifier = licenseclassifier.NewClassifier(threshold)
+			classifier.LoadLicenses(dbPath)
+		}
+		fileBytes, err := ioutil.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		file := File{Path: entry.Name(), Readme: true}
+		for _, match := range classifier.Match(fileBytes) {
+			if match.MatchType == string(matchTypeHeader) {
+				continue
+			}
+			file.Licenses = append(file.Licenses, Found{
+				SpdxId:     match.Name,
+				StartLine:  match.StartLine,
+				EndLine:    match.EndLine,
+				Confidence: match.Confidence,
+			})
+		}
+		if len(file.Licenses) > 0 {
+			klog.V(2).InfoS("License found in README", "dir", dir, "path", file.Path)
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// isParentOrSelf reports whether dir is one of folders or their parents.
+func isParentOrSelf(dir string, folders []string) bool {
+	for _, folder := range folders {
+		if folder == dir || strings.HasPrefix(folder, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// licenseFileRegexp matches names of files that are likely license files,
+// including common names in other languages.
+var licenseFileRegexp = regexp.MustCompile(`(?i)^(licen[cs]e|copying|lizenz|licencia|licenza|licen[cs]a)`)
+
+// translation classifies license texts translated to a language.
+type translation struct {
+	language   string
+	classifier *licenseclassifier.Classifier
+}
+
+// loadTranslations loads a classifier for each language folder in dir.
+func loadTranslations(dir string, threshold float64) ([]translation, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	translations := make([]translation, 0)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		classifier := licenseclassifier.NewClassifier(threshold)
+		if err := classifier.LoadLicenses(filepath.Join(dir, entry.Name())); err != nil {
+			return nil, err
+		}
+		translations = append(translations, translation{language: entry.Name(), classifier: classifier})
+	}
+	return translations, nil
+}
+
+// matchTranslations returns licenses found using the first language that
+// matches content.
+func matchTranslations(translations []translation, content []byte) []Found {
+	for _, t := range translations {
+		var found []Found
+		for _, match := range t.classifier.Match(content) {
+			if match.MatchType == string(matchTypeHeader) {
+				continue
+			}
+			found = append(found, Found{
+				SpdxId:     match.Name,
+				StartLine:  match.StartLine,
+				EndLine:    match.EndLine,
+				Confidence: match.Confidence,
+				Language:   t.language,
+			})
+		}
+		if len(found) > 0 {
+			return found
+		}
+	}
+	return nil
+}
+
+// isVendored reports whether relPath is inside a vendored dependencies folder.
+func isVendored(relPath string) bool {
+	for _, segment := range strings.Split(filepath.ToSlash(filepath.Dir(relPath)), "/") {
+		if vendoredDir[segment] {
+			return true
+		}
+	}
+	return false
+}
+
+// isCLibrary reports whether relPath is in a sub folder that contains C
+// source code directly or in its descendant folders. Files in the root folder
+// are never considered part of a C library, because cgo modules usually have
+// C code at the root as part of the go module itself.
+func isCLibrary(relPath string, cSourceDirs map[string]bool) bool {
+	fileDir := filepath.ToSlash(filepath.Dir(relPath))
+	if fileDir == "." {
+		return false
+	}
+	for cDir := range cSourceDirs {
+		if cDir == fileDir || strings.HasPrefix(cDir, fileDir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
 // Temporarily disabled
 // func GetLicenseFullText(module goutils.Module, license LicenseFound) (string, error) {
 // 	errorContext := func() string {
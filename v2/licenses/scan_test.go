@@ -15,10 +15,15 @@
 package licenses_test
 
 import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/google/go-licenses/v2/licenses"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const DbPath = "../third_party/google/licenseclassifier/licenses"
@@ -34,6 +39,7 @@ func TestScan_ThisRepo(t *testing.T) {
 				"deps/testdata",
 				// testdata
 				"licenses/testdata",
+				"tests/modules",
 				// distribution
 				"dist",
 				// license db
@@ -50,15 +56,188 @@ func TestScan_ThisRepo(t *testing.T) {
 		{
 			Path:     "third_party/go/runtime/debug/LICENSE",
 			Licenses: []licenses.Found{{SpdxId: "BSD-3-Clause", StartLine: 3, EndLine: 27, Confidence: 0.9812206572769953}},
+			Vendored: true,
 		},
 		{
 			Path:     "third_party/google/licenseclassifier/LICENSE",
 			Licenses: []licenses.Found{{SpdxId: "Apache-2.0", StartLine: 2, EndLine: 175, Confidence: 1}},
+			Vendored: true,
 		},
 	}
 	assert.Equal(t, expected, found)
 }
 
+func TestScan_Vendored(t *testing.T) {
+	found, err := licenses.ScanDir(
+		"testdata/vendored",
+		licenses.ScanDirOptions{
+			DbPath: DbPath,
+		},
+	)
+	if err != nil {
+		t.Error(err)
+	}
+	expected := []licenses.File{
+		{
+			Path:     "LICENSE",
+			Licenses: []licenses.Found{{SpdxId: "MIT", StartLine: 1, EndLine: 17, Confidence: 1}},
+		},
+		{
+			Path:     "third_party/bsd/LICENSE",
+			Licenses: []licenses.Found{{SpdxId: "BSD-3-Clause", StartLine: 3, EndLine: 27, Confidence: 0.9812206572769953}},
+			Vendored: true,
+		},
+	}
+	assert.Equal(t, expected, found)
+}
+
+func TestScan_CLibrary(t *testing.T) {
+	found, err := licenses.ScanDir(
+		"testdata/cgo",
+		licenses.ScanDirOptions{
+			DbPath: DbPath,
+		},
+	)
+	if err != nil {
+		t.Error(err)
+	}
+	expected := []licenses.File{
+		{
+			Path:     "LICENSE",
+			Licenses: []licenses.Found{{SpdxId: "MIT", StartLine: 1, EndLine: 17, Confidence: 1}},
+		},
+		{
+			Path:     "libfoo/COPYING",
+			Licenses: []licenses.Found{{SpdxId: "BSD-3-Clause", StartLine: 3, EndLine: 27, Confidence: 0.9812206572769953}},
+			CLibrary: true,
+		},
+	}
+	assert.Equal(t, expected, found)
+}
+
+func TestScan_LicenseFilename(t *testing.T) {
+	found, err := licenses.ScanDir(
+		"testdata/cgo",
+		licenses.ScanDirOptions{
+			DbPath:          DbPath,
+			LicenseFilename: "LICENSE",
+		},
+	)
+	if err != nil {
+		t.Error(err)
+	}
+	expected := []licenses.File{
+		{
+			Path:     "LICENSE",
+			Licenses: []licenses.Found{{SpdxId: "MIT", StartLine: 1, EndLine: 17, Confidence: 1}},
+		},
+	}
+	assert.Equal(t, expected, found)
+}
+
+func TestScan_IgnoreDirs(t *testing.T) {
+	found, err := licenses.ScanDir(
+		"testdata/vendored",
+		licenses.ScanDirOptions{
+			DbPath:     DbPath,
+			IgnoreDirs: append(licenses.DefaultIgnoreDirs, "third_party"),
+		},
+	)
+	if err != nil {
+		t.Error(err)
+	}
+	require.Len(t, found, 1)
+	assert.Equal(t, "LICENSE", found[0].Path)
+}
+
+func TestScan_IgnorePatterns(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		expected []string
+	}{
+		{name: "none", expected: []string{"LICENSE", "third_party/bsd/LICENSE"}},
+		{name: "folder name", patterns: []string{"bsd/"}, expected: []string{"LICENSE"}},
+		{name: "anchored path", patterns: []string{"/third_party/*"}, expected: []string{"LICENSE"}},
+		{name: "any depth", patterns: []string{"**/bsd/LICENSE"}, expected: []string{"LICENSE"}},
+		{name: "file name", patterns: []string{"LICEN?E"}, expected: []string{}},
+		{name: "anchored file", patterns: []string{"/LICENSE"}, expected: []string{"third_party/bsd/LICENSE"}},
+		{name: "folder only", patterns: []string{"LICENSE/"}, expected: []string{"LICENSE", "third_party/bsd/LICENSE"}},
+		{name: "not matching", patterns: []string{"bsd/LICENSE"}, expected: []string{"LICENSE", "third_party/bsd/LICENSE"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			found, err := licenses.ScanDir(
+				"testdata/vendored",
+				licenses.ScanDirOptions{
+					DbPath:         DbPath,
+					IgnorePatterns: tc.patterns,
+				},
+			)
+			require.NoError(t, err)
+			paths := make([]string, 0)
+			for _, file := range found {
+				paths = append(paths, file.Path)
+			}
+			assert.Equal(t, tc.expected, paths)
+		})
+	}
+}
+
+func TestScan_InvalidIgnorePattern(t *testing.T) {
+	_, err := licenses.ScanDir("testdata/vendored", licenses.ScanDirOptions{DbPath: DbPath, IgnorePatterns: []string{"[a-"}})
+	assert.Error(t, err)
+}
+
+func TestReadIgnoreFile(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, ".licenseignore")
+	content := "# generated code\n*.pb.go\n\n  /testdata/  \n"
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	patterns, err := licenses.ReadIgnoreFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"*.pb.go", "/testdata/"}, patterns)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("ok\n[a-\n"), 0644))
+	_, err = licenses.ReadIgnoreFile(path)
+	assert.Error(t, err)
+}
+
+func TestScan_Shallow(t *testing.T) {
+	found, err := licenses.ScanDir(
+		"testdata/vendored",
+		licenses.ScanDirOptions{
+			DbPath:  DbPath,
+			Shallow: true,
+		},
+	)
+	if err != nil {
+		t.Error(err)
+	}
+	require.Len(t, found, 1)
+	assert.Equal(t, "LICENSE", found[0].Path)
+}
+
+func TestScan_Translated(t *testing.T) {
+	found, err := licenses.ScanDir(
+		"testdata/translated",
+		licenses.ScanDirOptions{
+			DbPath:             DbPath,
+			TranslationsDbPath: "testdata/translations",
+		},
+	)
+	if err != nil {
+		t.Error(err)
+	}
+	require.Len(t, found, 1)
+	assert.Equal(t, "LIZENZ", found[0].Path)
+	require.Len(t, found[0].Licenses, 1)
+	assert.Equal(t, "MIT", found[0].Licenses[0].SpdxId)
+	assert.Equal(t, "de", found[0].Licenses[0].Language)
+}
+
 func TestScan_DirWithSymlink(t *testing.T) {
 	found, err := licenses.ScanDir(
 		"testdata/folder-with-symlink",
@@ -72,3 +251,94 @@ func TestScan_DirWithSymlink(t *testing.T) {
 	expected := []licenses.File{}
 	assert.Equal(t, expected, found)
 }
+
+func TestScan_FollowSymlinks(t *testing.T) {
+	found, err := licenses.ScanDir(
+		"testdata/symlinked/module",
+		licenses.ScanDirOptions{
+			DbPath:         DbPath,
+			FollowSymlinks: true,
+		},
+	)
+	if err != nil {
+		t.Error(err)
+	}
+	// loop links to a parent folder, so it's skipped
+	require.Len(t, found, 2)
+	assert.Equal(t, "LICENSE", found[0].Path)
+	assert.Equal(t, "sub/LICENSE", found[1].Path)
+
+	// broken links are skipped
+	found, err = licenses.ScanDir(
+		"testdata/folder-with-symlink",
+		licenses.ScanDirOptions{
+			DbPath:         DbPath,
+			FollowSymlinks: true,
+		},
+	)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, []licenses.File{}, found)
+}
+
+// cancelAfterContext is cancelled after its Err method is called a number
+// of times, so that a scan is cancelled deterministically mid-way.
+type cancelAfterContext struct {
+	context.Context
+	calls int
+}
+
+func (c *cancelAfterContext) Err() error {
+	if c.calls <= 0 {
+		return context.Canceled
+	}
+	c.calls = c.calls - 1
+	return nil
+}
+
+func TestScan_Cancelled(t *testing.T) {
+	ctx := &cancelAfterContext{Context: context.Background(), calls: 3}
+	found, err := licenses.ScanDirContext(ctx, "testdata", licenses.ScanDirOptions{DbPath: DbPath})
+	assert.Equal(t, context.Canceled, err)
+	assert.Nil(t, found)
+}
+
+func TestScanDeclared(t *testing.T) {
+	found, err := licenses.ScanDeclared("testdata/declared")
+	if err != nil {
+		t.Error(err)
+	}
+	expected := []licenses.File{
+		{
+			Path:     "AUTHORS",
+			Licenses: []licenses.Found{{SpdxId: "Apache-2.0 OR MIT"}},
+			Declared: true,
+		},
+		{
+			Path:     "doc.go",
+			Licenses: []licenses.Found{{SpdxId: "MIT"}},
+			Declared: true,
+		},
+	}
+	assert.Equal(t, expected, found)
+}
+
+func TestScan_Readme(t *testing.T) {
+	options := licenses.ScanDirOptions{
+		DbPath:          DbPath,
+		LicenseFilename: "LICENSE",
+	}
+	found, err := licenses.ScanDir("testdata/readme", options)
+	require.NoError(t, err)
+	assert.Empty(t, found)
+
+	options.ScanReadme = true
+	found, err = licenses.ScanDir("testdata/readme", options)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, "README.md", found[0].Path)
+	assert.True(t, found[0].Readme)
+	require.Len(t, found[0].Licenses, 1)
+	assert.Equal(t, "MIT", found[0].Licenses[0].SpdxId)
+}
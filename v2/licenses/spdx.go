@@ -0,0 +1,67 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses
+
+import (
+	"regexp"
+)
+
+// DeprecatedSpdxIds maps deprecated SPDX license IDs to their current
+// canonical forms, which may be license expressions with exceptions. The
+// classifier still reports some deprecated IDs, e.g. GPL-2.0, which strict SPDX
+// validators reject. Add entries to normalize more IDs.
+// Reference: https://spdx.org/licenses/#deprecated
+var DeprecatedSpdxIds = map[string]string{
+	"AGPL-1.0":                         "AGPL-1.0-only",
+	"AGPL-3.0":                         "AGPL-3.0-only",
+	"BSD-2-Clause-FreeBSD":             "BSD-2-Clause",
+	"BSD-2-Clause-NetBSD":              "BSD-2-Clause",
+	"GFDL-1.1":                         "GFDL-1.1-only",
+	"GFDL-1.2":                         "GFDL-1.2-only",
+	"GFDL-1.3":                         "GFDL-1.3-only",
+	"GPL-1.0":                          "GPL-1.0-only",
+	"GPL-1.0+":                         "GPL-1.0-or-later",
+	"GPL-2.0":                          "GPL-2.0-only",
+	"GPL-2.0+":                         "GPL-2.0-or-later",
+	"GPL-2.0-with-classpath-exception": "GPL-2.0-only WITH Classpath-exception-2.0",
+	"GPL-3.0":                          "GPL-3.0-only",
+	"GPL-3.0+":                         "GPL-3.0-or-later",
+	"LGPL-2.0":                         "LGPL-2.0-only",
+	"LGPL-2.0+":                        "LGPL-2.0-or-later",
+	"LGPL-2.1":                         "LGPL-2.1-only",
+	"LGPL-2.1+":                        "LGPL-2.1-or-later",
+	"LGPL-3.0":                         "LGPL-3.0-only",
+	"LGPL-3.0+":                        "LGPL-3.0-or-later",
+	"StandardML-NJ":                    "SMLNJ",
+}
+
+// spdxIdRegexp matches SPDX IDs, and operators, in license expressions.
+var spdxIdRegexp = regexp.MustCompile(`[A-Za-z0-9.+-]+`)
+
+// NormalizeSpdxId returns the canonical form of a deprecated SPDX ID in
+// DeprecatedSpdxIds, or the ID itself.
+func NormalizeSpdxId(spdxId string) string {
+	if canonical, ok := DeprecatedSpdxIds[spdxId]; ok {
+		return canonical
+	}
+	return spdxId
+}
+
+// NormalizeSpdxIds replaces deprecated SPDX IDs in text, e.g. a license
+// expression like "GPL-2.0 OR MIT" or licenses joined by " / ", with their
+// canonical forms.
+func NormalizeSpdxIds(text string) string {
+	return spdxIdRegexp.ReplaceAllStringFunc(text, NormalizeSpdxId)
+}
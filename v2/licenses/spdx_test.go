@@ -0,0 +1,41 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package licenses_test
+
+import (
+	"testing"
+
+	"github.com/google/go-licenses/v2/licenses"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeSpdxIds(t *testing.T) {
+	var tests = []struct {
+		text     string
+		expected string
+	}{
+		{"MIT", "MIT"},
+		{"GPL-2.0", "GPL-2.0-only"},
+		{"GPL-2.0+", "GPL-2.0-or-later"},
+		{"GPL-2.0-only", "GPL-2.0-only"},
+		{"LGPL-2.1 OR MIT", "LGPL-2.1-only OR MIT"},
+		{"(GPL-3.0 AND Apache-2.0) OR MIT", "(GPL-3.0-only AND Apache-2.0) OR MIT"},
+		{"Apache-2.0 / AGPL-3.0", "Apache-2.0 / AGPL-3.0-only"},
+		{"GPL-2.0-with-classpath-exception", "GPL-2.0-only WITH Classpath-exception-2.0"},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.expected, licenses.NormalizeSpdxIds(tc.text), tc.text)
+	}
+}
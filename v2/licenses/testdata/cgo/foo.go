@@ -0,0 +1,9 @@
+package foo
+
+// #cgo CFLAGS: -Ilibfoo/src
+// #include "foo.h"
+import "C"
+
+func Foo() int {
+	return int(C.foo())
+}
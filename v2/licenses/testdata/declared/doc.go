@@ -0,0 +1,4 @@
+// SPDX-License-Identifier: MIT
+
+// Package declared declares its license without a license file.
+package declared
@@ -14,8 +14,12 @@
 
 package main
 
-import "github.com/google/go-licenses/v2/cmd"
+import (
+	"os"
+
+	"github.com/google/go-licenses/v2/cmd"
+)
 
 func main() {
-	cmd.Execute()
+	os.Exit(cmd.Execute())
 }
@@ -0,0 +1,13 @@
+module github.com/google/go-licenses/v2/tests/modules/buildtags04
+
+go 1.15
+
+require (
+	example.com/tagged v1.0.0
+	example.com/windows v1.0.0
+)
+
+replace (
+	example.com/tagged => ./tagged
+	example.com/windows => ./windows
+)
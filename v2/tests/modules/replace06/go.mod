@@ -0,0 +1,12 @@
+module github.com/google/go-licenses/v2/tests/modules/replace06
+
+go 1.15
+
+require (
+	example.com/flags v1.0.0
+	example.com/localdep v0.0.0
+)
+
+replace example.com/flags => github.com/spf13/pflag v1.0.5
+
+replace example.com/localdep => ./localdep
@@ -0,0 +1,8 @@
+package localdep
+
+import "fmt"
+
+// Hello prints hello.
+func Hello() {
+	fmt.Println("hello")
+}
@@ -0,0 +1,11 @@
+package main
+
+import (
+	flags "example.com/flags"
+	"example.com/localdep"
+)
+
+func main() {
+	flags.Parse()
+	localdep.Hello()
+}
@@ -0,0 +1,7 @@
+module github.com/google/go-licenses/v2/tests/modules/testdeps05
+
+go 1.15
+
+require example.com/testonly v1.0.0
+
+replace example.com/testonly => ./testonly
@@ -0,0 +1,5 @@
+module github.com/google/go-licenses/v2/tests/modules/vendored03
+
+go 1.15
+
+require example.com/greeting v1.0.0